@@ -0,0 +1,272 @@
+package admin
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/todd-beckman/mmmorty"
+)
+
+//go:embed dashboard.html
+var dashboardHTML string
+
+var templates = template.Must(template.New("dashboard").Parse(dashboardHTML))
+
+const cookieName = "mmmorty_admin"
+
+// Dashboard is a web page where the bot's owner can browse and edit each server's plugin data while the bot runs.
+// Plugins offer their data by implementing mmmorty.GuildDataEditor. Changes are made while holding the plugin's lock,
+// so the bot's own saves keep them rather than overwriting them.
+type Dashboard struct {
+	bot   *mmmorty.Bot
+	token string
+	mux   *http.ServeMux
+}
+
+// New creates a dashboard for a bot. Every request must carry the token, either once as ?token= to sign in, or as
+// a bearer token.
+func New(bot *mmmorty.Bot, token string) *Dashboard {
+	d := &Dashboard{
+		bot:   bot,
+		token: token,
+		mux:   http.NewServeMux(),
+	}
+	d.mux.HandleFunc("/", d.handleIndex)
+	d.mux.HandleFunc("/guild", d.handleGuild)
+	d.mux.HandleFunc("/edit", d.handleEdit)
+	d.mux.HandleFunc("/save", d.handleSave)
+	return d
+}
+
+// IsLocal returns whether an address only listens on this machine, eg. "localhost:8080" or "127.0.0.1:8080".
+func IsLocal(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// ServeHTTP serves the dashboard to anyone with the token.
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if token := r.URL.Query().Get("token"); token != "" && r.Method == http.MethodGet && d.valid(token) {
+		http.SetCookie(w, &http.Cookie{
+			Name:     cookieName,
+			Value:    token,
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+		query := r.URL.Query()
+		query.Del("token")
+		target := r.URL.Path
+		if len(query) > 0 {
+			target += "?" + query.Encode()
+		}
+		http.Redirect(w, r, target, http.StatusSeeOther)
+		return
+	}
+	if !d.authorized(r) {
+		d.render(w, http.StatusUnauthorized, "login", nil)
+		return
+	}
+	d.mux.ServeHTTP(w, r)
+}
+
+func (d *Dashboard) valid(token string) bool {
+	return d.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(d.token)) == 1
+}
+
+func (d *Dashboard) authorized(r *http.Request) bool {
+	if bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "); bearer != r.Header.Get("Authorization") {
+		return d.valid(bearer)
+	}
+	cookie, err := r.Cookie(cookieName)
+	return err == nil && d.valid(cookie.Value)
+}
+
+// render writes a page with the status, setting its headers first.
+func (d *Dashboard) render(w http.ResponseWriter, status int, name string, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := templates.ExecuteTemplate(w, name, data); err != nil {
+		d.bot.Logger.Error("Error rendering admin dashboard", "page", name, "err", err)
+	}
+}
+
+// guildLister is implemented by services that can list the guilds the bot is in, such as Discord.
+type guildLister interface {
+	Guilds() []*discordgo.Guild
+}
+
+type guildLink struct {
+	ID   string
+	Name string
+}
+
+type serviceIndex struct {
+	Name   string
+	Guilds []guildLink
+}
+
+func (d *Dashboard) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	services := []serviceIndex{}
+	for name, service := range d.bot.Services {
+		s := serviceIndex{Name: name}
+		if l, ok := service.Service.(guildLister); ok {
+			for _, g := range l.Guilds() {
+				s.Guilds = append(s.Guilds, guildLink{ID: g.ID, Name: g.Name})
+			}
+		}
+		sort.Slice(s.Guilds, func(i, j int) bool {
+			return s.Guilds[i].Name < s.Guilds[j].Name
+		})
+		services = append(services, s)
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+
+	d.render(w, http.StatusOK, "index", map[string]interface{}{
+		"Version":  mmmorty.VersionString,
+		"Services": services,
+		"Saved":    r.URL.Query().Get("saved") != "",
+	})
+}
+
+type pluginData struct {
+	Name  string
+	Data  string
+	Error string
+	Saved bool
+}
+
+// editors returns the plugins on a service whose guild data can be edited, ordered by name.
+func (d *Dashboard) editors(serviceName string) []mmmorty.Plugin {
+	service := d.bot.Services[serviceName]
+	if service == nil {
+		return nil
+	}
+	plugins := []mmmorty.Plugin{}
+	for _, plugin := range service.Plugins {
+		if _, ok := plugin.(mmmorty.GuildDataEditor); ok {
+			plugins = append(plugins, plugin)
+		}
+	}
+	sort.Slice(plugins, func(i, j int) bool {
+		return plugins[i].Name() < plugins[j].Name()
+	})
+	return plugins
+}
+
+// guildData returns a plugin's data for a guild as indented JSON.
+func (d *Dashboard) guildData(service mmmorty.Service, plugin mmmorty.Plugin, guildID string) (string, error) {
+	d.bot.LockPlugin(plugin)
+	defer d.bot.UnlockPlugin(plugin)
+
+	data, err := json.MarshalIndent(plugin.(mmmorty.GuildDataEditor).GuildData(service, guildID), "", "  ")
+	return string(data), err
+}
+
+// renderGuild shows every plugin's data for a guild, with the plugin just edited showing what was submitted if it
+// couldn't be used.
+func (d *Dashboard) renderGuild(w http.ResponseWriter, status int, serviceName, guildID string, edited pluginData) {
+	service := d.bot.Services[serviceName]
+	if service == nil || guildID == "" {
+		http.Error(w, "Unknown service or server.", http.StatusNotFound)
+		return
+	}
+
+	guildName := guildID
+	if g, err := service.Guild(guildID); err == nil && g.Name != "" {
+		guildName = g.Name
+	}
+
+	plugins := []pluginData{}
+	for _, plugin := range d.editors(serviceName) {
+		if plugin.Name() == edited.Name && edited.Error != "" {
+			plugins = append(plugins, edited)
+			continue
+		}
+		p := pluginData{Name: plugin.Name(), Saved: plugin.Name() == edited.Name}
+		data, err := d.guildData(service.Service, plugin, guildID)
+		if err != nil {
+			p.Error = err.Error()
+		}
+		p.Data = data
+		plugins = append(plugins, p)
+	}
+
+	d.render(w, status, "guild", map[string]interface{}{
+		"Service":   serviceName,
+		"GuildID":   guildID,
+		"GuildName": guildName,
+		"Plugins":   plugins,
+	})
+}
+
+func (d *Dashboard) handleGuild(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	d.renderGuild(w, http.StatusOK, query.Get("service"), strings.TrimSpace(query.Get("guild")), pluginData{Name: query.Get("saved")})
+}
+
+func (d *Dashboard) handleEdit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Edits must be posted.", http.StatusMethodNotAllowed)
+		return
+	}
+	serviceName, guildID, pluginName := r.FormValue("service"), r.FormValue("guild"), r.FormValue("plugin")
+	data := r.FormValue("data")
+
+	service := d.bot.Services[serviceName]
+	if service == nil || guildID == "" {
+		http.Error(w, "Unknown service or server.", http.StatusNotFound)
+		return
+	}
+	plugin := service.Plugins[pluginName]
+	editor, ok := plugin.(mmmorty.GuildDataEditor)
+	if !ok {
+		http.Error(w, "Unknown plugin.", http.StatusNotFound)
+		return
+	}
+
+	d.bot.LockPlugin(plugin)
+	err := editor.SetGuildData(d.bot, service.Service, guildID, []byte(data))
+	d.bot.UnlockPlugin(plugin)
+
+	if err != nil {
+		d.renderGuild(w, http.StatusBadRequest, serviceName, guildID, pluginData{Name: pluginName, Data: data, Error: err.Error()})
+		return
+	}
+	d.bot.Logger.Info("Edited plugin data from the admin dashboard", "service", serviceName, "guild", guildID, "plugin", pluginName)
+
+	query := url.Values{"service": {serviceName}, "guild": {guildID}, "saved": {pluginName}}
+	http.Redirect(w, r, "/guild?"+query.Encode(), http.StatusSeeOther)
+}
+
+func (d *Dashboard) handleSave(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Saves must be posted.", http.StatusMethodNotAllowed)
+		return
+	}
+	d.bot.Save()
+	d.bot.Logger.Info("Saved from the admin dashboard")
+	http.Redirect(w, r, "/?saved=1", http.StatusSeeOther)
+}
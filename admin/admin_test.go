@@ -0,0 +1,143 @@
+package admin_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/todd-beckman/mmmorty"
+	"github.com/todd-beckman/mmmorty/admin"
+	"github.com/todd-beckman/mmmorty/mmmortytest"
+	"github.com/todd-beckman/mmmorty/quoteplugin"
+)
+
+const token = "secret"
+
+// newDashboard returns a dashboard for a bot with the quote plugin on a fake service with one server, g0.
+func newDashboard(t *testing.T) *admin.Dashboard {
+	t.Helper()
+	service := mmmortytest.New()
+	service.AddGuild("g0", "Citadel", "rick")
+
+	bot := mmmorty.NewBot()
+	bot.Store = mmmortytest.NewStore()
+	bot.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	bot.RegisterService(service)
+	bot.RegisterPlugin(service, quoteplugin.New())
+	return admin.New(bot, token)
+}
+
+// serve sends a request to the dashboard, signed in with a bearer token unless bearer is empty.
+func serve(d *admin.Dashboard, method, target, bearer string, form url.Values) *httptest.ResponseRecorder {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	r := httptest.NewRequest(method, target, body)
+	if form != nil {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if bearer != "" {
+		r.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	w := httptest.NewRecorder()
+	d.ServeHTTP(w, r)
+	return w
+}
+
+func TestDashboardNeedsToken(t *testing.T) {
+	d := newDashboard(t)
+	for name, bearer := range map[string]string{
+		"none":  "",
+		"wrong": "guess",
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := serve(d, http.MethodGet, "/", bearer, nil)
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("got status %d, expected %d", w.Code, http.StatusUnauthorized)
+			}
+			if contentType := w.Result().Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/html") {
+				t.Errorf("got Content-Type %q, expected html", contentType)
+			}
+		})
+	}
+}
+
+func TestDashboardSignsInWithTokenQuery(t *testing.T) {
+	d := newDashboard(t)
+	w := serve(d, http.MethodGet, "/guild?service=Discord&token="+token, "", nil)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("got status %d, expected %d", w.Code, http.StatusSeeOther)
+	}
+	if location := w.Header().Get("Location"); location != "/guild?service=Discord" {
+		t.Errorf("redirected to %q, expected the page without the token", location)
+	}
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != token || !cookies[0].HttpOnly {
+		t.Fatalf("got cookies %v, expected one HttpOnly cookie with the token", cookies)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cookies[0])
+	signedIn := httptest.NewRecorder()
+	d.ServeHTTP(signedIn, r)
+	if signedIn.Code != http.StatusOK {
+		t.Errorf("got status %d with the cookie, expected %d", signedIn.Code, http.StatusOK)
+	}
+}
+
+func TestDashboardAcceptsBearerToken(t *testing.T) {
+	d := newDashboard(t)
+	w := serve(d, http.MethodGet, "/guild?service=Discord&guild=g0", token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, expected %d", w.Code, http.StatusOK)
+	}
+	if !strings.Contains(w.Body.String(), "Citadel") {
+		t.Errorf("the server's page doesn't name it:\n%s", w.Body.String())
+	}
+}
+
+func TestDashboardChangesMustBePosted(t *testing.T) {
+	d := newDashboard(t)
+	for _, path := range []string{"/edit", "/save"} {
+		t.Run(path, func(t *testing.T) {
+			w := serve(d, http.MethodGet, path, token, nil)
+			if w.Code != http.StatusMethodNotAllowed {
+				t.Errorf("got status %d, expected %d", w.Code, http.StatusMethodNotAllowed)
+			}
+		})
+	}
+}
+
+func TestDashboardShowsInvalidEdits(t *testing.T) {
+	d := newDashboard(t)
+	form := url.Values{
+		"service": {"Discord"},
+		"guild":   {"g0"},
+		"plugin":  {"Quote"},
+		"data":    {`[{"author": "Rick", "quote": ""}]`},
+	}
+	w := serve(d, http.MethodPost, "/edit", token, form)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("got status %d, expected %d", w.Code, http.StatusBadRequest)
+	}
+	if contentType := w.Result().Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/html") {
+		t.Errorf("got Content-Type %q, expected html", contentType)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "quote 1 needs an author and a quote") {
+		t.Errorf("the page doesn't say what was wrong:\n%s", body)
+	}
+	if !strings.Contains(body, "Rick") {
+		t.Errorf("the page doesn't show what was submitted:\n%s", body)
+	}
+
+	form.Set("data", `[{"author": "Rick", "quote": "Wubba lubba dub dub"}]`)
+	if w := serve(d, http.MethodPost, "/edit", token, form); w.Code != http.StatusSeeOther {
+		t.Errorf("got status %d for a valid edit, expected %d", w.Code, http.StatusSeeOther)
+	}
+}
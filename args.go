@@ -0,0 +1,266 @@
+package mmmorty
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var argTokenRegex = regexp.MustCompile(`<[^>]*>|\S+`)
+
+const (
+	argWord   = "word"
+	argInt    = "int"
+	argMinute = "minute"
+	argText   = "text"
+)
+
+type argToken struct {
+	// literal is set for words that must appear as written.
+	literal string
+
+	name     string
+	kind     string
+	min, max int
+	ranged   bool
+}
+
+// ArgSpec describes a command and its arguments, eg.
+//
+//	start sprint at <minute:int 0-59> for <minutes:int 1-180>
+//
+// The command's name is the words before the first argument, and the other words must appear where they're written.
+// Arguments are written <name> or <name:type>, where type is one of:
+//
+//	word          a single word, the default
+//	int           a whole number, "int 1-180" only allows numbers from 1 to 180
+//	minute        a minute past the hour, written 30 or :30
+//	text          one or more words, up to the next word of the spec or the end of the message
+type ArgSpec struct {
+	Name   string
+	tokens []argToken
+}
+
+// ParseArgSpec parses a command spec.
+func ParseArgSpec(spec string) (*ArgSpec, error) {
+	s := &ArgSpec{}
+	name := []string{}
+	seen := map[string]bool{}
+
+	for _, t := range argTokenRegex.FindAllString(spec, -1) {
+		if !strings.HasPrefix(t, "<") {
+			if len(s.tokens) == 0 {
+				name = append(name, t)
+			} else {
+				s.tokens = append(s.tokens, argToken{literal: t})
+			}
+			continue
+		}
+		if len(name) == 0 {
+			return nil, fmt.Errorf("%q has no command name", spec)
+		}
+
+		token, err := parseArgToken(t)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %v", spec, err)
+		}
+		if seen[token.name] {
+			return nil, fmt.Errorf("%q has two arguments named %s", spec, token.name)
+		}
+		seen[token.name] = true
+		if token.kind == argText && len(s.tokens) > 0 && s.tokens[len(s.tokens)-1].kind == argText {
+			return nil, fmt.Errorf("%q has two text arguments in a row", spec)
+		}
+		s.tokens = append(s.tokens, token)
+	}
+	if len(name) == 0 {
+		return nil, fmt.Errorf("%q has no command name", spec)
+	}
+
+	s.Name = strings.Join(name, " ")
+	return s, nil
+}
+
+func parseArgToken(t string) (argToken, error) {
+	fields := strings.Fields(strings.Trim(t, "<>"))
+	if len(fields) == 0 || len(fields) > 2 {
+		return argToken{}, fmt.Errorf("can't understand %s", t)
+	}
+
+	token := argToken{name: fields[0], kind: argWord}
+	if i := strings.Index(fields[0], ":"); i != -1 {
+		token.name, token.kind = fields[0][:i], fields[0][i+1:]
+	}
+	if token.name == "" {
+		return argToken{}, fmt.Errorf("%s has no name", t)
+	}
+
+	switch token.kind {
+	case argWord, argMinute, argText:
+		if len(fields) == 2 {
+			return argToken{}, fmt.Errorf("%s can't have a range", t)
+		}
+	case argInt:
+		if len(fields) == 2 {
+			bounds := strings.SplitN(fields[1], "-", 2)
+			if len(bounds) != 2 {
+				return argToken{}, fmt.Errorf("%s has a range that isn't min-max", t)
+			}
+			var err1, err2 error
+			token.min, err1 = strconv.Atoi(bounds[0])
+			token.max, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil || token.min > token.max {
+				return argToken{}, fmt.Errorf("%s has a range that isn't min-max", t)
+			}
+			token.ranged = true
+		}
+	default:
+		return argToken{}, fmt.Errorf("%s has unknown type %s", t, token.kind)
+	}
+	return token, nil
+}
+
+// MustParseArgSpec is like ParseArgSpec but panics if the spec can't be parsed.
+func MustParseArgSpec(spec string) *ArgSpec {
+	s, err := ParseArgSpec(spec)
+	if err != nil {
+		panic("mmmorty: " + err.Error())
+	}
+	return s
+}
+
+// Arguments returns the usage of the spec's arguments, eg. "at <minute> for <minutes>".
+func (s *ArgSpec) Arguments() string {
+	usage := make([]string, len(s.tokens))
+	for i, t := range s.tokens {
+		if t.literal != "" {
+			usage[i] = t.literal
+		} else {
+			usage[i] = "<" + t.name + ">"
+		}
+	}
+	return strings.Join(usage, " ")
+}
+
+// Usage returns how to use the command, eg. "@morty start sprint at <minute> for <minutes>".
+func (s *ArgSpec) Usage(service Service) string {
+	if len(s.tokens) == 0 {
+		return service.CommandPrefix() + s.Name
+	}
+	return service.CommandPrefix() + s.Name + " " + s.Arguments()
+}
+
+// UsageReply returns a reply explaining why a message didn't match the spec.
+func (s *ArgSpec) UsageReply(service Service, message Message, err error) string {
+	return Text(service, message, "args.usage", Vars{"problem": err, "usage": s.Usage(service)})
+}
+
+// argError returns an error describing a problem with a message's arguments in the service's language.
+func argError(service Service, id string, vars Vars) error {
+	return errors.New(Text(service, nil, id, vars))
+}
+
+// Args are the arguments parsed from a command.
+type Args struct {
+	// Raw is everything after the command's name.
+	Raw string
+	// Parts is Raw split into words.
+	Parts []string
+
+	values map[string]string
+	ints   map[string]int
+}
+
+// String returns the named argument as written.
+func (a Args) String(name string) string {
+	return a.values[name]
+}
+
+// Int returns the named int or minute argument.
+func (a Args) Int(name string) int {
+	return a.ints[name]
+}
+
+// Parse parses a message's arguments. Messages with arguments that don't match the spec return an error
+// describing the problem, which is meant to be shown to the user with UsageReply.
+// A spec without arguments accepts anything after the command's name.
+func (s *ArgSpec) Parse(service Service, message Message) (Args, error) {
+	return s.ParseString(service, message.Message())
+}
+
+// ParseString parses the arguments of a message string.
+func (s *ArgSpec) ParseString(service Service, message string) (Args, error) {
+	message, _ = trimCommandPrefix(service, strings.TrimSpace(message))
+	words := strings.Fields(message)
+
+	for _, name := range strings.Fields(s.Name) {
+		if len(words) == 0 || !strings.EqualFold(words[0], name) {
+			return Args{}, argError(service, "args.expected", Vars{"word": strconv.Quote(name)})
+		}
+		words = words[1:]
+	}
+
+	args := Args{
+		Raw:    strings.Join(words, " "),
+		Parts:  words,
+		values: map[string]string{},
+		ints:   map[string]int{},
+	}
+
+	for i, t := range s.tokens {
+		if t.literal != "" {
+			if len(words) == 0 || !strings.EqualFold(words[0], t.literal) {
+				return Args{}, argError(service, "args.expected", Vars{"word": strconv.Quote(t.literal)})
+			}
+			words = words[1:]
+			continue
+		}
+		if len(words) == 0 {
+			return Args{}, argError(service, "args.missing", Vars{"name": t.name})
+		}
+
+		if t.kind == argText {
+			end := len(words)
+			if i+1 < len(s.tokens) && s.tokens[i+1].literal != "" {
+				for j, word := range words {
+					if j > 0 && strings.EqualFold(word, s.tokens[i+1].literal) {
+						end = j
+						break
+					}
+				}
+			}
+			args.values[t.name] = strings.Join(words[:end], " ")
+			words = words[end:]
+			continue
+		}
+
+		word := words[0]
+		words = words[1:]
+		args.values[t.name] = word
+
+		switch t.kind {
+		case argInt:
+			n, err := strconv.Atoi(word)
+			if err != nil {
+				return Args{}, argError(service, "args.int", Vars{"name": t.name})
+			}
+			if t.ranged && (n < t.min || n > t.max) {
+				return Args{}, argError(service, "args.range", Vars{"name": t.name, "min": t.min, "max": t.max})
+			}
+			args.ints[t.name] = n
+		case argMinute:
+			n, err := strconv.Atoi(strings.TrimPrefix(word, ":"))
+			if err != nil || n < 0 || n > 59 {
+				return Args{}, argError(service, "args.minute", Vars{"name": t.name})
+			}
+			args.ints[t.name] = n
+		}
+	}
+
+	if len(words) > 0 && len(s.tokens) > 0 {
+		return Args{}, argError(service, "args.unexpected", Vars{"words": strconv.Quote(strings.Join(words, " "))})
+	}
+	return args, nil
+}
@@ -0,0 +1,60 @@
+package boltstore
+
+import (
+	"time"
+
+	"github.com/todd-beckman/mmmorty"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Store is a mmmorty.Store backed by an embedded bbolt database.
+// Each service gets a bucket, and each plugin's data is stored under its name.
+type Store struct {
+	db *bolt.DB
+}
+
+var _ mmmorty.Store = (*Store)(nil)
+
+// Open opens or creates the database at path.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{
+		db: db,
+	}, nil
+}
+
+// Load returns the data saved for a plugin, or nil if nothing has been saved yet.
+func (s *Store) Load(service, plugin string) ([]byte, error) {
+	var data []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(service))
+		if b == nil {
+			return nil
+		}
+		if v := b.Get([]byte(plugin)); v != nil {
+			// Values are only valid for the life of the transaction.
+			data = append([]byte{}, v...)
+		}
+		return nil
+	})
+	return data, err
+}
+
+// Save stores the data for a plugin.
+func (s *Store) Save(service, plugin string, data []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(service))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(plugin), data)
+	})
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
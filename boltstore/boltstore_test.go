@@ -0,0 +1,53 @@
+package boltstore
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mmmorty.db")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := s.Load("Discord", "Quote"); err != nil || data != nil {
+		t.Errorf("loaded Quote as %q, %v without a bucket, expected nil", data, err)
+	}
+	if err := s.Save("Discord", "Quote", []byte(`{"quotes":{}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := s.Load("Discord", "Words"); err != nil || data != nil {
+		t.Errorf("loaded Words as %q, %v before saving it, expected nil", data, err)
+	}
+
+	saved := map[string]string{"Quote": `{"quotes":{"g0":[]}}`, "Words": `{"words":{}}`}
+	for plugin, data := range saved {
+		if err := s.Save("Discord", plugin, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(when string) {
+		t.Helper()
+		for plugin, expected := range saved {
+			data, err := s.Load("Discord", plugin)
+			if err != nil || string(data) != expected {
+				t.Errorf("loaded %s as %q, %v %s, expected %q", plugin, data, err, when, expected)
+			}
+		}
+		if data, err := s.Load("Console", "Quote"); err != nil || data != nil {
+			t.Errorf("loaded another service's Quote as %q, %v %s, expected nil", data, err, when)
+		}
+	}
+	check("after saving")
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = Open(path); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	check("after reopening")
+}
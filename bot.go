@@ -1,122 +1,663 @@
 package mmmorty
 
 import (
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"log"
-	"os"
+	"io"
+	"log/slog"
+	"reflect"
 	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
 // VersionString is the current version of the bot
 const VersionString string = "0.11"
 
 type serviceEntry struct {
-	Discord
-	Plugins         map[string]Plugin
-	messageChannels []chan DiscordMessage
+	Service
+	Plugins    map[string]Plugin
+	core       map[string]bool // plugins every service has, which servers can't turn off
+	router     *router
+	dispatcher *dispatcher
+	received   <-chan Message
+	stop       chan struct{} // closed to stop taking messages from the service
+	stopped    chan struct{} // closed once the bot has stopped taking messages from the service
 }
 
 // Bot enables registering of Services and Plugins.
 type Bot struct {
 	Services    map[string]*serviceEntry
+	Store       Store
 	ImgurID     string
 	ImgurAlbum  string
 	MashableKey string
+
+	// Workers is the number of messages each service handles at once.
+	Workers int
+	// QueueSize is the number of messages each service queues before dropping edits and blocking on creates.
+	QueueSize int
+	// HandlerTimeout is how long a plugin can take to handle a message before the bot moves on without it.
+	HandlerTimeout time.Duration
+	// Config is the configuration that can change while the bot runs, such as its language and limits.
+	Config
+	// Now returns the time commands are used at for their cooldowns, tests can replace it to control the clock.
+	Now func() time.Time
+	// AfterFunc calls f in its own goroutine once d has passed, for plugins' timers. Tests replace it along with Now so
+	// timers follow the same clock.
+	AfterFunc func(d time.Duration, f func()) Timer
+	// Logger is where the bot logs. Lines logged while handling a message carry its guild, channel, user, plugin,
+	// command and correlation ID.
+	Logger *slog.Logger
+	// Metrics is told what the bot is doing so it can be measured.
+	Metrics Metrics
+
+	cooldowns *cooldowns
+	replyLog  *replyLog
+	started   time.Time
+	configMu  sync.RWMutex
+
+	// Each plugin's state is guarded by its own lock, shared plugin instances share a lock.
+	locksMu sync.Mutex
+	locks   map[Plugin]*sync.Mutex
 }
 
 // MessageRecover is the default panic handler
-func (b *Bot) MessageRecover(discord Discord, channel string) {
+func (b *Bot) MessageRecover(service Service, channel string) {
 	if r := recover(); r != nil {
 		panic := fmt.Sprintf("%s", r)
 		// log first
-		log.Println(panic)
-		log.Println("Recovered:", string(debug.Stack()))
+		Logger(service).Error("Recovered from panic", "panic", panic, "stack", string(debug.Stack()))
+		b.Metrics.PanicRecovered(service.Name())
 
-		// notify owner
-		owner := fmt.Sprintf("<@%s>", discord.OwnerUserID)
-		discord.SendMessage(channel, fmt.Sprintf("%s: Something went wrong. Summary: %s", owner, panic))
+		// notify owner, with the correlation ID to find it in the log
+		owner := fmt.Sprintf("<@%s>", service.BotOwnerID())
+		summary := panic
+		if g, ok := service.(*guildService); ok && g.correlationID != "" {
+			summary = fmt.Sprintf("%s (correlation %s)", panic, g.correlationID)
+		}
+		service.SendMessage(channel, fmt.Sprintf("%s: Something went wrong. Summary: %s", owner, summary))
 	}
 }
 
 // NewBot will create a new bot.
+// Plugin data is stored in files under the working directory unless Store is replaced before Open.
 func NewBot() *Bot {
 	return &Bot{
-		Services: make(map[string]*serviceEntry, 0),
+		Services:       make(map[string]*serviceEntry, 0),
+		Store:          NewFileStore("."),
+		Workers:        8,
+		QueueSize:      1000,
+		HandlerTimeout: 30 * time.Second,
+		Config: Config{
+			Language:        DefaultLanguage,
+			DisabledPlugins: map[string]bool{},
+		},
+		Now:       time.Now,
+		AfterFunc: func(d time.Duration, f func()) Timer { return time.AfterFunc(d, f) },
+		Logger:    slog.Default(),
+		Metrics:   noMetrics{},
+		cooldowns: newCooldowns(),
+		replyLog:  newReplyLog(),
+		locks:     map[Plugin]*sync.Mutex{},
+	}
+}
+
+func (b *Bot) pluginLock(plugin Plugin) *sync.Mutex {
+	b.locksMu.Lock()
+	defer b.locksMu.Unlock()
+
+	l, ok := b.locks[plugin]
+	if !ok {
+		l = &sync.Mutex{}
+		b.locks[plugin] = l
+	}
+	return l
+}
+
+// LockPlugin locks a plugin's state.
+// The bot holds this lock while calling a plugin's Load, Save and Message, so plugins only need it
+// when they change state from their own goroutines, such as timers.
+func (b *Bot) LockPlugin(plugin Plugin) {
+	b.pluginLock(plugin).Lock()
+}
+
+// lockPluginBy locks a plugin's state, giving up if it's still locked at the deadline.
+func (b *Bot) lockPluginBy(plugin Plugin, deadline time.Time) bool {
+	l := b.pluginLock(plugin)
+	if l.TryLock() {
+		return true
+	}
+	locked := make(chan struct{})
+	go func() {
+		l.Lock()
+		close(locked)
+	}()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-locked:
+		return true
+	case <-timer.C:
+		// Let it go again once the plugin is done with it.
+		go func() {
+			<-locked
+			l.Unlock()
+		}()
+		return false
+	}
+}
+
+// UnlockPlugin unlocks a plugin's state.
+func (b *Bot) UnlockPlugin(plugin Plugin) {
+	b.pluginLock(plugin).Unlock()
+}
+
+func (b *Bot) getData(service Service, plugin Plugin) []byte {
+	data, err := b.Store.Load(service.Name(), plugin.Name())
+	if err != nil {
+		b.Logger.Error("Error loading plugin", "service", service.Name(), "plugin", plugin.Name(), "err", err)
+		return nil
+	}
+	return data
+}
+
+// loadData migrates saved data to the plugin's current version and loads it into the plugin.
+func (b *Bot) loadData(service Service, plugin Plugin, data []byte) error {
+	if data != nil {
+		from, to, migrated, err := migrateData(plugin.Name(), data)
+		if err != nil {
+			return err
+		}
+		if from != to {
+			b.Logger.Info("Migrated plugin", "service", service.Name(), "plugin", plugin.Name(), "from", from, "to", to)
+		}
+		data = migrated
+		if err := checkDecodes(plugin, data); err != nil {
+			return err
+		}
+	}
+	return plugin.Load(b, service, data)
+}
+
+// checkDecodes returns an error if JSON data doesn't decode into a new plugin of the same type. Plugins decode their
+// data into themselves, and JSON that only partly decodes would leave some of it behind, mixed into the backup loaded
+// next. JSON that isn't valid is never decoded at all, and data that isn't JSON is up to the plugin.
+func checkDecodes(plugin Plugin, data []byte) error {
+	t := reflect.TypeOf(plugin)
+	if !json.Valid(data) || t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return nil
 	}
+	return json.Unmarshal(data, reflect.New(t.Elem()).Interface())
 }
 
-func (b *Bot) getData(service Discord, plugin Plugin) []byte {
-	if b, err := ioutil.ReadFile(service.Name() + "/" + plugin.Name()); err == nil {
-		return b
+// loadPlugin loads a plugin's saved data, falling back to the newest backup the plugin accepts if the data is corrupt.
+func (b *Bot) loadPlugin(service Service, plugin Plugin) {
+	b.LockPlugin(plugin)
+	defer b.UnlockPlugin(plugin)
+
+	err := b.loadData(service, plugin, b.getData(service, plugin))
+	if err == nil {
+		return
+	}
+	logger := b.Logger.With("service", service.Name(), "plugin", plugin.Name())
+	logger.Error("Error loading plugin", "err", err)
+
+	store, ok := b.Store.(BackupStore)
+	if !ok {
+		return
 	}
-	return nil
+	backups, err := store.LoadBackups(service.Name(), plugin.Name())
+	if err != nil {
+		logger.Error("Error loading backups for plugin", "err", err)
+		return
+	}
+	for i, data := range backups {
+		if err := b.loadData(service, plugin, data); err == nil {
+			logger.Warn("Loaded plugin from backup", "backup", i+1)
+			return
+		}
+	}
+	logger.Warn("No valid backup for plugin, starting empty")
 }
 
 // RegisterService registers a service with the bot.
-func (b *Bot) RegisterService(service Discord) {
+func (b *Bot) RegisterService(service Service) {
 	if b.Services[service.Name()] != nil {
-		log.Println("Service with that name already registered", service.Name())
+		b.Logger.Warn("Service with that name already registered", "service", service.Name())
 	}
 	serviceName := service.Name()
 	b.Services[serviceName] = &serviceEntry{
-		Discord: service,
+		Service: service,
 		Plugins: make(map[string]Plugin, 0),
+		core:    map[string]bool{},
+		router:  newRouter(),
+	}
+	for _, plugin := range []Plugin{NewHelpPlugin(), NewPrefixPlugin(), NewLanguagePlugin(), NewReplyPlugin(), NewTogglePlugin(), NewPermissionPlugin(), NewCooldownPlugin(), NewStatsPlugin()} {
+		b.RegisterPlugin(service, plugin)
+		b.Services[serviceName].core[plugin.Name()] = true
 	}
-	b.RegisterPlugin(service, NewHelpPlugin())
 }
 
 // RegisterPlugin registers a plugin on a service.
-func (b *Bot) RegisterPlugin(service Discord, plugin Plugin) {
+// A plugin with a command another plugin already registered is not registered.
+func (b *Bot) RegisterPlugin(service Service, plugin Plugin) {
 	s := b.Services[service.Name()]
+	if commander, ok := plugin.(Commander); ok {
+		if err := s.router.add(plugin, commander.Commands()); err != nil {
+			b.Logger.Error("Plugin not registered", "service", service.Name(), "plugin", plugin.Name(), "err", err)
+			return
+		}
+	} else {
+		s.router.remove(plugin.Name())
+	}
 	if s.Plugins[plugin.Name()] != nil {
-		log.Println("Plugin with that name already registered", plugin.Name())
+		b.Logger.Warn("Plugin with that name already registered", "service", service.Name(), "plugin", plugin.Name())
 	}
 	s.Plugins[plugin.Name()] = plugin
 }
 
-func (b *Bot) listen(service Discord, messageChan <-chan *DiscordMessage) {
-	serviceName := service.Name()
+// guildKey returns the guild a message was sent in, or its channel for private messages.
+func guildKey(service Service, message Message) string {
+	if c, err := service.Channel(message.Channel()); err == nil && c.GuildID != "" {
+		return c.GuildID
+	}
+	return message.Channel()
+}
 
+// listen queues the messages a service receives until it's stopped. Messages the service has already received when
+// it's stopped are still queued.
+func (b *Bot) listen(service *serviceEntry) {
+	defer close(service.stopped)
+
+	receive := func(message Message) {
+		b.Metrics.MessageReceived(service.Name(), message.Type())
+		service.dispatcher.push(message)
+	}
 	for {
-		message := <-messageChan
-		//log.Printf("<%s> %s: %s\n", message.Channel(), message.UserName(), message.Message())
-		plugins := b.Services[serviceName].Plugins
-		for _, plugin := range plugins {
-			go plugin.Message(b, service, *message)
+		select {
+		case message := <-service.received:
+			receive(message)
+		case <-service.stop:
+			for {
+				select {
+				case message := <-service.received:
+					receive(message)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// sortedPlugins returns the plugins on a service ordered by name, so they always see messages in the same order.
+func (b *Bot) sortedPlugins(service Service) []Plugin {
+	plugins := b.Services[service.Name()].Plugins
+
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sorted := make([]Plugin, len(names))
+	for i, name := range names {
+		sorted[i] = plugins[name]
+	}
+	return sorted
+}
+
+// pluginCall is one plugin's part in handling a message.
+type pluginCall struct {
+	plugin Plugin
+	// service is the service the plugin sees, logging with the plugin and command.
+	service Service
+	// command is the command the call runs, if it runs one.
+	command string
+	call    func()
+}
+
+// messageCalls returns the calls that handle a message, and what to call once they have. A message that matches a
+// registered command runs only that command, anything else is passed to every plugin's Message in turn.
+// Plugins see the service as it is in the message's guild, so commands there honor the guild's prefix, and
+// slash commands are answered through the interaction. It logs with the plugin and command as well as the message.
+// When a message the bot replied to is edited, the new replies edit the old ones and the rest of the old ones are
+// deleted once it's handled, so an edit that isn't a command any more takes the replies away. Edits only run commands
+// that can Rerun, and only replies to those are changed. Messages that must be answered, like slash commands, get a
+// reply once they're handled if nothing answered them.
+func (b *Bot) messageCalls(service Service, message Message) ([]pluginCall, func()) {
+	if r, ok := message.(Replier); ok {
+		service = r.ReplyService(service)
+	}
+	answerer, _ := service.(Answerer)
+	_, isInteraction := service.(EphemeralSender)
+	g := b.guildService(service, message).(*guildService)
+	g.correlationID = newCorrelationID()
+	g.logger = g.logger.With("user", message.UserID(), "correlation", g.correlationID)
+	g.logger.Debug("Handling message", "type", message.Type(), "message", message.MessageID())
+	service = g
+
+	key := replyKey(service, message)
+	previous, known := b.replyLog.get(key)
+	edited := message.Type() == MessageTypeUpdate && known
+	if edited && previous.content == message.Message() {
+		g.logger.Debug("Message edited without changing")
+		return nil, func() {}
+	}
+	// Whether the replies can be changed if the message is edited.
+	rerun := true
+	done := func() {}
+	if e, ok := g.Service.(MessageEditor); ok && !isInteraction && message.Type() != MessageTypeDelete {
+		g.sent = &replies{editor: e}
+		if edited {
+			g.sent.previous = previous.replies
+		}
+		done = func() {
+			if sent := g.sent.done(g); rerun && (len(sent) > 0 || known) {
+				b.replyLog.set(key, answered{content: message.Message(), replies: sent})
+			}
+		}
+	}
+	if answerer != nil {
+		handled := done
+		done = func() {
+			handled()
+			if err := answerer.FinishAnswer(Text(g, message, "error.silent", nil)); err != nil {
+				g.logger.Error("Error answering message", "err", err)
+			}
+		}
+	}
+
+	if c, ok := b.Services[service.Name()].router.match(service, message); ok {
+		logger := g.logger.With("plugin", c.plugin.Name(), "command", c.name)
+		service := withLogger(service, logger)
+		call := pluginCall{plugin: c.plugin, service: service}
+		if message.Type() == MessageTypeUpdate && !c.rerun {
+			logger.Debug("Edited command can't run again")
+			return nil, func() {}
+		}
+		rerun = c.rerun
+
+		if !b.PluginEnabled(service, c.plugin) {
+			logger.Debug("Plugin is off")
+			// Slash commands must be answered, typed commands are ignored as if the plugin wasn't there.
+			if !isInteraction {
+				return nil, done
+			}
+			call.call = func() {
+				reply := Text(service, message, "toggle.blocked", Vars{"plugin": strings.ToLower(c.plugin.Name())})
+				SendEphemeral(service, message.Channel(), reply)
+			}
+			return []pluginCall{call}, done
+		}
+		if !b.commandAllowed(service, message, c) {
+			logger.Debug("Command denied")
+			call.call = func() {
+				SendEphemeral(service, message.Channel(), Text(service, message, "error.denied", nil))
+			}
+			return []pluginCall{call}, done
+		}
+		// Fixing a command that was already answered only waits for the member's own cooldown, so editing can't
+		// re-roll without limit, and its replies stay as they were until it can run again.
+		fixing := edited && len(previous.replies) > 0
+		if wait, notice := b.coolDown(service, message, c, fixing); wait > 0 {
+			logger.Debug("Command cooling down", "wait", wait)
+			if fixing {
+				return nil, func() {}
+			}
+			// Slash commands must be answered, typed commands only get the one notice.
+			if !notice && !isInteraction {
+				return nil, done
+			}
+			call.call = func() {
+				vars := Vars{"command": c.name, "count": int((wait + time.Second - 1) / time.Second)}
+				SendEphemeral(service, message.Channel(), Text(service, message, "cooldown.wait", vars))
+			}
+			return []pluginCall{call}, done
+		}
+		logger.Debug("Running command")
+		call.command = c.name
+		call.call = func() { c.handler(b, service, message) }
+		return []pluginCall{call}, done
+	}
+
+	calls := []pluginCall{}
+	for _, plugin := range b.sortedPlugins(service) {
+		if !b.PluginEnabled(service, plugin) {
+			continue
+		}
+		plugin := plugin
+		service := withLogger(service, g.logger.With("plugin", plugin.Name()))
+		calls = append(calls, pluginCall{
+			plugin:  plugin,
+			service: service,
+			call:    func() { plugin.Message(b, service, message) },
+		})
+	}
+	return calls, done
+}
+
+// PluginEnabled returns whether a plugin is on where a service is, as returned by GuildService.
+// Plugins are on unless they're in DisabledPlugins, the configuration can turn them on or off on a server, and
+// servers can turn them on or off, or just in some channels.
+func (b *Bot) PluginEnabled(service Service, plugin Plugin) bool {
+	name := plugin.Name()
+	if b.Services[service.Name()].core[name] {
+		return true
+	}
+	if g, ok := service.(*guildService); ok {
+		if enabled, ok := g.plugins[name]; ok {
+			return enabled
+		}
+	}
+	return !b.config().DisabledPlugins[name]
+}
+
+// commandAllowed returns whether the sender of a message can use a command, by its permission or the guild's grants.
+func (b *Bot) commandAllowed(service Service, message Message, c routedCommand) bool {
+	if HasPermission(service, message, c.permission) {
+		return true
+	}
+	g, ok := service.(*guildService)
+	if !ok || g.grants[c.name] == nil {
+		return false
+	}
+	// Every member has the server's @everyone role, which has the server's ID.
+	roles := append(service.UserRoles(g.guildID, message.UserID()), g.guildID)
+	return g.grants[c.name].allows(message.UserID(), roles)
+}
+
+// coolDown uses a command, and returns how long until it can be used if the sender of a message has to wait, and
+// whether they should be told. Moderators never wait, and members fixing a command only wait for their own cooldown.
+func (b *Bot) coolDown(service Service, message Message, c routedCommand, fixing bool) (time.Duration, bool) {
+	cooldown := c.cooldown
+	guildID := ""
+	if g, ok := service.(*guildService); ok {
+		guildID = g.guildID
+		if override, ok := g.cooldowns[c.name]; ok {
+			cooldown = override
+		}
+	}
+	if cooldown == (Cooldown{}) || HasPermission(service, message, Moderator) {
+		return 0, false
+	}
+
+	keys := map[cooldownKey]time.Duration{
+		{scope: cooldownUser, id: guildID + "/" + message.UserID(), command: c.name}: cooldown.User,
+	}
+	if !fixing {
+		keys[cooldownKey{scope: cooldownChannel, id: message.Channel(), command: c.name}] = cooldown.Channel
+		if guildID != "" {
+			keys[cooldownKey{scope: cooldownGuild, id: guildID, command: c.name}] = cooldown.Guild
 		}
 	}
+	return b.cooldowns.use(b.Now(), message.UserID(), keys)
+}
+
+// HandleMessage handles a message, holding each plugin's lock while it runs.
+// Messages from services opened by the bot are handled by its workers, this is for handling messages synchronously.
+func (b *Bot) HandleMessage(service Service, message Message) {
+	calls, done := b.messageCalls(service, message)
+	for _, c := range calls {
+		b.runPluginCall(message, c)
+	}
+	done()
+}
+
+// QueueStats returns a snapshot of the message queue of every open service, keyed by service name.
+func (b *Bot) QueueStats() map[string]QueueStats {
+	stats := map[string]QueueStats{}
+	for name, service := range b.Services {
+		if service.dispatcher != nil {
+			s := service.dispatcher.stats()
+			s.Received = len(service.received)
+			stats[name] = s
+		}
+	}
+	return stats
+}
+
+func (b *Bot) runPluginCall(message Message, c pluginCall) {
+	b.LockPlugin(c.plugin)
+	defer b.UnlockPlugin(c.plugin)
+	defer b.MessageRecover(c.service, message.Channel())
+
+	start := time.Now()
+	defer func() {
+		b.Metrics.Handled(c.service.Name(), c.plugin.Name(), c.command, time.Since(start))
+	}()
+	c.call()
+}
+
+// Uptime returns how long the bot has been open.
+func (b *Bot) Uptime() time.Duration {
+	if b.started.IsZero() {
+		return 0
+	}
+	return b.Now().Sub(b.started).Round(time.Second)
 }
 
 // Open will open all the current services and begins listening.
 func (b *Bot) Open() {
+	b.started = b.Now()
 	for _, service := range b.Services {
 		if messageChan, err := service.Open(); err == nil {
 			for _, plugin := range service.Plugins {
-				plugin.Load(b, service.Discord, b.getData(service.Discord, plugin))
+				b.loadPlugin(service.Service, plugin)
 			}
-			go b.listen(service.Discord, messageChan)
+			if sc, ok := service.Service.(SlashCommander); ok {
+				if err := sc.RegisterSlashCommands(b.SlashCommands(service.Service)); err != nil {
+					b.Logger.Error("Error registering slash commands", "service", service.Name(), "err", err)
+				}
+			}
+			service.dispatcher = newDispatcher(b, service.Service, b.Workers, b.QueueSize, b.HandlerTimeout)
+			service.dispatcher.start()
+			service.received = messageChan
+			service.stop = make(chan struct{})
+			service.stopped = make(chan struct{})
+			go b.listen(service)
 		} else {
-			log.Printf("Error creating service %s: %v\n", service.Name(), err)
+			b.Logger.Error("Error creating service", "service", service.Name(), "err", err)
 		}
 	}
 }
 
+// Close shuts the bot down. It stops taking messages from its services and waits up to timeout for the messages it
+// has taken to be handled, dropping any that are left, then lets plugins that are Closers tidy up, closes services that are io.Closers, such as
+// Discord's sessions, and saves. Plugins still handling a message when the timeout is up are neither closed nor saved,
+// and services that failed to open are left alone.
+// The bot can't be opened again.
+func (b *Bot) Close(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	var wg sync.WaitGroup
+	for _, service := range b.Services {
+		if service.dispatcher == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(service *serviceEntry) {
+			defer wg.Done()
+			close(service.stop)
+			<-service.stopped
+			if dropped := service.dispatcher.close(timeout); dropped > 0 {
+				b.Logger.Warn("Gave up waiting for messages to be handled", "service", service.Name(), "timeout", timeout, "dropped", dropped)
+			}
+		}(service)
+	}
+	wg.Wait()
+
+	for _, service := range b.Services {
+		if service.dispatcher == nil {
+			continue
+		}
+		for _, plugin := range b.sortedPlugins(service.Service) {
+			c, ok := plugin.(Closer)
+			if !ok {
+				continue
+			}
+			if !b.lockPluginBy(plugin, deadline) {
+				b.Logger.Error("Gave up waiting to close plugin", "service", service.Name(), "plugin", plugin.Name(), "timeout", timeout)
+				continue
+			}
+			err := c.Close(b, service.Service)
+			b.UnlockPlugin(plugin)
+			if err != nil {
+				b.Logger.Error("Error closing plugin", "service", service.Name(), "plugin", plugin.Name(), "err", err)
+			}
+		}
+	}
+
+	for _, service := range b.Services {
+		if service.dispatcher == nil {
+			continue
+		}
+		if c, ok := service.Service.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				b.Logger.Error("Error closing service", "service", service.Name(), "err", err)
+			}
+		}
+	}
+
+	b.save(deadline)
+}
+
 // Save will save the current plugin state for all plugins on all services.
 func (b *Bot) Save() {
+	b.save(time.Time{})
+}
+
+// save saves every plugin, skipping plugins that are still locked at the deadline unless it's zero. Services that
+// never opened are skipped, as their plugins never loaded and saving them would overwrite their data.
+func (b *Bot) save(deadline time.Time) {
 	for _, service := range b.Services {
-		serviceName := service.Name()
-		if err := os.Mkdir(serviceName, os.ModePerm); err != nil {
-			if !os.IsExist(err) {
-				log.Println("Error creating service directory.")
-			}
+		if service.dispatcher == nil {
+			continue
 		}
+		serviceName := service.Name()
 		for _, plugin := range service.Plugins {
-			if data, err := plugin.Save(); err != nil {
-				log.Printf("Error saving plugin %s %s. %v", serviceName, plugin.Name(), err)
+			start := time.Now()
+			if deadline.IsZero() {
+				b.LockPlugin(plugin)
+			} else if !b.lockPluginBy(plugin, deadline) {
+				b.Logger.Error("Gave up waiting to save plugin", "service", serviceName, "plugin", plugin.Name())
+				continue
+			}
+			data, err := plugin.Save()
+			b.UnlockPlugin(plugin)
+
+			if err != nil {
+				b.Logger.Error("Error saving plugin", "service", serviceName, "plugin", plugin.Name(), "err", err)
 			} else if data != nil {
-				if err := ioutil.WriteFile(serviceName+"/"+plugin.Name(), data, os.ModePerm); err != nil {
-					log.Printf("Error saving plugin %s %s. %v", serviceName, plugin.Name(), err)
+				if data, err = wrapData(plugin.Name(), data); err != nil {
+					b.Logger.Error("Error saving plugin", "service", serviceName, "plugin", plugin.Name(), "err", err)
+				} else if err := b.Store.Save(serviceName, plugin.Name(), data); err != nil {
+					b.Logger.Error("Error saving plugin", "service", serviceName, "plugin", plugin.Name(), "err", err)
+				} else {
+					b.Metrics.Saved(serviceName, plugin.Name(), time.Since(start), len(data))
 				}
 			}
 		}
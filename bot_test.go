@@ -0,0 +1,222 @@
+package mmmorty_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/todd-beckman/mmmorty"
+	"github.com/todd-beckman/mmmorty/diceplugin"
+	"github.com/todd-beckman/mmmorty/mmmortytest"
+	"github.com/todd-beckman/mmmorty/quoteplugin"
+)
+
+// TestBotHandlesLoadInOrder sends many servers' messages, edits, deletes and commands through the bot at once while
+// it saves, and checks each server's messages were handled in the order they were sent. Run it with -race.
+func TestBotHandlesLoadInOrder(t *testing.T) {
+	const guilds, events = 20, 60
+	r := &recorder{}
+	service := mmmortytest.New()
+	for g := 0; g < guilds; g++ {
+		guild := fmt.Sprintf("g%d", g)
+		service.AddGuild(guild, guild, "rick")
+		service.AddChannel(guild, fmt.Sprintf("c%d", g), "general")
+		service.AddMember(guild, "jerry", "Jerry")
+	}
+
+	bot := mmmorty.NewBot()
+	bot.Store = mmmortytest.NewStore()
+	bot.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	// Room for every message, so no edits are dropped.
+	bot.QueueSize = guilds * events * 2
+	bot.RegisterService(service)
+	bot.RegisterPlugin(service, r)
+	bot.RegisterPlugin(service, quoteplugin.New())
+	bot.RegisterPlugin(service, diceplugin.New())
+	bot.Open()
+	defer bot.Close(time.Second)
+
+	// Each server sends its messages in order, alongside every other server, and edits and deletes its last message.
+	expected := make([][]string, guilds)
+	var wg sync.WaitGroup
+	for g := 0; g < guilds; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			channel := fmt.Sprintf("c%d", g)
+			var last *mmmortytest.Message
+			for i := 0; i < events; i++ {
+				content := fmt.Sprintf("g%d %03d", g, i)
+				switch {
+				case i%10 == 9:
+					service.Create(channel, "jerry", "Jerry", fmt.Sprintf("@morty add quote Jerry said %d", i))
+					service.Create(channel, "jerry", "Jerry", "@morty roll 2d6")
+					continue
+				case last != nil && i%5 == 2:
+					last = service.Update(last, content)
+				case last != nil && i%5 == 4:
+					deleted := *last
+					deleted.Content = content
+					service.Delete(&deleted)
+					last = nil
+				default:
+					last = service.Create(channel, "jerry", "Jerry", content)
+				}
+				expected[g] = append(expected[g], content)
+			}
+		}(g)
+	}
+
+	stop := make(chan struct{})
+	saved := make(chan struct{})
+	go func() {
+		defer close(saved)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Millisecond):
+				bot.Save()
+			}
+		}
+	}()
+
+	wg.Wait()
+	total := 0
+	for _, e := range expected {
+		total += len(e)
+	}
+	eventually(t, "every message to be handled", func() bool {
+		return len(r.Seen()) == total
+	})
+	close(stop)
+	<-saved
+
+	seen := make([][]string, guilds)
+	for _, s := range r.Seen() {
+		var g int
+		fmt.Sscanf(s, "g%d", &g)
+		seen[g] = append(seen[g], s)
+	}
+	for g := range expected {
+		if strings.Join(seen[g], ", ") != strings.Join(expected[g], ", ") {
+			t.Errorf("g%d's messages were handled as %v, expected %v", g, seen[g], expected[g])
+		}
+	}
+	if stats := bot.QueueStats()[service.Name()]; stats.Dropped != 0 {
+		t.Errorf("dropped %d messages, expected none", stats.Dropped)
+	}
+}
+
+func TestCloseGivesUpOnStuckPlugins(t *testing.T) {
+	r := &recorder{wait: make(chan struct{})}
+	defer close(r.wait)
+	service := mmmortytest.New()
+	service.AddGuild("g0", "g0", "rick")
+	service.AddChannel("g0", "c0", "general")
+
+	bot := mmmorty.NewBot()
+	bot.Store = mmmortytest.NewStore()
+	bot.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	bot.HandlerTimeout = 10 * time.Millisecond
+	bot.RegisterService(service)
+	bot.RegisterPlugin(service, r)
+	bot.Open()
+
+	service.Create("c0", "jerry", "jerry", "hello")
+	eventually(t, "the handler to time out", func() bool {
+		return bot.QueueStats()[service.Name()].TimedOut == 1
+	})
+
+	closed := make(chan struct{})
+	go func() {
+		bot.Close(50 * time.Millisecond)
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close waited for a plugin that was still handling a message")
+	}
+}
+
+// unopenedService is a fake service that fails to open.
+type unopenedService struct {
+	*mmmortytest.Service
+}
+
+func (s unopenedService) Open() (<-chan mmmorty.Message, error) {
+	return nil, errors.New("bad login")
+}
+
+// closeRecorder is a plugin that remembers being closed and saves whatever it has.
+type closeRecorder struct {
+	recorder
+	closed bool
+}
+
+func (r *closeRecorder) Save() ([]byte, error) { return []byte(`{"fresh":true}`), nil }
+
+func (r *closeRecorder) Close(*mmmorty.Bot, mmmorty.Service) error {
+	r.closed = true
+	return nil
+}
+
+func TestCloseLeavesUnopenedServicesAlone(t *testing.T) {
+	service := unopenedService{mmmortytest.New()}
+	store := mmmortytest.NewStore()
+	saved := []byte(`{"mmmorty_version":1,"data":{"quotes":["saved"]}}`)
+	store.Save(service.Name(), "Recorder", saved)
+
+	bot := mmmorty.NewBot()
+	bot.Store = store
+	bot.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	r := &closeRecorder{}
+	bot.RegisterService(service)
+	bot.RegisterPlugin(service, r)
+	bot.Open()
+	bot.Close(time.Second)
+
+	if r.closed {
+		t.Error("closed a plugin on a service that never opened")
+	}
+	if data, _ := store.Load(service.Name(), "Recorder"); string(data) != string(saved) {
+		t.Errorf("saved %s over the data a service that never opened didn't load", data)
+	}
+}
+
+func TestAddedCommandsCheckPermission(t *testing.T) {
+	service := mmmortytest.New()
+	service.OwnerUserID = "rick"
+	service.AddGuild("g0", "g0", "beth")
+	service.AddChannel("g0", "c0", "general")
+
+	quits := 0
+	cp := mmmorty.NewCommandPlugin()
+	cp.AddCommand("quit", mmmorty.BotOwner, func(*mmmorty.Bot, mmmorty.Service, mmmorty.Message, mmmorty.Args) {
+		quits++
+	}, nil)
+	bot := mmmorty.NewBot()
+	bot.Store = mmmortytest.NewStore()
+	bot.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	bot.RegisterService(service)
+	bot.RegisterPlugin(service, cp)
+
+	bot.HandleMessage(service, service.Create("c0", "beth", "Beth", "@morty quit"))
+	if quits != 0 {
+		t.Error("the server's owner used a command only the bot's owner can")
+	}
+	if sent := service.Messages(); len(sent) != 1 || !strings.Contains(sent[0].Content, "let you do that") {
+		t.Errorf("the server's owner was told %v, expected they can't quit", sent)
+	}
+
+	bot.HandleMessage(service, service.Create("c0", "rick", "Rick", "@morty quit"))
+	if quits != 1 {
+		t.Error("the bot's owner couldn't quit")
+	}
+}
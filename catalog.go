@@ -0,0 +1,276 @@
+package mmmorty
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultLanguage is the language Morty replies in unless a server picks another, and the language used for any
+// message that hasn't been translated.
+const DefaultLanguage = "en"
+
+var placeholderRegex = regexp.MustCompile(`\{(\w+)\}`)
+
+// Vars are the values of a message's placeholders, eg. Vars{"word": "tacos"} for "I think {word} is...".
+type Vars map[string]interface{}
+
+// PluralFunc returns which form of a message a number uses, "one" or "other".
+type PluralFunc func(n int) string
+
+type language struct {
+	name     string
+	plural   PluralFunc
+	messages map[string]string
+}
+
+var (
+	catalogMu sync.RWMutex
+	catalog   = map[string]*language{}
+	required  = map[string][]string{}
+	samples   = map[string]Vars{}
+)
+
+// englishPlural is the plural rule for English and Spanish, where only 1 is singular.
+func englishPlural(n int) string {
+	if n == 1 {
+		return "one"
+	}
+	return "other"
+}
+
+// RegisterLanguage registers a language Morty can reply in, eg. RegisterLanguage("es", "Español", nil).
+// The plural func picks a message's form for a number, nil means only 1 is singular.
+func RegisterLanguage(code, name string, plural PluralFunc) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	if plural == nil {
+		plural = englishPlural
+	}
+	l := catalog[code]
+	if l == nil {
+		l = &language{messages: map[string]string{}}
+		catalog[code] = l
+	}
+	l.name = name
+	l.plural = plural
+}
+
+// RegisterMessages registers the templates of messages in a language, keyed by ID, eg. "quote.added".
+// Placeholders are written {name} and filled from the message's Vars. A message that depends on a number is
+// registered as <id>.one and <id>.other, and the form is picked by the "count" var.
+// Plugins should register their messages in init, and must register English messages for every ID they use.
+func RegisterMessages(code string, messages map[string]string) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	l := catalog[code]
+	if l == nil {
+		l = &language{plural: englishPlural, messages: map[string]string{}}
+		catalog[code] = l
+	}
+	for id, template := range messages {
+		if _, ok := l.messages[id]; ok {
+			panic(fmt.Sprintf("mmmorty: message %s already registered for %s", id, code))
+		}
+		l.messages[id] = template
+	}
+}
+
+// RequirePlaceholders registers the placeholders a server's own version of a message must keep, keyed by ID, eg.
+// "sprint.start": {"sprinters"} so that a sprint still mentions its sprinters.
+func RequirePlaceholders(placeholders map[string][]string) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	for id, names := range placeholders {
+		required[id] = append(required[id], names...)
+	}
+}
+
+// RegisterSamples registers sample values for placeholders, keyed by a message ID or the start of one, eg.
+// "sprint": {"sprinters": "@Summer @Jerry"} for every sprint message. Previews of a server's own version of a message
+// fill in its placeholders with the samples of the longest key it starts with, and "" is for every message.
+func RegisterSamples(s map[string]Vars) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	for key, vars := range s {
+		if samples[key] == nil {
+			samples[key] = Vars{}
+		}
+		for name, v := range vars {
+			samples[key][name] = v
+		}
+	}
+}
+
+// sampleVars returns sample values for a message's placeholders.
+func sampleVars(id string) Vars {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	vars := Vars{}
+	parts := strings.Split(id, ".")
+	for i := 0; i <= len(parts); i++ {
+		for name, v := range samples[strings.Join(parts[:i], ".")] {
+			vars[name] = v
+		}
+	}
+	return vars
+}
+
+// Languages returns the codes of every language Morty can reply in.
+func Languages() []string {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	codes := []string{}
+	for code, l := range catalog {
+		if l.name != "" {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// LanguageName returns the name of a language in that language, or "" if it isn't registered.
+func LanguageName(code string) string {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	if l, ok := catalog[code]; ok {
+		return l.name
+	}
+	return ""
+}
+
+// Language returns the language Morty replies in on a service, which plugins see as it is in the message's server.
+func Language(service Service) string {
+	if l, ok := service.(interface{ Language() string }); ok && l.Language() != "" {
+		return l.Language()
+	}
+	return DefaultLanguage
+}
+
+// template returns a message's template in a language, falling back to English.
+func template(code, id string, vars Vars) (string, bool) {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	for _, c := range []string{code, DefaultLanguage} {
+		l, ok := catalog[c]
+		if !ok {
+			continue
+		}
+		if count, ok := vars["count"].(int); ok {
+			if t, ok := l.messages[id+"."+l.plural(count)]; ok {
+				return t, true
+			}
+		}
+		if t, ok := l.messages[id]; ok {
+			return t, true
+		}
+	}
+	return "", false
+}
+
+// placeholders returns the names of the placeholders in a template.
+func placeholders(template string) []string {
+	names := []string{}
+	for _, match := range placeholderRegex.FindAllStringSubmatch(template, -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
+
+// messagePlaceholders returns the placeholders an English message can use and the ones a server's own version of it
+// must keep, or false if there is no such message. The ID can name a message or one of its forms, eg. "sprint.start.one".
+func messagePlaceholders(id string) (known, needed []string, ok bool) {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	l, found := catalog[DefaultLanguage]
+	if !found {
+		return nil, nil, false
+	}
+	seen := map[string]bool{}
+	for _, form := range []string{id, id + ".one", id + ".other"} {
+		t, found := l.messages[form]
+		if !found {
+			continue
+		}
+		ok = true
+		for _, name := range placeholders(t) {
+			if !seen[name] {
+				seen[name] = true
+				known = append(known, name)
+			}
+		}
+	}
+	if !ok {
+		return nil, nil, false
+	}
+
+	needed = required[id]
+	for _, form := range []string{".one", ".other"} {
+		if strings.HasSuffix(id, form) {
+			needed = append(needed, required[strings.TrimSuffix(id, form)]...)
+		}
+	}
+	sort.Strings(known)
+	return known, needed, true
+}
+
+// reply returns a server's own template for a message, which is used in place of the catalog's in any language.
+func reply(service Service, id string, vars Vars) (string, bool) {
+	r, ok := service.(interface {
+		Reply(id string) (string, bool)
+	})
+	if !ok {
+		return "", false
+	}
+	if count, ok := vars["count"].(int); ok {
+		if t, ok := r.Reply(id + "." + englishPlural(count)); ok {
+			return t, true
+		}
+	}
+	return r.Reply(id)
+}
+
+// Format fills a template's placeholders. Placeholders without a var are left as written.
+func Format(template string, vars Vars) string {
+	return placeholderRegex.ReplaceAllStringFunc(template, func(placeholder string) string {
+		if v, ok := vars[placeholder[1:len(placeholder)-1]]; ok {
+			return fmt.Sprint(v)
+		}
+		return placeholder
+	})
+}
+
+// Text returns a message in the service's language, or as the server has chosen to word it. When message isn't nil {user} mentions whoever sent it,
+// unless vars says otherwise. Messages that aren't registered return their ID.
+func Text(service Service, message Message, id string, vars Vars) string {
+	if message != nil {
+		if _, ok := vars["user"]; !ok {
+			withUser := Vars{"user": fmt.Sprintf("<@%s>", message.UserID())}
+			for k, v := range vars {
+				withUser[k] = v
+			}
+			vars = withUser
+		}
+	}
+
+	t, ok := reply(service, id, vars)
+	if !ok {
+		t, ok = template(Language(service), id, vars)
+	}
+	if !ok {
+		return id
+	}
+	return Format(t, vars)
+}
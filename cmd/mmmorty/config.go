@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/todd-beckman/mmmorty"
+	"gopkg.in/yaml.v3"
+)
+
+// configFile is a YAML configuration file, given with -config. Anything it sets is used instead of its flag's default,
+// but flags given on the command line always win.
+//
+// For example:
+//
+//	discord:
+//	  token: abc123
+//	  ownerUserID: "1234"
+//	dataDir: /var/lib/mmmorty
+//	plugins:
+//	  war: true
+//	limits:
+//	  quotes: 1000
+//	guilds:
+//	  "5678":
+//	    language: es
+//	    limits:
+//	      quotes: 5000
+type configFile struct {
+	Discord  discordConfig          `yaml:"discord"`
+	DataDir  string                 `yaml:"dataDir"`
+	Store    string                 `yaml:"store"`
+	Language string                 `yaml:"language"`
+	Plugins  map[string]bool        `yaml:"plugins"` // plugins on or off everywhere, named as with the enable command
+	Limits   map[string]int         `yaml:"limits"`  // the most plugins allow, eg. "quotes"
+	Guilds   map[string]guildConfig `yaml:"guilds"`  // changes to the rest on some servers, by guild ID
+}
+
+// discordConfig is how to connect to Discord. Changing it takes a restart.
+type discordConfig struct {
+	Token               string `yaml:"token"`
+	Email               string `yaml:"email"`
+	Password            string `yaml:"password"`
+	ApplicationClientID string `yaml:"applicationClientID"`
+	OwnerUserID         string `yaml:"ownerUserID"`
+	Shards              int    `yaml:"shards"`
+}
+
+type guildConfig struct {
+	Language string          `yaml:"language"`
+	Plugins  map[string]bool `yaml:"plugins"`
+	Limits   map[string]int  `yaml:"limits"`
+}
+
+// readConfig reads a configuration file. Settings it doesn't know are errors, so typos don't go unnoticed.
+func readConfig(path string) (*configFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &configFile{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	errs := []error{}
+	if c.Store != "" && c.Store != "file" && c.Store != "bolt" {
+		errs = append(errs, fmt.Errorf("%s: unknown store %q, expected \"file\" or \"bolt\"", path, c.Store))
+	}
+	if c.Discord.Shards < 0 {
+		errs = append(errs, fmt.Errorf("%s: discord needs at least 1 shard, not %d", path, c.Discord.Shards))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// setOnCommandLine returns the names of the flags given on the command line.
+func setOnCommandLine() map[string]bool {
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
+}
+
+// useConfig sets the flags that can't change while the bot runs from a configuration file, unless they were given on
+// the command line.
+func useConfig(c *configFile) {
+	set := setOnCommandLine()
+	use := func(name string, value *string, configured string) {
+		if configured != "" && !set[name] {
+			*value = configured
+		}
+	}
+	use("discordtoken", &discordToken, c.Discord.Token)
+	use("discordemail", &discordEmail, c.Discord.Email)
+	use("discordpassword", &discordPassword, c.Discord.Password)
+	use("discordapplicationclientid", &discordApplicationClientID, c.Discord.ApplicationClientID)
+	use("discordowneruserid", &discordOwnerUserID, c.Discord.OwnerUserID)
+	use("datadir", &dataDir, c.DataDir)
+	use("store", &storeType, c.Store)
+	if c.Discord.Shards > 0 && !set["discordshards"] {
+		discordShards = c.Discord.Shards
+	}
+}
+
+// needsRestart returns whether the settings that can't change while the bot runs differ between two configuration
+// files.
+func needsRestart(before, after *configFile) bool {
+	return before.Discord != after.Discord || before.DataDir != after.DataDir || before.Store != after.Store
+}
+
+// botConfig returns the configuration of the bot from its flags and a configuration file, which may be nil. Set names
+// the flags given on the command line, which win over the file.
+func botConfig(c *configFile, set map[string]bool) mmmorty.Config {
+	if c == nil {
+		c = &configFile{}
+	}
+
+	config := mmmorty.Config{
+		Language:        language,
+		DisabledPlugins: map[string]bool{},
+		Limits:          c.Limits,
+		Guilds:          map[string]mmmorty.GuildConfig{},
+	}
+	if c.Language != "" && !set["language"] {
+		config.Language = c.Language
+	}
+	for name, enabled := range pluginFlags {
+		if !*enabled {
+			config.DisabledPlugins[name] = true
+		}
+	}
+	for name, enabled := range c.Plugins {
+		name = strings.ToLower(name)
+		if !set[name] {
+			config.DisabledPlugins[name] = !enabled
+		}
+	}
+	for guildID, g := range c.Guilds {
+		config.Guilds[guildID] = mmmorty.GuildConfig{
+			Language: g.Language,
+			Plugins:  g.Plugins,
+			Limits:   g.Limits,
+		}
+	}
+	return config
+}
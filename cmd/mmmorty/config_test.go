@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadConfig(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		yaml     string
+		expected *configFile
+		err      string
+	}{
+		{
+			name: "valid",
+			yaml: "discord:\n  token: abc123\n  shards: 2\nstore: bolt\nlimits:\n  quotes: 1000\n",
+			expected: &configFile{
+				Discord: discordConfig{Token: "abc123", Shards: 2},
+				Store:   "bolt",
+				Limits:  map[string]int{"quotes": 1000},
+			},
+		},
+		{
+			name:     "empty",
+			yaml:     "",
+			expected: &configFile{},
+		},
+		{
+			name: "typo",
+			yaml: "dataDri: /tmp\n",
+			err:  "field dataDri not found",
+		},
+		{
+			name: "bad store",
+			yaml: "store: sqlite\n",
+			err:  `unknown store "sqlite"`,
+		},
+		{
+			name: "bad shards",
+			yaml: "discord:\n  shards: -1\n",
+			err:  "at least 1 shard, not -1",
+		},
+		{
+			name: "not a number",
+			yaml: "limits:\n  quotes: lots\n",
+			err:  "lots",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(test.yaml), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := readConfig(path)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("got error %v, expected one containing %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c, test.expected) {
+				t.Errorf("read %+v, expected %+v", c, test.expected)
+			}
+		})
+	}
+}
+
+func TestBotConfigFlagsWin(t *testing.T) {
+	defer func(l string, dice bool) {
+		language, *pluginFlags["dice"] = l, dice
+	}(language, *pluginFlags["dice"])
+	language, *pluginFlags["dice"] = "en", false
+
+	file := &configFile{
+		Language: "es",
+		Plugins:  map[string]bool{"Dice": true, "war": true},
+		Limits:   map[string]int{"quotes": 1000},
+	}
+	for _, test := range []struct {
+		name     string
+		set      map[string]bool
+		language string
+		disabled map[string]bool
+	}{
+		{
+			name:     "file",
+			set:      map[string]bool{},
+			language: "es",
+			disabled: map[string]bool{"dice": false, "war": false},
+		},
+		{
+			name:     "command line",
+			set:      map[string]bool{"language": true, "dice": true},
+			language: "en",
+			disabled: map[string]bool{"dice": true, "war": false},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c := botConfig(file, test.set)
+			if c.Language != test.language {
+				t.Errorf("got language %q, expected %q", c.Language, test.language)
+			}
+			for name, disabled := range test.disabled {
+				if c.DisabledPlugins[name] != disabled {
+					t.Errorf("got %s disabled %v, expected %v", name, c.DisabledPlugins[name], disabled)
+				}
+			}
+			if c.Limits["quotes"] != 1000 {
+				t.Errorf("got quotes limit %d, expected the file's 1000", c.Limits["quotes"])
+			}
+		})
+	}
+}
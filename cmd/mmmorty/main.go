@@ -1,20 +1,29 @@
 package main
 
 import (
+	cryptorand "crypto/rand"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"math/rand"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/todd-beckman/mmmorty"
+	"github.com/todd-beckman/mmmorty/admin"
+	"github.com/todd-beckman/mmmorty/boltstore"
 	"github.com/todd-beckman/mmmorty/colorplugin"
 	"github.com/todd-beckman/mmmorty/roleplugin"
 	"github.com/todd-beckman/mmmorty/diceplugin"
 	"github.com/todd-beckman/mmmorty/evalplugin"
 	"github.com/todd-beckman/mmmorty/pickplugin"
+	"github.com/todd-beckman/mmmorty/prommetrics"
 	"github.com/todd-beckman/mmmorty/promptplugin"
 	"github.com/todd-beckman/mmmorty/quoteplugin"
 	"github.com/todd-beckman/mmmorty/warplugin"
@@ -22,12 +31,28 @@ import (
 )
 
 var (
+	configPath                 string
 	discordToken               string
 	discordEmail               string
 	discordPassword            string
 	discordApplicationClientID string
 	discordOwnerUserID         string
 	discordShards              int
+	runConsole                 bool
+	dataDir                    string
+	storeType                  string
+	backups                    int
+	migrateDryRun              bool
+	workers                    int
+	queueSize                  int
+	handlerTimeout             time.Duration
+	shutdownTimeout            time.Duration
+	language                   string
+	logFormat                  string
+	logLevel                   string
+	httpAddr                   string
+	adminAddr                  string
+	adminToken                 string
 	enableColor                bool
 	enableRoles                bool
 	enableDice                 bool
@@ -39,21 +64,53 @@ var (
 	enableWords                bool
 )
 
+// pluginFlags are the flags that turn each plugin on everywhere, by the plugin's name as servers turn it on and off.
+var pluginFlags = map[string]*bool{
+	"color":  &enableColor,
+	"dice":   &enableDice,
+	"eval":   &enableEval,
+	"pick":   &enablePicking,
+	"prompt": &enablePrompts,
+	"quote":  &enableQuotes,
+	"roles":  &enableRoles,
+	"war":    &enableWars,
+	"word":   &enableWords,
+}
+
 const (
 	// OwnerEnv is the key to the environment variable containing the User ID of this bot's owner
 	OwnerEnv = "DISCORD_OWNER"
 
 	// TokenEnv is the key to the environment variable containing the public token of this bot
 	TokenEnv = "DISCORD_TOKEN"
+
+	// AdminTokenEnv is the key to the environment variable containing the token for the admin dashboard
+	AdminTokenEnv = "MMMORTY_ADMIN_TOKEN"
 )
 
 func init() {
+	flag.StringVar(&configPath, "config", "", "YAML file to read settings from. Flags given on the command line win over it. Reread on SIGHUP.")
 	flag.StringVar(&discordToken, "discordtoken", "", "Discord token.")
 	flag.StringVar(&discordEmail, "discordemail", "", "Discord account email.")
 	flag.StringVar(&discordPassword, "discordpassword", "", "Discord account password.")
 	flag.StringVar(&discordOwnerUserID, "discordowneruserid", "", "Discord owner user id.")
 	flag.StringVar(&discordApplicationClientID, "discordapplicationclientid", "", "Discord application client id.")
 	flag.IntVar(&discordShards, "discordshards", 1, "Number of discord shards.")
+	flag.StringVar(&dataDir, "datadir", ".", "Directory to store plugin data in.")
+	flag.StringVar(&storeType, "store", "file", "How to store plugin data, either \"file\" or \"bolt\".")
+	flag.IntVar(&backups, "backups", 5, "Number of previous saves to keep for each plugin with the file store.")
+	flag.IntVar(&workers, "workers", 8, "Number of messages to handle at once.")
+	flag.IntVar(&queueSize, "queuesize", 1000, "Number of messages to queue before dropping edits and waiting on new messages.")
+	flag.DurationVar(&handlerTimeout, "handlertimeout", 30*time.Second, "How long a plugin can take to handle a message before moving on.")
+	flag.DurationVar(&shutdownTimeout, "shutdowntimeout", 5*time.Second, "How long to wait for messages to be handled when shutting down.")
+	flag.StringVar(&language, "language", "en", "Language to reply in on servers that haven't chosen one.")
+	flag.StringVar(&logFormat, "logformat", "text", "How to write log lines, either \"text\" or \"json\".")
+	flag.StringVar(&logLevel, "loglevel", "info", "Least important log lines to write: debug, info, warn or error.")
+	flag.StringVar(&httpAddr, "httpaddr", "", "Address to serve Prometheus metrics at /metrics and health at /healthz on, eg. \"localhost:9100\". Off if empty.")
+	flag.StringVar(&adminAddr, "adminaddr", "", "Local address to serve the admin dashboard on, eg. \"localhost:8080\". Off if empty.")
+	flag.StringVar(&adminToken, "admintoken", "", "Token needed to use the admin dashboard. A random one is logged if empty.")
+	flag.BoolVar(&migrateDryRun, "migratedryrun", false, "Report the plugin data migrations that would run, then exit without starting.")
+	flag.BoolVar(&runConsole, "console", false, "Whether to run in the terminal instead of connecting to Discord")
 
 	flag.BoolVar(&enableColor, "color", true, "Whether to enable setting colors")
 	flag.BoolVar(&enableDice, "dice", true, "Whether to enable rolling dice")
@@ -65,6 +122,12 @@ func init() {
 	flag.BoolVar(&enableWars, "war", false, "Whether to enable timed word wars")
 	flag.BoolVar(&enableWords, "word", true, "Whether to enable the dictionary plugin")
 
+	rand.Seed(time.Now().UnixNano())
+}
+
+// parseFlags parses the command line, falling back to the environment for secrets that weren't given on it.
+// It isn't done in init so that tests can run with their own flags.
+func parseFlags() {
 	flag.Parse()
 
 	if discordToken == "" {
@@ -73,78 +136,173 @@ func init() {
 	if discordOwnerUserID == "" {
 		discordOwnerUserID = os.Getenv(OwnerEnv)
 	}
-
-	rand.Seed(time.Now().UnixNano())
+	if adminToken == "" {
+		adminToken = os.Getenv(AdminTokenEnv)
+	}
 }
 
 func main() {
-	q := make(chan bool)
+	parseFlags()
+	q := make(chan bool, 1)
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(logLevel)); err != nil {
+		log.Printf("Unknown log level %q, expected debug, info, warn or error.\n", logLevel)
+		os.Exit(1)
+	}
+	logger, err := mmmorty.NewLogger(os.Stderr, logFormat, level)
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+	// Plugins that still use the log package are written through the same logger.
+	slog.SetDefault(logger)
+
+	var config *configFile
+	if configPath != "" {
+		config, err = readConfig(configPath)
+		if err != nil {
+			logConfigError("Error reading configuration", err)
+			os.Exit(1)
+		}
+		useConfig(config)
+	}
 
 	// Set our variables.
 	bot := mmmorty.NewBot()
+	bot.Logger = logger
+	bot.Workers = workers
+	bot.QueueSize = queueSize
+	bot.HandlerTimeout = handlerTimeout
+
+	switch storeType {
+	case "file":
+		store := mmmorty.NewFileStore(dataDir)
+		store.Backups = backups
+		bot.Store = store
+	case "bolt":
+		if err := os.MkdirAll(dataDir, 0755); err != nil {
+			slog.Error("Error creating data directory", "dir", dataDir, "err", err)
+			os.Exit(1)
+		}
+		store, err := boltstore.Open(filepath.Join(dataDir, "mmmorty.db"))
+		if err != nil {
+			slog.Error("Error opening database", "err", err)
+			os.Exit(1)
+		}
+		bot.Store = store
+	default:
+		slog.Error("Unknown store, expected \"file\" or \"bolt\"", "store", storeType)
+		os.Exit(1)
+	}
+	defer bot.Store.Close()
 
 	// Generally CommandPlugins don't hold state, so we share one instance of the command plugin for all services.
 	cp := mmmorty.NewCommandPlugin()
 
-	cp.AddCommand("quit", func(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, args string, parts []string) {
-		if service.IsBotOwner(message) {
-			q <- true
+	cp.AddCommand("quit", mmmorty.BotOwner, func(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, args mmmorty.Args) {
+		// Quitting again while shutting down does nothing.
+		select {
+		case q <- true:
+		default:
 		}
 	}, nil)
 
-	// Register the Discord service if we have an email or token.
-	if (discordEmail != "" && discordPassword != "") || discordToken != "" {
-		var discordPointer *mmmorty.Discord
+	// Closed when the console runs out of input, never closed otherwise.
+	var done <-chan struct{}
+
+	if runConsole {
+		// Register the Console service instead of Discord so plugins can be tried out without a token.
+		console := mmmorty.NewConsole(os.Stdin, os.Stdout)
+		if discordOwnerUserID != "" {
+			console.OwnerUserID = discordOwnerUserID
+		}
+		bot.RegisterService(console)
+		registerPlugins(bot, console, cp)
+		done = console.Done()
+	} else if (discordEmail != "" && discordPassword != "") || discordToken != "" {
+		// Register the Discord service if we have an email or token.
+		var discord *mmmorty.Discord
 		if discordToken != "" {
-			discordPointer = mmmorty.NewDiscord(fmt.Sprintf("Bot %s", discordToken))
+			discord = mmmorty.NewDiscord(fmt.Sprintf("Bot %s", discordToken))
 		} else {
-			discordPointer = mmmorty.NewDiscord(discordEmail, discordPassword)
+			discord = mmmorty.NewDiscord(discordEmail, discordPassword)
 		}
-		discord := *discordPointer
 		discord.ApplicationClientID = discordApplicationClientID
 		discord.OwnerUserID = discordOwnerUserID
 		discord.Shards = discordShards
 		bot.RegisterService(discord)
+		registerPlugins(bot, discord, cp)
+	} else {
+		slog.Error("(discordEmail and discordPassword) or discordToken is required")
+		os.Exit(1)
+	}
 
-		bot.RegisterPlugin(discord, cp)
-		if enableColor {
-			bot.RegisterPlugin(discord, colorplugin.New())
-		}
-		if enableDice {
-			bot.RegisterPlugin(discord, diceplugin.New())
-		}
-		if enableEval {
-			bot.RegisterPlugin(discord, evalplugin.New())
-		}
-		if enablePicking {
-			bot.RegisterPlugin(discord, pickplugin.New())
-		}
-		if enableQuotes {
-			bot.RegisterPlugin(discord, quoteplugin.New())
-		}
-		if enablePrompts {
-			bot.RegisterPlugin(discord, promptplugin.New())
-		}
-		if enableRoles {
-			bot.RegisterPlugin(discord, roleplugin.New())
+	// The configuration can only be checked once the plugins it names are registered.
+	if err := bot.Reconfigure(botConfig(config, setOnCommandLine())); err != nil {
+		logConfigError("Invalid configuration", err)
+		os.Exit(1)
+	}
+
+	if migrateDryRun {
+		for _, report := range bot.MigrationDryRun() {
+			fmt.Println(report)
 		}
-		if enableWars {
-			bot.RegisterPlugin(discord, warplugin.New())
+		return
+	}
+
+	var mux *http.ServeMux
+	if httpAddr != "" {
+		metrics := prommetrics.New(bot)
+		bot.Metrics = metrics
+		mux = http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		mux.Handle("/healthz", bot.HealthHandler())
+	}
+
+	if adminAddr != "" {
+		// The dashboard can change anything, so it's never served beyond this machine.
+		if !admin.IsLocal(adminAddr) {
+			slog.Error("The admin dashboard can only listen on localhost", "addr", adminAddr)
+			os.Exit(1)
 		}
-		if enableWords {
-			bot.RegisterPlugin(discord, wordplugin.New())
+		if adminToken == "" {
+			b := make([]byte, 16)
+			if _, err := cryptorand.Read(b); err != nil {
+				slog.Error("Error creating admin token", "err", err)
+				os.Exit(1)
+			}
+			adminToken = hex.EncodeToString(b)
+			slog.Info("Admin dashboard is ready", "url", "http://"+adminAddr+"/?token="+adminToken)
 		}
-	} else {
-		log.Println("(discordEmail and discordPassword) or discordToken is required.")
-		os.Exit(1)
 	}
 
 	// Start all our services.
 	bot.Open()
 
+	// Only serve what the bot reports about itself once it's open, as opening sets it up.
+	if mux != nil {
+		go func() {
+			if err := http.ListenAndServe(httpAddr, mux); err != nil {
+				slog.Error("Error serving metrics", "addr", httpAddr, "err", err)
+			}
+		}()
+	}
+	if adminAddr != "" {
+		go func() {
+			if err := http.ListenAndServe(adminAddr, admin.New(bot, adminToken)); err != nil {
+				slog.Error("Error serving admin dashboard", "addr", adminAddr, "err", err)
+			}
+		}()
+	}
+
 	// Wait for a termination signal, while saving the bot state every minute. Save on close.
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, os.Kill)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+
+	// Reload the configuration on SIGHUP, without reconnecting.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
 
 	t := time.Tick(1 * time.Minute)
 
@@ -155,10 +313,71 @@ out:
 			break out
 		case <-c:
 			break out
+		case <-done:
+			break out
+		case <-hup:
+			reload(bot, config)
 		case <-t:
 			bot.Save()
 		}
 	}
 
-	bot.Save()
+	// A second signal stops the bot without waiting any longer.
+	go func() {
+		<-c
+		slog.Warn("Stopped without finishing shutting down")
+		os.Exit(1)
+	}()
+
+	slog.Info("Shutting down", "timeout", shutdownTimeout)
+	bot.Close(shutdownTimeout)
+	slog.Info("Shut down")
+}
+
+// registerPlugins registers every plugin on a service. Plugins whose flag is off are off on servers that haven't
+// turned them on, once the bot is configured.
+func registerPlugins(bot *mmmorty.Bot, service mmmorty.Service, cp *mmmorty.CommandPlugin) {
+	bot.RegisterPlugin(service, cp)
+	bot.RegisterPlugin(service, colorplugin.New())
+	bot.RegisterPlugin(service, diceplugin.New())
+	bot.RegisterPlugin(service, evalplugin.New())
+	bot.RegisterPlugin(service, pickplugin.New())
+	bot.RegisterPlugin(service, quoteplugin.New())
+	bot.RegisterPlugin(service, promptplugin.New())
+	bot.RegisterPlugin(service, roleplugin.New())
+	bot.RegisterPlugin(service, warplugin.New())
+	bot.RegisterPlugin(service, wordplugin.New())
+}
+
+// reload rereads the configuration file and reconfigures the bot with it. The bot keeps the configuration it has if
+// the file has problems. Settings that can't change while the bot runs are left alone until it restarts.
+func reload(bot *mmmorty.Bot, started *configFile) {
+	if configPath == "" {
+		slog.Warn("No configuration to reload, start with -config <file>")
+		return
+	}
+	config, err := readConfig(configPath)
+	if err != nil {
+		logConfigError("Configuration not reloaded", err)
+		return
+	}
+	if err := bot.Reconfigure(botConfig(config, setOnCommandLine())); err != nil {
+		logConfigError("Configuration not reloaded", err)
+		return
+	}
+	if needsRestart(started, config) {
+		slog.Warn("Restart to use the new Discord settings, data directory or store")
+	}
+	slog.Info("Reloaded configuration", "file", configPath)
+}
+
+// logConfigError logs each problem with the configuration on its own line.
+func logConfigError(msg string, err error) {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	for _, err := range errs {
+		slog.Error(msg, "err", err)
+	}
 }
@@ -3,7 +3,7 @@ package colorplugin
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"sort"
 	"strings"
 
 	"github.com/todd-beckman/mmmorty"
@@ -44,22 +44,78 @@ func doesRoleHaveAuth(permissions int64) bool {
 	return permissions&authPermissions > 0
 }
 
-type handleFunc func(*mmmorty.Bot, mmmorty.Discord, mmmorty.DiscordMessage, string)
+// GuildData returns the colors a server lets members give themselves.
+func (p *ColorPlugin) GuildData(service mmmorty.Service, guildID string) interface{} {
+	roles := p.getPrintableRoles(guildID)
+	sort.Strings(roles)
+	return roles
+}
 
-func (p *ColorPlugin) findHandler(service mmmorty.Discord, message mmmorty.DiscordMessage) handleFunc {
-	handlers := map[string]handleFunc{
-		colorCommand:        p.handleColorMe,
-		manageColorCommand:  p.handleManageColor,
-		stopManagingCommand: p.handleStopManaging,
+// SetGuildData replaces the colors a server lets members give themselves. Each must be a role on the server that
+// doesn't give members any authority.
+func (p *ColorPlugin) SetGuildData(bot *mmmorty.Bot, service mmmorty.Service, guildID string, data []byte) error {
+	names := []string{}
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	guild, err := service.Guild(guildID)
+	if err != nil {
+		return err
 	}
-	for c, h := range handlers {
-		if mmmorty.MatchesCommand(service, c, message) {
-			return h
+
+	managed := map[string]bool{}
+	for _, name := range names {
+		name = strings.ToLower(name)
+		for _, role := range guild.Roles {
+			if strings.ToLower(role.Name) != name {
+				continue
+			}
+			if doesRoleHaveAuth(role.Permissions) {
+				return fmt.Errorf("%s gives members authority", name)
+			}
+			managed[name] = true
+		}
+		if !managed[name] {
+			return fmt.Errorf("there's no role called %s", name)
 		}
 	}
+
+	if p.RolesByGuild == nil {
+		p.RolesByGuild = map[string]colorSet{}
+	}
+	if len(managed) == 0 {
+		delete(p.RolesByGuild, guildID)
+	} else {
+		p.RolesByGuild[guildID] = colorSet{ManagedRoles: managed}
+	}
 	return nil
 }
 
+type handleFunc func(*mmmorty.Bot, mmmorty.Service, mmmorty.Message, string)
+
+// Commands returns the commands this plugin handles
+func (p *ColorPlugin) Commands() []mmmorty.Command {
+	return []mmmorty.Command{
+		{
+			Name:        colorCommand,
+			Handler:     p.guildCommand(p.handleColorMe),
+			Description: "assigns the desired color if this server supports it",
+		},
+		{
+			Name:        manageColorCommand,
+			Handler:     p.guildCommand(p.handleManageColor),
+			Description: "lets members give themselves a color, for moderators",
+			Permission:  mmmorty.Moderator,
+		},
+		{
+			Name:        stopManagingCommand,
+			Handler:     p.guildCommand(p.handleStopManaging),
+			Description: "stops members giving themselves a color, for moderators",
+			Permission:  mmmorty.Moderator,
+		},
+	}
+}
+
 func (p *ColorPlugin) getPrintableRoles(guildID string) []string {
 	printableRoles := []string{}
 	for role, isManaged := range p.RolesByGuild[guildID].ManagedRoles {
@@ -71,16 +127,15 @@ func (p *ColorPlugin) getPrintableRoles(guildID string) []string {
 }
 
 // Help gets the usage for this plugin
-func (p *ColorPlugin) Help(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, detailed bool) []string {
-	help := mmmorty.CommandHelp(service, colorCommand, "color", "assigns the desired color if this server supports it and the color is available")
+func (p *ColorPlugin) Help(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, detailed bool) []string {
+	help := mmmorty.CommandHelp(service, colorCommand, mmmorty.Text(service, nil, "color.help.arguments", nil), mmmorty.Text(service, nil, "color.help", nil))
 	return help
 }
 
 // Load loads this plugin from the given data
-func (p *ColorPlugin) Load(bot *mmmorty.Bot, service mmmorty.Discord, data []byte) error {
+func (p *ColorPlugin) Load(bot *mmmorty.Bot, service mmmorty.Service, data []byte) error {
 	if data != nil {
 		if err := json.Unmarshal(data, p); err != nil {
-			log.Println("Error loading data", err)
 			return err
 		}
 	}
@@ -88,54 +143,47 @@ func (p *ColorPlugin) Load(bot *mmmorty.Bot, service mmmorty.Discord, data []byt
 	return nil
 }
 
-// Message is the command handler for this plugin
-func (p *ColorPlugin) Message(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage) {
-	defer bot.MessageRecover(service, message.Channel())
-	if service.IsMe(message) {
-		return
-	}
-
-	handler := p.findHandler(service, message)
-	if handler == nil {
-		return
-	}
+// Message is unused, this plugin's commands are routed by the bot
+func (p *ColorPlugin) Message(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
+}
 
-	requester := fmt.Sprintf("<@%s>", message.UserID())
-	channelID := message.Channel()
-	discordChannel, err := service.Channel(channelID)
-	if err != nil {
-		reply := fmt.Sprintf("Uh, %s, something went figuring out your server.", requester)
-		service.SendMessage(message.Channel(), reply)
-		return
-	}
-	guildID := discordChannel.GuildID
+// guildCommand looks up the guild a command was sent in before handling it
+func (p *ColorPlugin) guildCommand(handler handleFunc) mmmorty.CommandFunc {
+	return func(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
+		channelID := message.Channel()
+		discordChannel, err := service.Channel(channelID)
+		if err != nil {
+			reply := mmmorty.Text(service, message, "error.guild", nil)
+			service.SendMessage(message.Channel(), reply)
+			return
+		}
+		guildID := discordChannel.GuildID
 
-	if p.RolesByGuild == nil {
-		p.RolesByGuild = map[string]colorSet{
-			guildID: {},
+		if p.RolesByGuild == nil {
+			p.RolesByGuild = map[string]colorSet{
+				guildID: {},
+			}
 		}
-	}
 
-	if p.RolesByGuild[guildID].ManagedRoles == nil {
-		p.RolesByGuild[guildID] = colorSet{
-			ManagedRoles: map[string]bool{},
+		if p.RolesByGuild[guildID].ManagedRoles == nil {
+			p.RolesByGuild[guildID] = colorSet{
+				ManagedRoles: map[string]bool{},
+			}
 		}
-	}
 
-	handler(bot, service, message, guildID)
+		handler(bot, service, message, guildID)
+	}
 }
 
-func (p *ColorPlugin) handleColorMe(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, guildID string) {
-	requester := fmt.Sprintf("<@%s>", message.UserID())
-
+func (p *ColorPlugin) handleColorMe(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, guildID string) {
 	if service.IsPrivate(message) {
-		reply := fmt.Sprintf("Uh, %s, I cannot color you in private.", requester)
+		reply := mmmorty.Text(service, message, "color.private", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
 	if availableRoles := p.getPrintableRoles(guildID); len(availableRoles) == 0 {
-		reply := fmt.Sprintf("Uh, %s, I don't think this server lets me set your color.", requester)
+		reply := mmmorty.Text(service, message, "color.none", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
@@ -143,11 +191,11 @@ func (p *ColorPlugin) handleColorMe(bot *mmmorty.Bot, service mmmorty.Discord, m
 	_, parts := mmmorty.ParseCommand(service, message)
 
 	if len(parts) == 1 {
-		reply := fmt.Sprintf("Uh, %s, I think you forgot to name a color.", requester)
+		reply := mmmorty.Text(service, message, "color.missing", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	} else if len(parts) > 2 {
-		reply := fmt.Sprintf("Uh, %s, I can't give you more than one color.", requester)
+		reply := mmmorty.Text(service, message, "color.many", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
@@ -157,13 +205,13 @@ func (p *ColorPlugin) handleColorMe(bot *mmmorty.Bot, service mmmorty.Discord, m
 	role := service.GetRoleByName(message.Channel(), color)
 
 	if role == nil {
-		reply := fmt.Sprintf("Uh, %s, I can't find a role called %s", requester, color)
+		reply := mmmorty.Text(service, message, "color.unknown", mmmorty.Vars{"color": color})
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
 	if doesRoleHaveAuth(role.Permissions) {
-		reply := fmt.Sprintf("Uh, %s, I think %s is more than just a colored role.", requester, color)
+		reply := mmmorty.Text(service, message, "color.auth", mmmorty.Vars{"color": color})
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
@@ -180,7 +228,7 @@ func (p *ColorPlugin) handleColorMe(bot *mmmorty.Bot, service mmmorty.Discord, m
 			if userRole == managedRole.ID {
 				ok := service.GuildMemberRoleRemove(guildID, message.UserID(), userRole)
 				if !ok {
-					reply := fmt.Sprintf("Uh, %s, something went wrong. Are you sure I can manage %v?", requester, color)
+					reply := mmmorty.Text(service, message, "color.remove.failed", mmmorty.Vars{"color": color})
 					service.SendMessage(message.Channel(), reply)
 					continue
 				}
@@ -190,26 +238,18 @@ func (p *ColorPlugin) handleColorMe(bot *mmmorty.Bot, service mmmorty.Discord, m
 
 	ok := service.GuildMemberRoleAdd(guildID, message.UserID(), role.ID)
 	if !ok {
-		reply := fmt.Sprintf("Uh, %s, something went wrong. Are you sure I can let you be %v?", requester, color)
+		reply := mmmorty.Text(service, message, "color.failed", mmmorty.Vars{"color": color})
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
-	reply := fmt.Sprintf("You got it, %s! You are now %s", requester, color)
+	reply := mmmorty.Text(service, message, "color.given", mmmorty.Vars{"color": color})
 	service.SendMessage(message.Channel(), reply)
 }
 
-func (p *ColorPlugin) handleManageColor(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, guildID string) {
-	requester := fmt.Sprintf("<@%s>", message.UserID())
-
+func (p *ColorPlugin) handleManageColor(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, guildID string) {
 	if service.IsPrivate(message) {
-		reply := fmt.Sprintf("Uh, %s, I cannot color you in private.", requester)
-		service.SendMessage(message.Channel(), reply)
-		return
-	}
-
-	if message.UserID() != service.OwnerUserID {
-		reply := fmt.Sprintf("Uh, %s, I think you need to ask my Rick for that command.", requester)
+		reply := mmmorty.Text(service, message, "color.private", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
@@ -217,7 +257,7 @@ func (p *ColorPlugin) handleManageColor(bot *mmmorty.Bot, service mmmorty.Discor
 	_, parts := mmmorty.ParseCommand(service, message)
 
 	if len(parts) < 1 {
-		reply := fmt.Sprintf("Uh, %s, I think you forgot to name a color.", requester)
+		reply := mmmorty.Text(service, message, "color.missing", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
@@ -225,7 +265,7 @@ func (p *ColorPlugin) handleManageColor(bot *mmmorty.Bot, service mmmorty.Discor
 	for _, c := range parts {
 		color := strings.ToLower(c)
 		if p.RolesByGuild[guildID].ManagedRoles[color] {
-			reply := fmt.Sprintf("Uh, %s, I am already managing %s", requester, color)
+			reply := mmmorty.Text(service, message, "color.manage.already", mmmorty.Vars{"color": color})
 			service.SendMessage(message.Channel(), reply)
 			continue
 		}
@@ -233,13 +273,13 @@ func (p *ColorPlugin) handleManageColor(bot *mmmorty.Bot, service mmmorty.Discor
 		role := service.GetRoleByName(message.Channel(), color)
 
 		if role == nil {
-			reply := fmt.Sprintf("Uh, %s, I can't find a role called %s", requester, color)
+			reply := mmmorty.Text(service, message, "color.unknown", mmmorty.Vars{"color": color})
 			service.SendMessage(message.Channel(), reply)
 			continue
 		}
 
 		if doesRoleHaveAuth(role.Permissions) {
-			reply := fmt.Sprintf("Uh, %s, I think %s is more than just a colored role.", requester, color)
+			reply := mmmorty.Text(service, message, "color.auth", mmmorty.Vars{"color": color})
 			service.SendMessage(message.Channel(), reply)
 			continue
 		}
@@ -248,23 +288,15 @@ func (p *ColorPlugin) handleManageColor(bot *mmmorty.Bot, service mmmorty.Discor
 	}
 
 	printableRoles := p.getPrintableRoles(guildID)
-	reply := fmt.Sprintf("Uh, I guess that means I am managing %v now.", printableRoles)
+	reply := mmmorty.Text(service, message, "color.manage.managed", mmmorty.Vars{"colors": printableRoles})
 	service.SendMessage(message.Channel(), reply)
 }
 
-func (p *ColorPlugin) handleStopManaging(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, guildID string) {
-	requester := fmt.Sprintf("<@%s>", message.UserID())
-
-	if message.UserID() != service.OwnerUserID {
-		reply := fmt.Sprintf("Uh, %s, I think you need to ask my Rick for that command.", requester)
-		service.SendMessage(message.Channel(), reply)
-		return
-	}
-
+func (p *ColorPlugin) handleStopManaging(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, guildID string) {
 	_, parts := mmmorty.ParseCommand(service, message)
 
 	if len(parts) < 1 {
-		reply := fmt.Sprintf("Uh, %s, I think you forgot to name a color.", requester)
+		reply := mmmorty.Text(service, message, "color.missing", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
@@ -272,7 +304,7 @@ func (p *ColorPlugin) handleStopManaging(bot *mmmorty.Bot, service mmmorty.Disco
 	for _, c := range parts {
 		color := strings.ToLower(c)
 		if !p.RolesByGuild[guildID].ManagedRoles[color] {
-			reply := fmt.Sprintf("Uh, %s, I'm not managing %s", requester, color)
+			reply := mmmorty.Text(service, message, "color.manage.unknown", mmmorty.Vars{"color": color})
 			service.SendMessage(message.Channel(), reply)
 			continue
 		}
@@ -281,7 +313,7 @@ func (p *ColorPlugin) handleStopManaging(bot *mmmorty.Bot, service mmmorty.Disco
 	}
 
 	printableRoles := p.getPrintableRoles(guildID)
-	reply := fmt.Sprintf("Uh, I guess that means I am managing %v now.", printableRoles)
+	reply := mmmorty.Text(service, message, "color.manage.managed", mmmorty.Vars{"colors": printableRoles})
 	service.SendMessage(message.Channel(), reply)
 }
 
@@ -0,0 +1,46 @@
+package colorplugin
+
+import "github.com/todd-beckman/mmmorty"
+
+func init() {
+	mmmorty.RegisterMessages("en", map[string]string{
+		"color.help":           "assigns the desired color if this server supports it and the color is available",
+		"color.help.arguments": "color",
+		"color.private":        "Uh, {user}, I cannot color you in private.",
+		"color.none":           "Uh, {user}, I don't think this server lets me set your color.",
+		"color.missing":        "Uh, {user}, I think you forgot to name a color.",
+		"color.many":           "Uh, {user}, I can't give you more than one color.",
+		"color.unknown":        "Uh, {user}, I can't find a role called {color}",
+		"color.auth":           "Uh, {user}, I think {color} is more than just a colored role.",
+		"color.remove.failed":  "Uh, {user}, something went wrong. Are you sure I can manage {color}?",
+		"color.failed":         "Uh, {user}, something went wrong. Are you sure I can let you be {color}?",
+		"color.given":          "You got it, {user}! You are now {color}",
+		"color.manage.already": "Uh, {user}, I am already managing {color}",
+		"color.manage.managed": "Uh, I guess that means I am managing {colors} now.",
+		"color.manage.unknown": "Uh, {user}, I'm not managing {color}",
+	})
+
+	mmmorty.RegisterMessages("es", map[string]string{
+		"color.help":           "te da el color que quieras si este servidor lo permite y el color está disponible",
+		"color.help.arguments": "color",
+		"color.private":        "Eh, {user}, no puedo darte color en privado.",
+		"color.none":           "Eh, {user}, no creo que este servidor me deje cambiar tu color.",
+		"color.missing":        "Eh, {user}, creo que olvidaste decir el color.",
+		"color.many":           "Eh, {user}, no puedo darte más de un color.",
+		"color.unknown":        "Eh, {user}, no encuentro ningún rol llamado {color}",
+		"color.auth":           "Eh, {user}, creo que {color} es algo más que un rol de color.",
+		"color.remove.failed":  "Eh, {user}, algo salió mal. ¿Seguro que puedo gestionar {color}?",
+		"color.failed":         "Eh, {user}, algo salió mal. ¿Seguro que puedo darte {color}?",
+		"color.given":          "¡Hecho, {user}! Ahora eres {color}",
+		"color.manage.already": "Eh, {user}, ya estoy gestionando {color}",
+		"color.manage.managed": "Eh, supongo que eso significa que ahora gestiono {colors}.",
+		"color.manage.unknown": "Eh, {user}, no estoy gestionando {color}",
+	})
+
+	mmmorty.RegisterSamples(map[string]mmmorty.Vars{
+		"color": {
+			"color":  "red",
+			"colors": "[red blue]",
+		},
+	})
+}
@@ -5,41 +5,36 @@ import (
 	"strings"
 )
 
-const commandDelimeter = "!"
-
 // CommandHelpFunc is the function signature for command help methods.
-type CommandHelpFunc func(bot *Bot, service Discord, message DiscordMessage) (string, string)
+type CommandHelpFunc func(bot *Bot, service Service, message Message) (string, string)
 
 // CommandMessageFunc is the function signature for bot message commands.
-type CommandMessageFunc func(bot *Bot, service Discord, message DiscordMessage, args string, parts []string)
+type CommandMessageFunc func(bot *Bot, service Service, message Message, args Args)
 
 // NewCommandHelp creates a new Command Help function.
 func NewCommandHelp(args, help string) CommandHelpFunc {
-	return func(bot *Bot, service Discord, message DiscordMessage) (string, string) {
+	return func(bot *Bot, service Service, message Message) (string, string) {
 		return args, help
 	}
 }
 
 // MatchesCommandString returns true if a message matches a command.
 // Commands will be matched ignoring case with a prefix if they are not private messages.
-func MatchesCommandString(service Discord, commandString string, private bool, message string) bool {
-	lowerMessage := strings.ToLower(strings.TrimSpace(message))
-	lowerPrefix := strings.ToLower(service.CommandPrefix())
-
-	if strings.HasPrefix(lowerMessage, lowerPrefix) {
-		lowerMessage = lowerMessage[len(lowerPrefix):]
-	} else if !private {
+// In a guild with its own prefix either that prefix or the mention will do.
+func MatchesCommandString(service Service, commandString string, private bool, message string) bool {
+	lowerMessage, ok := trimCommandPrefix(service, strings.TrimSpace(message))
+	if !ok && !private {
 		return false
 	}
 
-	lowerMessage = strings.TrimSpace(lowerMessage)
+	lowerMessage = strings.ToLower(lowerMessage)
 	lowerCommand := strings.ToLower(commandString)
 
 	return lowerMessage == lowerCommand || strings.HasPrefix(lowerMessage, lowerCommand+" ")
 }
 
 // MatchesCommand returns true if a message matches a command.
-func MatchesCommand(service Discord, commandString string, message DiscordMessage) bool {
+func MatchesCommand(service Service, commandString string, message Message) bool {
 	// Deleted messages can't trigger commands.
 	if message.Type() == MessageTypeDelete {
 		return false
@@ -48,15 +43,8 @@ func MatchesCommand(service Discord, commandString string, message DiscordMessag
 }
 
 // ParseCommandString will strip all prefixes from a message string, and return that string, and a space separated tokenized version of that string.
-func ParseCommandString(service Discord, message string) (string, []string) {
-	message = strings.TrimSpace(message)
-
-	lowerMessage := strings.ToLower(message)
-	lowerPrefix := strings.ToLower(service.CommandPrefix())
-
-	if strings.HasPrefix(lowerMessage, lowerPrefix) {
-		message = message[len(lowerPrefix):]
-	}
+func ParseCommandString(service Service, message string) (string, []string) {
+	message, _ = trimCommandPrefix(service, strings.TrimSpace(message))
 	rest := strings.Fields(message)
 
 	if len(rest) > 1 {
@@ -67,7 +55,7 @@ func ParseCommandString(service Discord, message string) (string, []string) {
 }
 
 // ParseCommand parses a message.
-func ParseCommand(service Discord, message DiscordMessage) (string, []string) {
+func ParseCommand(service Service, message Message) (string, []string) {
 	return ParseCommandString(service, message.Message())
 }
 
@@ -75,7 +63,7 @@ func ParseCommand(service Discord, message DiscordMessage) (string, []string) {
 // eg. CommandHelp(service, "foo", "<bar>", "Foo bar baz") will return:
 //     !foo <bar> - Foo bar baz
 // The string is automatatically styled in Discord.
-func CommandHelp(service Discord, command, arguments, help string) []string {
+func CommandHelp(service Service, command, arguments, help string) []string {
 	ticks := "`"
 
 	if arguments != "" {
@@ -85,8 +73,10 @@ func CommandHelp(service Discord, command, arguments, help string) []string {
 }
 
 type command struct {
-	message CommandMessageFunc
-	help    CommandHelpFunc
+	spec       *ArgSpec
+	permission Permission
+	message    CommandMessageFunc
+	help       CommandHelpFunc
 }
 
 // CommandPlugin is a plugin that can have commands registered and will handle messages matching that command by calling functions.
@@ -100,7 +90,7 @@ func (p *CommandPlugin) Name() string {
 }
 
 // Load will load plugin state from a byte array.
-func (p *CommandPlugin) Load(bot *Bot, service Discord, data []byte) error {
+func (p *CommandPlugin) Load(bot *Bot, service Service, data []byte) error {
 	// TODO: Add a generic data store backed by json.
 	return nil
 }
@@ -112,7 +102,7 @@ func (p *CommandPlugin) Save() ([]byte, error) {
 }
 
 // Help returns a list of help strings that are printed when the user requests them.
-func (p *CommandPlugin) Help(bot *Bot, service Discord, message DiscordMessage, detailed bool) []string {
+func (p *CommandPlugin) Help(bot *Bot, service Service, message Message, detailed bool) []string {
 	if detailed {
 		return nil
 	}
@@ -120,6 +110,9 @@ func (p *CommandPlugin) Help(bot *Bot, service Discord, message DiscordMessage,
 	for commandString, command := range p.commands {
 		if command.help != nil {
 			arguments, h := command.help(bot, service, message)
+			if arguments == "" {
+				arguments = command.spec.Arguments()
+			}
 			help = append(help, CommandHelp(service, commandString, arguments, h)...)
 		}
 	}
@@ -127,25 +120,48 @@ func (p *CommandPlugin) Help(bot *Bot, service Discord, message DiscordMessage,
 }
 
 // Message handler.
-// Iterates over the registered commands and executes them if the message matches.
-func (p *CommandPlugin) Message(bot *Bot, service Discord, message DiscordMessage) {
-	defer bot.MessageRecover(service, message.Channel())
-	if !service.IsMe(message) {
-		for commandString, command := range p.commands {
-			if MatchesCommand(service, commandString, message) {
-				args, parts := ParseCommand(service, message)
-				command.message(bot, service, message, args, parts)
-				return
-			}
+// Commands are routed by the bot, so there is nothing else to handle.
+func (p *CommandPlugin) Message(bot *Bot, service Service, message Message) {
+}
+
+// Commands returns the registered commands so the bot can route them.
+func (p *CommandPlugin) Commands() []Command {
+	commands := []Command{}
+	for commandString, c := range p.commands {
+		c := c
+		command := Command{
+			Name: commandString,
+			Handler: func(bot *Bot, service Service, message Message) {
+				args, err := c.spec.Parse(service, message)
+				if err != nil {
+					SendEphemeral(service, message.Channel(), c.spec.UsageReply(service, message, err))
+					return
+				}
+				c.message(bot, service, message, args)
+			},
+			Permission: c.permission,
+		}
+		if c.spec.tokens != nil {
+			command.Spec = c.spec
 		}
+		commands = append(commands, command)
 	}
+	return commands
 }
 
-// AddCommand adds a command.
-func (p *CommandPlugin) AddCommand(commandString string, message CommandMessageFunc, help CommandHelpFunc) {
-	p.commands[commandString] = &command{
-		message: message,
-		help:    help,
+// AddCommand adds a command. Commands must be added before the plugin is registered.
+// The command string is the command's name followed by its arguments as described by ArgSpec, eg.
+//     start sprint at <minute:int 0-59> for <minutes:int 1-180>
+// Messages whose arguments don't match get a usage reply instead of running the command, and a command without
+// arguments gets whatever follows its name in args.Raw and args.Parts. AddCommand panics if the spec is invalid.
+// The bot only runs the command for members with the permission, or who the server has granted it to.
+func (p *CommandPlugin) AddCommand(commandString string, permission Permission, message CommandMessageFunc, help CommandHelpFunc) {
+	spec := MustParseArgSpec(commandString)
+	p.commands[spec.Name] = &command{
+		spec:       spec,
+		permission: permission,
+		message:    message,
+		help:       help,
 	}
 }
 
@@ -0,0 +1,187 @@
+package mmmorty
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Config is the part of the bot's configuration that can change while it runs, such as when a configuration file is
+// reloaded. Bot embeds it so it can be set directly before Open, use Reconfigure to change it once the bot is open.
+type Config struct {
+	// Language is the language the bot replies in on servers that haven't picked one.
+	Language string
+	// DisabledPlugins are the names of plugins that are off on servers that haven't turned them on.
+	DisabledPlugins map[string]bool
+	// Limits change the most plugins allow, by the limit's name, eg. "quotes". Plugins declare their limits by
+	// implementing Limiter.
+	Limits map[string]int
+	// Guilds change the rest of the configuration on some servers, by guild ID.
+	Guilds map[string]GuildConfig
+}
+
+// GuildConfig changes the bot's configuration on one server.
+type GuildConfig struct {
+	// Language is the language the bot replies in on the server until it picks one.
+	Language string
+	// Plugins turns plugins on or off on the server until it turns them on or off itself, by plugin name.
+	Plugins map[string]bool
+	// Limits change the most plugins allow on the server, by the limit's name.
+	Limits map[string]int
+}
+
+// Limiter is implemented by plugins with limits the bot's configuration can change, such as how many quotes a server
+// can keep.
+type Limiter interface {
+	// Limits returns the plugin's limits and their defaults by name, eg. "quotes". They must not change.
+	Limits() map[string]int
+}
+
+// config returns the bot's configuration. It's replaced rather than changed, so it can be read without holding the lock.
+func (b *Bot) config() Config {
+	b.configMu.RLock()
+	defer b.configMu.RUnlock()
+	return b.Config
+}
+
+// guildLanguage returns the language the bot replies in on a server that hasn't picked one.
+func (c Config) guildLanguage(guildID string) string {
+	if language := c.Guilds[guildID].Language; language != "" {
+		return language
+	}
+	return c.Language
+}
+
+// limitDefaults returns the default of every limit declared by the bot's plugins, by name.
+func (b *Bot) limitDefaults() map[string]int {
+	limits := map[string]int{}
+	for _, service := range b.Services {
+		for _, plugin := range service.Plugins {
+			if l, ok := plugin.(Limiter); ok {
+				for name, value := range l.Limits() {
+					limits[name] = value
+				}
+			}
+		}
+	}
+	return limits
+}
+
+// Limit returns the most a plugin allows on a server for one of the limits it declares as a Limiter.
+func (b *Bot) Limit(guildID, name string) int {
+	c := b.config()
+	if value, ok := c.Guilds[guildID].Limits[name]; ok {
+		return value
+	}
+	if value, ok := c.Limits[name]; ok {
+		return value
+	}
+	return b.limitDefaults()[name]
+}
+
+// pluginName returns the name of the plugin called name regardless of case, and whether there is one that servers
+// can turn on and off.
+func (b *Bot) pluginName(name string) (string, bool) {
+	for _, service := range b.Services {
+		for _, plugin := range service.Plugins {
+			if strings.EqualFold(plugin.Name(), name) && !service.core[plugin.Name()] {
+				return plugin.Name(), true
+			}
+		}
+	}
+	return "", false
+}
+
+// checkConfig returns the configuration with its plugins named the way they name themselves, or everything wrong
+// with it.
+func (b *Bot) checkConfig(c Config) (Config, error) {
+	errs := []error{}
+	defaults := b.limitDefaults()
+
+	checkLanguage := func(where, language string) {
+		if language != "" && LanguageName(language) == "" {
+			errs = append(errs, fmt.Errorf("%sunknown language %q, expected one of %s", where, language, strings.Join(Languages(), ", ")))
+		}
+	}
+	checkPlugins := func(where string, plugins map[string]bool) map[string]bool {
+		named := map[string]bool{}
+		for _, name := range sortedKeys(plugins) {
+			plugin, ok := b.pluginName(name)
+			if !ok {
+				errs = append(errs, fmt.Errorf("%sunknown plugin %q, or it can't be turned off", where, name))
+				continue
+			}
+			named[plugin] = plugins[name]
+		}
+		return named
+	}
+	checkLimits := func(where string, limits map[string]int) {
+		for _, name := range sortedKeys(limits) {
+			if _, ok := defaults[name]; !ok {
+				errs = append(errs, fmt.Errorf("%sunknown limit %q, expected one of %s", where, name, strings.Join(sortedKeys(defaults), ", ")))
+			} else if limits[name] < 1 {
+				errs = append(errs, fmt.Errorf("%slimit %q must be at least 1, not %d", where, name, limits[name]))
+			}
+		}
+	}
+
+	checked := Config{
+		Language: c.Language,
+		Limits:   c.Limits,
+		Guilds:   map[string]GuildConfig{},
+	}
+	if c.Language == "" {
+		errs = append(errs, errors.New("a language is needed"))
+	}
+	checkLanguage("", c.Language)
+	checked.DisabledPlugins = checkPlugins("", c.DisabledPlugins)
+	checkLimits("", c.Limits)
+
+	for _, guildID := range sortedKeys(c.Guilds) {
+		g := c.Guilds[guildID]
+		where := fmt.Sprintf("server %s: ", guildID)
+		if strings.TrimSpace(guildID) == "" {
+			errs = append(errs, errors.New("servers need an ID"))
+			continue
+		}
+		checkLanguage(where, g.Language)
+		checkLimits(where, g.Limits)
+		checked.Guilds[guildID] = GuildConfig{
+			Language: g.Language,
+			Plugins:  checkPlugins(where, g.Plugins),
+			Limits:   g.Limits,
+		}
+	}
+	return checked, errors.Join(errs...)
+}
+
+// CheckConfig returns everything wrong with a configuration, such as plugins or limits the bot doesn't have.
+// Plugins must be registered first.
+func (b *Bot) CheckConfig(c Config) error {
+	_, err := b.checkConfig(c)
+	return err
+}
+
+// Reconfigure changes the bot's configuration if it's valid, and returns everything wrong with it if it isn't.
+// It can be called while the bot is open, and messages handled afterwards see the new configuration.
+func (b *Bot) Reconfigure(c Config) error {
+	checked, err := b.checkConfig(c)
+	if err != nil {
+		return err
+	}
+	b.configMu.Lock()
+	defer b.configMu.Unlock()
+	b.Config = checked
+	return nil
+}
+
+// sortedKeys returns a map's keys in order, so problems are always reported in the same order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,110 @@
+package mmmorty_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/todd-beckman/mmmorty"
+	"github.com/todd-beckman/mmmorty/mmmortytest"
+	"github.com/todd-beckman/mmmorty/quoteplugin"
+)
+
+// newConfigBot returns a bot with the quote plugin, whose limits include "quotes".
+func newConfigBot() *mmmorty.Bot {
+	service := mmmortytest.New()
+	bot := mmmorty.NewBot()
+	bot.Language = "en"
+	bot.RegisterService(service)
+	bot.RegisterPlugin(service, quoteplugin.New())
+	return bot
+}
+
+func TestCheckConfig(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		config mmmorty.Config
+		errs   []string
+	}{
+		{
+			name: "valid",
+			config: mmmorty.Config{
+				Language:        "es",
+				DisabledPlugins: map[string]bool{"quote": true},
+				Limits:          map[string]int{"quotes": 1000},
+				Guilds: map[string]mmmorty.GuildConfig{
+					"g0": {Language: "en", Plugins: map[string]bool{"Quote": true}, Limits: map[string]int{"quotes": 1}},
+				},
+			},
+		},
+		{
+			name:   "no language",
+			config: mmmorty.Config{},
+			errs:   []string{"a language is needed"},
+		},
+		{
+			name:   "unknown language",
+			config: mmmorty.Config{Language: "klingon"},
+			errs:   []string{`unknown language "klingon"`},
+		},
+		{
+			name:   "unknown plugin",
+			config: mmmorty.Config{Language: "en", DisabledPlugins: map[string]bool{"jokes": true}},
+			errs:   []string{`unknown plugin "jokes"`},
+		},
+		{
+			name:   "unknown limit",
+			config: mmmorty.Config{Language: "en", Limits: map[string]int{"jokes": 10}},
+			errs:   []string{`unknown limit "jokes"`},
+		},
+		{
+			name:   "limit below 1",
+			config: mmmorty.Config{Language: "en", Limits: map[string]int{"quotes": 0}},
+			errs:   []string{`limit "quotes" must be at least 1, not 0`},
+		},
+		{
+			name: "server",
+			config: mmmorty.Config{
+				Language: "en",
+				Guilds: map[string]mmmorty.GuildConfig{
+					"g0": {Plugins: map[string]bool{"jokes": true}, Limits: map[string]int{"quotes": -1}},
+				},
+			},
+			errs: []string{`server g0: unknown plugin "jokes"`, `server g0: limit "quotes" must be at least 1, not -1`},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := newConfigBot().CheckConfig(test.config)
+			if len(test.errs) == 0 {
+				if err != nil {
+					t.Errorf("got error %v, expected none", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("got no error, expected %q", test.errs)
+			}
+			for _, expected := range test.errs {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("got error %q, expected it to contain %q", err, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestReconfigureKeepsConfigWhenInvalid(t *testing.T) {
+	bot := newConfigBot()
+	if err := bot.Reconfigure(mmmorty.Config{Language: "en", Limits: map[string]int{"quotes": 10}}); err != nil {
+		t.Fatal(err)
+	}
+	if limit := bot.Limit("g0", "quotes"); limit != 10 {
+		t.Fatalf("got quotes limit %d, expected 10", limit)
+	}
+
+	if err := bot.Reconfigure(mmmorty.Config{Language: "en", Limits: map[string]int{"quotes": 0}}); err == nil {
+		t.Error("got no error reconfiguring with a limit of 0")
+	}
+	if limit := bot.Limit("g0", "quotes"); limit != 10 {
+		t.Errorf("got quotes limit %d after an invalid configuration, expected 10 still", limit)
+	}
+}
@@ -0,0 +1,570 @@
+package mmmorty
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ConsoleServiceName is the service name for the Console service.
+const ConsoleServiceName string = "Console"
+
+const (
+	consoleBotID     = "morty"
+	consoleBotName   = "morty"
+	consoleUserID    = "rick"
+	consoleUserName  = "Rick"
+	consoleGuildID   = "console"
+	consoleChannelID = "general"
+)
+
+// Used to determine if a simulated role can moderate, mirroring Discord.IsModerator.
+const consoleModeratorPermissions = discordgo.PermissionAdministrator |
+	discordgo.PermissionManageChannels |
+	discordgo.PermissionManageServer
+
+var errConsoleMemberNotFound = errors.New("member not found")
+
+const consoleHelp = `Console commands:
+  /user <id> [name]        talk as another user, joining them to the current guild
+  /channel <id>            talk in another channel of the current guild
+  /guild <id>              talk in another guild, owned by the current user
+  /dm                      talk to morty in private
+  /role <name> [mod]       create a role, with moderator permissions if "mod"
+  /give <role>             give the current user a role
+  /edit <message>          edit your last message
+  /delete                  delete your last message
+  /whoami                  print the current user, channel and guild
+  /help                    print this message`
+
+// ConsoleMessage is a message typed into the console.
+type ConsoleMessage struct {
+	ID          string
+	ChannelID   string
+	AuthorID    string
+	AuthorName  string
+	Content     string
+	MessageType MessageType
+}
+
+// Channel returns the channel id for this message.
+func (m *ConsoleMessage) Channel() string {
+	return m.ChannelID
+}
+
+// UserName returns the user name for this message.
+func (m *ConsoleMessage) UserName() string {
+	return m.AuthorName
+}
+
+// UserID returns the user id for this message.
+func (m *ConsoleMessage) UserID() string {
+	return m.AuthorID
+}
+
+// UserAvatar returns the avatar url for this message.
+func (m *ConsoleMessage) UserAvatar() string {
+	return ""
+}
+
+// Message returns the message content for this message.
+func (m *ConsoleMessage) Message() string {
+	return m.Content
+}
+
+// RawMessage returns the raw message content for this message.
+func (m *ConsoleMessage) RawMessage() string {
+	return m.Content
+}
+
+// MessageID returns the message ID for this message.
+func (m *ConsoleMessage) MessageID() string {
+	return m.ID
+}
+
+// Type returns the type of message.
+func (m *ConsoleMessage) Type() MessageType {
+	return m.MessageType
+}
+
+// Console is a Service provider that reads messages from a terminal and prints replies.
+// Guilds, channels, roles and members are simulated locally.
+type Console struct {
+	in          io.Reader
+	out         io.Writer
+	messageChan chan Message
+	done        chan struct{}
+
+	// State holds the simulated guilds, channels, roles and members.
+	State       *discordgo.State
+	OwnerUserID string
+
+	mu          sync.Mutex
+	nextID      int
+	userID      string
+	userName    string
+	channelID   string
+	guildID     string
+	lastMessage *ConsoleMessage
+}
+
+// NewConsole creates a new console service reading from in and writing to out.
+func NewConsole(in io.Reader, out io.Writer) *Console {
+	c := &Console{
+		in:          in,
+		out:         out,
+		messageChan: make(chan Message, 200),
+		done:        make(chan struct{}),
+		State:       discordgo.NewState(),
+		OwnerUserID: consoleUserID,
+		userID:      consoleUserID,
+		userName:    consoleUserName,
+		channelID:   consoleChannelID,
+		guildID:     consoleGuildID,
+	}
+	c.State.User = &discordgo.User{
+		ID:       consoleBotID,
+		Username: consoleBotName,
+	}
+	c.addGuild(consoleGuildID, consoleUserID)
+	c.addChannel(consoleGuildID, consoleChannelID)
+	c.addMember(consoleGuildID, consoleUserID, consoleUserName)
+	return c
+}
+
+func (c *Console) addGuild(guildID, ownerID string) {
+	c.State.GuildAdd(&discordgo.Guild{
+		ID:      guildID,
+		Name:    guildID,
+		OwnerID: ownerID,
+		Roles: []*discordgo.Role{{
+			ID:   guildID,
+			Name: "@everyone",
+		}},
+		Members: []*discordgo.Member{},
+	})
+	c.addMember(guildID, consoleBotID, consoleBotName)
+}
+
+func (c *Console) addChannel(guildID, channelID string) {
+	c.State.ChannelAdd(&discordgo.Channel{
+		ID:      channelID,
+		GuildID: guildID,
+		Name:    channelID,
+		Type:    discordgo.ChannelTypeGuildText,
+	})
+}
+
+func (c *Console) addMember(guildID, userID, userName string) {
+	if _, err := c.State.Member(guildID, userID); err == nil {
+		return
+	}
+	c.State.MemberAdd(&discordgo.Member{
+		GuildID: guildID,
+		User: &discordgo.User{
+			ID:       userID,
+			Username: userName,
+		},
+		Roles: []string{},
+	})
+}
+
+func (c *Console) printf(format string, args ...interface{}) {
+	fmt.Fprintf(c.out, format+"\n", args...)
+}
+
+// newID returns the ID of a new message, c.mu must be held.
+func (c *Console) newID() string {
+	c.nextID++
+	return strconv.Itoa(c.nextID)
+}
+
+func (c *Console) read() {
+	defer close(c.done)
+
+	scanner := bufio.NewScanner(c.in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "/") {
+			if message := c.runDirective(line); message != nil {
+				c.messageChan <- message
+			}
+			continue
+		}
+
+		c.mu.Lock()
+		message := &ConsoleMessage{
+			ID:          c.newID(),
+			ChannelID:   c.channelID,
+			AuthorID:    c.userID,
+			AuthorName:  c.userName,
+			Content:     line,
+			MessageType: MessageTypeCreate,
+		}
+		c.lastMessage = message
+		c.mu.Unlock()
+
+		c.messageChan <- message
+	}
+}
+
+// runDirective runs a console command, returning any message it should send to the bot.
+func (c *Console) runDirective(line string) Message {
+	parts := strings.Fields(line)
+	directive, args := parts[0], parts[1:]
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch directive {
+	case "/user":
+		if len(args) < 1 {
+			c.printf("* usage: /user <id> [name]")
+			return nil
+		}
+		c.userID = args[0]
+		c.userName = args[0]
+		if len(args) > 1 {
+			c.userName = strings.Join(args[1:], " ")
+		}
+		c.addMember(c.guildID, c.userID, c.userName)
+		c.printf("* you are now %s (%s)", c.userName, c.userID)
+	case "/channel":
+		if len(args) != 1 {
+			c.printf("* usage: /channel <id>")
+			return nil
+		}
+		if _, err := c.State.Channel(args[0]); err != nil {
+			c.addChannel(c.guildID, args[0])
+		}
+		c.channelID = args[0]
+		c.printf("* you are now in #%s", c.channelID)
+	case "/guild":
+		if len(args) != 1 {
+			c.printf("* usage: /guild <id>")
+			return nil
+		}
+		if _, err := c.State.Guild(args[0]); err != nil {
+			c.addGuild(args[0], c.userID)
+			c.addChannel(args[0], args[0]+"-"+consoleChannelID)
+		}
+		c.guildID = args[0]
+		c.channelID = args[0] + "-" + consoleChannelID
+		c.addMember(c.guildID, c.userID, c.userName)
+		c.printf("* you are now in #%s of %s", c.channelID, c.guildID)
+	case "/dm":
+		channelID := "dm-" + c.userID
+		if _, err := c.State.Channel(channelID); err != nil {
+			c.State.ChannelAdd(&discordgo.Channel{
+				ID:   channelID,
+				Name: channelID,
+				Type: discordgo.ChannelTypeDM,
+			})
+		}
+		c.channelID = channelID
+		c.printf("* you are now talking to %s in private", consoleBotName)
+	case "/role":
+		if len(args) < 1 {
+			c.printf("* usage: /role <name> [mod]")
+			return nil
+		}
+		var permissions int64
+		if len(args) > 1 && args[1] == "mod" {
+			permissions = consoleModeratorPermissions
+		}
+		c.State.RoleAdd(c.guildID, &discordgo.Role{
+			ID:          c.guildID + "-" + strings.ToLower(args[0]),
+			Name:        args[0],
+			Permissions: permissions,
+		})
+		c.printf("* created role %s", args[0])
+	case "/give":
+		if len(args) != 1 {
+			c.printf("* usage: /give <role>")
+			return nil
+		}
+		roleID := c.guildID + "-" + strings.ToLower(args[0])
+		if _, err := c.State.Role(c.guildID, roleID); err != nil {
+			c.printf("* there is no role called %s", args[0])
+			return nil
+		}
+		if err := c.memberRoleAdd(c.guildID, c.userID, roleID); err != nil {
+			c.printf("* %v", err)
+		}
+	case "/edit":
+		if c.lastMessage == nil || len(args) == 0 {
+			c.printf("* usage: /edit <message>, after sending a message")
+			return nil
+		}
+		edited := *c.lastMessage
+		edited.Content = strings.Join(args, " ")
+		edited.MessageType = MessageTypeUpdate
+		c.lastMessage = &edited
+		return &edited
+	case "/delete":
+		if c.lastMessage == nil {
+			c.printf("* there is no message to delete")
+			return nil
+		}
+		deleted := *c.lastMessage
+		deleted.MessageType = MessageTypeDelete
+		c.lastMessage = nil
+		return &deleted
+	case "/whoami":
+		c.printf("* you are %s (%s) in #%s of %s", c.userName, c.userID, c.channelID, c.guildID)
+	default:
+		c.printf(consoleHelp)
+	}
+	return nil
+}
+
+func (c *Console) memberRoleAdd(guild, user, role string) error {
+	m, err := c.State.Member(guild, user)
+	if err != nil {
+		return errConsoleMemberNotFound
+	}
+	for _, r := range m.Roles {
+		if r == role {
+			return nil
+		}
+	}
+	c.setRoles(m, append(m.Roles[:len(m.Roles):len(m.Roles)], role))
+	c.printf("* %s was given role %s", m.User.Username, role)
+	return nil
+}
+
+// setRoles replaces a member's roles rather than changing them in place, so slices handed out earlier don't change
+// underneath their callers, c.mu must be held.
+func (c *Console) setRoles(m *discordgo.Member, roles []string) {
+	c.State.Lock()
+	defer c.State.Unlock()
+	m.Roles = roles
+}
+
+// Done returns a channel that is closed when the console input is exhausted.
+func (c *Console) Done() <-chan struct{} {
+	return c.done
+}
+
+// Name returns the name of the service.
+func (c *Console) Name() string {
+	return ConsoleServiceName
+}
+
+// UserName returns the bots name.
+func (c *Console) UserName() string {
+	return consoleBotName
+}
+
+// UserID returns the bots user id.
+func (c *Console) UserID() string {
+	return consoleBotID
+}
+
+// BotOwnerID returns the user id of the bot's owner.
+func (c *Console) BotOwnerID() string {
+	return c.OwnerUserID
+}
+
+// Open starts reading the console and returns a channel which all messages will be sent on.
+func (c *Console) Open() (<-chan Message, error) {
+	c.printf("* type /help for console commands, mention me with %q", c.CommandPrefix())
+	go c.read()
+	return c.messageChan, nil
+}
+
+// IsMe returns whether or not a message was sent by the bot.
+func (c *Console) IsMe(message Message) bool {
+	return message.UserID() == consoleBotID
+}
+
+// SendMessage prints a message.
+func (c *Console) SendMessage(channel, message string) error {
+	c.mu.Lock()
+	current := c.channelID
+	c.mu.Unlock()
+
+	if channel == current {
+		c.printf("%s> %s", consoleBotName, message)
+	} else {
+		c.printf("%s #%s> %s", consoleBotName, channel, message)
+	}
+	return nil
+}
+
+// SendMessageID prints a message and returns its ID.
+func (c *Console) SendMessageID(channel, message string) (string, error) {
+	c.mu.Lock()
+	id := c.newID()
+	c.mu.Unlock()
+	return id, c.SendMessage(channel, message)
+}
+
+// EditMessage prints what the bot changed one of its messages to.
+func (c *Console) EditMessage(channel, messageID, message string) error {
+	c.printf("* %s edited message %s to say: %s", consoleBotName, messageID, message)
+	return nil
+}
+
+// SendAction prints an action.
+func (c *Console) SendAction(channel, message string) error {
+	return c.SendMessage(channel, message)
+}
+
+// DeleteMessage prints that a message was deleted.
+func (c *Console) DeleteMessage(channel, messageID string) error {
+	c.printf("* %s deleted message %s", consoleBotName, messageID)
+	return nil
+}
+
+// PrivateMessage prints a private message to a user.
+func (c *Console) PrivateMessage(userID, message string) error {
+	c.printf("%s @%s> %s", consoleBotName, userID, message)
+	return nil
+}
+
+// SupportsPrivateMessages returns whether the service supports private messages.
+func (c *Console) SupportsPrivateMessages() bool {
+	return true
+}
+
+// SupportsMultiline returns whether the service supports multiline messages.
+func (c *Console) SupportsMultiline() bool {
+	return true
+}
+
+// CommandPrefix returns the command prefix for the service.
+func (c *Console) CommandPrefix() string {
+	return fmt.Sprintf("@%s ", consoleBotName)
+}
+
+// IsBotOwner returns whether or not a message sender was the owner of the bot.
+func (c *Console) IsBotOwner(message Message) bool {
+	return message.UserID() == c.OwnerUserID
+}
+
+// IsPrivate returns whether or not a message was private.
+func (c *Console) IsPrivate(message Message) bool {
+	ch, err := c.Channel(message.Channel())
+	return err == nil && ch.Type == discordgo.ChannelTypeDM
+}
+
+// IsChannelOwner returns whether or not the sender of a message owns the guild.
+func (c *Console) IsChannelOwner(message Message) bool {
+	ch, err := c.Channel(message.Channel())
+	if err != nil {
+		return false
+	}
+	g, err := c.Guild(ch.GuildID)
+	if err != nil {
+		return false
+	}
+	return g.OwnerID == message.UserID() || c.IsBotOwner(message)
+}
+
+// IsModerator returns whether or not the sender of a message is a moderator.
+func (c *Console) IsModerator(message Message) bool {
+	p, err := c.State.UserChannelPermissions(message.UserID(), message.Channel())
+	if err == nil && p&consoleModeratorPermissions != 0 {
+		return true
+	}
+	return c.IsChannelOwner(message)
+}
+
+// ChannelCount returns the number of simulated guilds.
+func (c *Console) ChannelCount() int {
+	return len(c.State.Guilds)
+}
+
+// Channel gets the simulated channel for the given ID.
+func (c *Console) Channel(channelID string) (*discordgo.Channel, error) {
+	return c.State.Channel(channelID)
+}
+
+// Guild gets the simulated guild for the given ID.
+func (c *Console) Guild(guildID string) (*discordgo.Guild, error) {
+	return c.State.Guild(guildID)
+}
+
+// GetRoleByName returns the simulated role for the given role name.
+func (c *Console) GetRoleByName(channel, roleName string) *discordgo.Role {
+	for _, r := range c.GetRoles(channel) {
+		if strings.ToLower(r.Name) == roleName {
+			return r
+		}
+	}
+	return nil
+}
+
+// GetRoles gets the list of roles for the guild of the given channel. It's a copy, since /role can add to it.
+func (c *Console) GetRoles(channel string) []*discordgo.Role {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch, err := c.Channel(channel)
+	if err != nil {
+		return []*discordgo.Role{}
+	}
+	g, err := c.Guild(ch.GuildID)
+	if err != nil {
+		return []*discordgo.Role{}
+	}
+	return append([]*discordgo.Role{}, g.Roles...)
+}
+
+// GuildLeave removes a simulated guild.
+func (c *Console) GuildLeave(guildID string) error {
+	g, err := c.Guild(guildID)
+	if err != nil {
+		return err
+	}
+	c.printf("* %s left %s", consoleBotName, guildID)
+	return c.State.GuildRemove(g)
+}
+
+// GuildMemberRoleAdd gives a simulated guild member a role.
+func (c *Console) GuildMemberRoleAdd(guild, user, role string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.memberRoleAdd(guild, user, role) == nil
+}
+
+// GuildMemberRoleRemove takes a simulated guild member's role.
+func (c *Console) GuildMemberRoleRemove(guild, user, role string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	m, err := c.State.Member(guild, user)
+	if err != nil {
+		return false
+	}
+	for i, r := range m.Roles {
+		if r == role {
+			c.setRoles(m, append(m.Roles[:i:i], m.Roles[i+1:]...))
+			c.printf("* %s lost role %s", m.User.Username, role)
+			return true
+		}
+	}
+	return false
+}
+
+// UserRoles gets the list of roles of the given simulated user.
+func (c *Console) UserRoles(guild, memberID string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	m, err := c.State.Member(guild, memberID)
+	if err != nil {
+		return []string{}
+	}
+	return append([]string{}, m.Roles...)
+}
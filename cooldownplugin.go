@@ -0,0 +1,309 @@
+package mmmorty
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cooldown is how long a command waits after it's used before it can be used again. Each is checked separately,
+// and zero means the command doesn't wait.
+type Cooldown struct {
+	User    time.Duration `json:"user"`    // before the same member can use it again
+	Channel time.Duration `json:"channel"` // before anyone can use it again in the same channel
+	Guild   time.Duration `json:"guild"`   // before anyone can use it again on the same server
+}
+
+// cooldownKey is one cooldown being waited out.
+type cooldownKey struct {
+	scope   string
+	id      string // the member, channel or guild waiting
+	command string
+}
+
+// cooldowns tracks when commands can be used again. It isn't saved, cooldowns start over when the bot does.
+type cooldowns struct {
+	mu sync.Mutex
+	// until is when each cooldown ends.
+	until map[cooldownKey]time.Time
+	// noticed is when the cooldown a member was told to wait for ends, so they're only told once.
+	noticed map[cooldownKey]time.Time
+}
+
+func newCooldowns() *cooldowns {
+	return &cooldowns{
+		until:   map[cooldownKey]time.Time{},
+		noticed: map[cooldownKey]time.Time{},
+	}
+}
+
+// use uses a command, starting its cooldowns, unless one of them hasn't ended yet. Then it returns how long until
+// the command can be used, and whether the member should be told.
+func (c *cooldowns) use(now time.Time, userID string, keys map[cooldownKey]time.Duration) (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.forget(now)
+
+	var wait time.Duration
+	var waiting cooldownKey
+	for key, d := range keys {
+		if d <= 0 {
+			continue
+		}
+		if left := c.until[key].Sub(now); left > wait {
+			wait, waiting = left, key
+		}
+	}
+
+	if wait > 0 {
+		noticeKey := waiting
+		noticeKey.id += "/" + userID
+		if c.noticed[noticeKey].Equal(c.until[waiting]) {
+			return wait, false
+		}
+		c.noticed[noticeKey] = c.until[waiting]
+		return wait, true
+	}
+
+	for key, d := range keys {
+		if d > 0 {
+			c.until[key] = now.Add(d)
+		}
+	}
+	return 0, false
+}
+
+// forget drops cooldowns and notices that have ended once there are enough of them to matter. Notices are counted
+// too, as one cooldown can be noticed by any number of users.
+func (c *cooldowns) forget(now time.Time) {
+	if len(c.until)+len(c.noticed) < 1024 {
+		return
+	}
+	for key, until := range c.until {
+		if !until.After(now) {
+			delete(c.until, key)
+		}
+	}
+	for key, until := range c.noticed {
+		if !until.After(now) {
+			delete(c.noticed, key)
+		}
+	}
+}
+
+const (
+	setCooldownCommand   = "set cooldown"
+	resetCooldownCommand = "reset cooldown"
+	cooldownCommand      = "cooldown"
+
+	cooldownUser    = "user"
+	cooldownChannel = "channel"
+	cooldownGuild   = "server"
+)
+
+var (
+	setCooldownSpec   = MustParseArgSpec("set cooldown <command:text> per <scope> <seconds:int 0-86400>")
+	resetCooldownSpec = MustParseArgSpec("reset cooldown <command:text>")
+	cooldownSpec      = MustParseArgSpec("cooldown <command:text>")
+)
+
+type cooldownPlugin struct {
+	Cooldowns map[string]map[string]Cooldown `json:"cooldowns"` // map of guild ID to command to its cooldown there
+}
+
+// Name returns the name of the plugin.
+func (p *cooldownPlugin) Name() string {
+	return "Cooldown"
+}
+
+// Help returns a list of help strings that are printed when the user requests them.
+func (p *cooldownPlugin) Help(bot *Bot, service Service, message Message, detailed bool) []string {
+	if detailed || service.IsPrivate(message) || !service.IsModerator(message) {
+		return nil
+	}
+	help := CommandHelp(service, setCooldownCommand, setCooldownSpec.Arguments(), Text(service, nil, "cooldown.help.set", nil))
+	help = append(help, CommandHelp(service, resetCooldownCommand, resetCooldownSpec.Arguments(), Text(service, nil, "cooldown.help.reset", nil))...)
+	return append(help, CommandHelp(service, cooldownCommand, cooldownSpec.Arguments(), Text(service, nil, "cooldown.help.show", nil))...)
+}
+
+// Commands returns the commands this plugin handles.
+func (p *cooldownPlugin) Commands() []Command {
+	return []Command{
+		{
+			Name:        setCooldownCommand,
+			Handler:     p.handleSetCooldown,
+			Description: "changes how long a command waits before it can be used again",
+			Spec:        setCooldownSpec,
+			Permission:  Moderator,
+		},
+		{
+			Name:        resetCooldownCommand,
+			Handler:     p.handleResetCooldown,
+			Description: "goes back to a command's usual cooldown",
+			Spec:        resetCooldownSpec,
+			Permission:  Moderator,
+		},
+		{
+			Name:        cooldownCommand,
+			Handler:     p.handleCooldown,
+			Description: "shows how long a command waits before it can be used again",
+			Spec:        cooldownSpec,
+			Permission:  Moderator,
+			Rerun:       true,
+		},
+	}
+}
+
+func (p *cooldownPlugin) Message(bot *Bot, service Service, message Message) {
+}
+
+// guildCooldowns returns a copy of the cooldowns a guild has changed.
+func (p *cooldownPlugin) guildCooldowns(guildID string) map[string]Cooldown {
+	cooldowns := map[string]Cooldown{}
+	for command, c := range p.Cooldowns[guildID] {
+		cooldowns[command] = c
+	}
+	return cooldowns
+}
+
+// describeCooldown returns a cooldown as it's shown to moderators, eg. "5 seconds per user, 1 second per channel".
+func describeCooldown(service Service, c Cooldown) string {
+	parts := []string{}
+	for _, scope := range []struct {
+		id string
+		d  time.Duration
+	}{
+		{"cooldown.user", c.User},
+		{"cooldown.channel", c.Channel},
+		{"cooldown.guild", c.Guild},
+	} {
+		if scope.d > 0 {
+			parts = append(parts, Text(service, nil, scope.id, Vars{"count": int(scope.d / time.Second)}))
+		}
+	}
+	if len(parts) == 0 {
+		return Text(service, nil, "cooldown.none", nil)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (p *cooldownPlugin) handleSetCooldown(bot *Bot, service Service, message Message) {
+	guildID, ok := settingGuildID(service, message, "cooldown.private")
+	if !ok {
+		return
+	}
+
+	args, err := setCooldownSpec.Parse(service, message)
+	if err != nil {
+		SendEphemeral(service, message.Channel(), setCooldownSpec.UsageReply(service, message, err))
+		return
+	}
+
+	c, ok := findCommand(bot, service, message, args.String("command"))
+	if !ok {
+		return
+	}
+
+	cooldown, ok := p.Cooldowns[guildID][c.name]
+	if !ok {
+		cooldown = c.cooldown
+	}
+	d := time.Duration(args.Int("seconds")) * time.Second
+	switch strings.ToLower(args.String("scope")) {
+	case cooldownUser:
+		cooldown.User = d
+	case cooldownChannel:
+		cooldown.Channel = d
+	case cooldownGuild:
+		cooldown.Guild = d
+	default:
+		SendEphemeral(service, message.Channel(), Text(service, message, "cooldown.scope", nil))
+		return
+	}
+
+	if p.Cooldowns == nil {
+		p.Cooldowns = map[string]map[string]Cooldown{}
+	}
+	if p.Cooldowns[guildID] == nil {
+		p.Cooldowns[guildID] = map[string]Cooldown{}
+	}
+	p.Cooldowns[guildID][c.name] = cooldown
+
+	reply := Text(service, message, "cooldown.set", Vars{"command": c.name, "cooldown": describeCooldown(service, cooldown)})
+	service.SendMessage(message.Channel(), reply)
+}
+
+func (p *cooldownPlugin) handleResetCooldown(bot *Bot, service Service, message Message) {
+	guildID, ok := settingGuildID(service, message, "cooldown.private")
+	if !ok {
+		return
+	}
+
+	args, err := resetCooldownSpec.Parse(service, message)
+	if err != nil {
+		SendEphemeral(service, message.Channel(), resetCooldownSpec.UsageReply(service, message, err))
+		return
+	}
+
+	c, ok := findCommand(bot, service, message, args.String("command"))
+	if !ok {
+		return
+	}
+
+	delete(p.Cooldowns[guildID], c.name)
+	if len(p.Cooldowns[guildID]) == 0 {
+		delete(p.Cooldowns, guildID)
+	}
+
+	reply := Text(service, message, "cooldown.reset", Vars{"command": c.name, "cooldown": describeCooldown(service, c.cooldown)})
+	service.SendMessage(message.Channel(), reply)
+}
+
+func (p *cooldownPlugin) handleCooldown(bot *Bot, service Service, message Message) {
+	guildID, ok := settingGuildID(service, message, "cooldown.private")
+	if !ok {
+		return
+	}
+
+	args, err := cooldownSpec.Parse(service, message)
+	if err != nil {
+		SendEphemeral(service, message.Channel(), cooldownSpec.UsageReply(service, message, err))
+		return
+	}
+
+	c, ok := findCommand(bot, service, message, args.String("command"))
+	if !ok {
+		return
+	}
+
+	cooldown, ok := p.Cooldowns[guildID][c.name]
+	if !ok {
+		cooldown = c.cooldown
+	}
+	reply := Text(service, message, "cooldown.show", Vars{"command": c.name, "cooldown": describeCooldown(service, cooldown)})
+	SendEphemeral(service, message.Channel(), reply)
+}
+
+// Load will load plugin state from a byte array.
+func (p *cooldownPlugin) Load(bot *Bot, service Service, data []byte) error {
+	if data != nil {
+		if err := json.Unmarshal(data, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save will save plugin state to a byte array.
+func (p *cooldownPlugin) Save() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// NewCooldownPlugin will create a new plugin that lets moderators change how long commands cool down on a server.
+func NewCooldownPlugin() Plugin {
+	return &cooldownPlugin{
+		Cooldowns: map[string]map[string]Cooldown{},
+	}
+}
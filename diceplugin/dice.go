@@ -2,22 +2,18 @@ package diceplugin
 
 import (
 	"encoding/json"
-	"fmt"
-	"log"
 	"math/rand"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/todd-beckman/mmmorty"
 )
 
 const (
-	maxWordCount       = 100
-	rollCommand        = "roll"
-	simpleRollTemplate = "Uh, %s, it looks like it landed on %s"
-
-	shorthandRollTemplate = "Uh, %s, it looks like they landed on %s which makes %s"
+	maxWordCount = 100
+	rollCommand  = "roll"
 )
 
 var (
@@ -29,16 +25,15 @@ var (
 type DicePlugin struct{}
 
 // Help gets the usage for this plugin
-func (p *DicePlugin) Help(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, detailed bool) []string {
-	return mmmorty.CommandHelp(service, rollCommand, "X sided die OR roll XdY",
-		"asks Morty to roll dice for you")
+func (p *DicePlugin) Help(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, detailed bool) []string {
+	return mmmorty.CommandHelp(service, rollCommand, mmmorty.Text(service, nil, "dice.help.arguments", nil),
+		mmmorty.Text(service, nil, "dice.help", nil))
 }
 
 // Load loads the plugin from the given data
-func (p *DicePlugin) Load(bot *mmmorty.Bot, service mmmorty.Discord, data []byte) error {
+func (p *DicePlugin) Load(bot *mmmorty.Bot, service mmmorty.Service, data []byte) error {
 	if data != nil {
 		if err := json.Unmarshal(data, p); err != nil {
-			log.Println("Error loading data", err)
 			return err
 		}
 	}
@@ -46,26 +41,28 @@ func (p *DicePlugin) Load(bot *mmmorty.Bot, service mmmorty.Discord, data []byte
 	return nil
 }
 
-// Message is the command handler for this plugin
-func (p *DicePlugin) Message(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage) {
-	defer bot.MessageRecover(service, message.Channel())
-
-	if service.IsMe(message) {
-		return
-	}
-
-	if mmmorty.MatchesCommand(service, rollCommand, message) {
-		p.handleRollCommand(bot, service, message)
+// Commands returns the commands this plugin handles
+func (p *DicePlugin) Commands() []mmmorty.Command {
+	return []mmmorty.Command{
+		{
+			Name:        rollCommand,
+			Handler:     p.handleRollCommand,
+			Description: "asks Morty to roll dice for you, like `6` or `3d6`",
+			Cooldown:    mmmorty.Cooldown{User: 5 * time.Second},
+			Rerun:       true,
+		},
 	}
 }
 
-func (p *DicePlugin) handleRollCommand(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage) {
-	requester := fmt.Sprintf("<@%s>", message.UserID())
+// Message is unused, this plugin's commands are routed by the bot
+func (p *DicePlugin) Message(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
+}
 
+func (p *DicePlugin) handleRollCommand(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
 	_, parts := mmmorty.ParseCommand(service, message)
 
 	if len(parts) == 0 {
-		reply := fmt.Sprintf("Uh, %s, could you tell me what to roll? `roll X sided die` or `roll XdY` should work.", requester)
+		reply := mmmorty.Text(service, message, "dice.missing", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
@@ -81,29 +78,25 @@ func (p *DicePlugin) handleRollCommand(bot *mmmorty.Bot, service mmmorty.Discord
 		return
 	}
 
-	reply := fmt.Sprintf("Uh, %s, I don't get that. Try `roll X sided die` or `roll XdY` should work.", requester)
+	reply := mmmorty.Text(service, message, "dice.unknown", nil)
 	service.SendMessage(message.Channel(), reply)
 }
 
-func (p *DicePlugin) handleSimpleRollCommand(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, parts []string) {
-	requester := fmt.Sprintf("<@%s>", message.UserID())
-
+func (p *DicePlugin) handleSimpleRollCommand(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, parts []string) {
 	sides, err := strconv.Atoi(parts[0])
 	if err != nil || sides < 1 {
-		reply := fmt.Sprintf("U1h, %s, I don't think I can roll a die with %s sides.", requester, parts[0])
+		reply := mmmorty.Text(service, message, "dice.sides", mmmorty.Vars{"sides": parts[0]})
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
 	roll := strconv.Itoa(rand.Intn(sides) + 1)
 
-	reply := fmt.Sprintf(simpleRollTemplate, requester, roll)
+	reply := mmmorty.Text(service, message, "dice.roll", mmmorty.Vars{"roll": roll})
 	service.SendMessage(message.Channel(), reply)
 }
 
-func (p *DicePlugin) handleShorthandRollCommand(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, parts []string) {
-	requester := fmt.Sprintf("<@%s>", message.UserID())
-
+func (p *DicePlugin) handleShorthandRollCommand(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, parts []string) {
 	shorthand := strings.Split(parts[0], "d")
 
 	var dice int
@@ -115,13 +108,13 @@ func (p *DicePlugin) handleShorthandRollCommand(bot *mmmorty.Bot, service mmmort
 	sides, _ := strconv.Atoi(shorthand[1])
 
 	if dice < 1 {
-		reply := fmt.Sprintf("Uh, %s, I don't think I can roll %s dice.", requester, shorthand[0])
+		reply := mmmorty.Text(service, message, "dice.dice", mmmorty.Vars{"dice": shorthand[0]})
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
 	if sides < 1 {
-		reply := fmt.Sprintf("Uh, %s, I don't think I can roll a die with %s sides.", requester, shorthand[1])
+		reply := mmmorty.Text(service, message, "dice.sides", mmmorty.Vars{"sides": shorthand[1]})
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
@@ -136,7 +129,7 @@ func (p *DicePlugin) handleShorthandRollCommand(bot *mmmorty.Bot, service mmmort
 
 	results := strings.Join(rolls, " + ")
 
-	reply := fmt.Sprintf(shorthandRollTemplate, requester, results, strconv.Itoa(sum))
+	reply := mmmorty.Text(service, message, "dice.rolls", mmmorty.Vars{"rolls": results, "sum": sum})
 	service.SendMessage(message.Channel(), reply)
 }
 
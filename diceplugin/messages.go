@@ -0,0 +1,42 @@
+package diceplugin
+
+import "github.com/todd-beckman/mmmorty"
+
+func init() {
+	mmmorty.RegisterMessages("en", map[string]string{
+		"dice.help":           "asks Morty to roll dice for you",
+		"dice.help.arguments": "X sided die OR roll XdY",
+		"dice.missing":        "Uh, {user}, could you tell me what to roll? `roll X sided die` or `roll XdY` should work.",
+		"dice.unknown":        "Uh, {user}, I don't get that. Try `roll X sided die` or `roll XdY` should work.",
+		"dice.sides":          "Uh, {user}, I don't think I can roll a die with {sides} sides.",
+		"dice.dice":           "Uh, {user}, I don't think I can roll {dice} dice.",
+		"dice.roll":           "Uh, {user}, it looks like it landed on {roll}",
+		"dice.rolls":          "Uh, {user}, it looks like they landed on {rolls} which makes {sum}",
+	})
+
+	mmmorty.RegisterMessages("es", map[string]string{
+		"dice.help":           "le pide a Morty que tire dados por ti",
+		"dice.help.arguments": "X o roll XdY",
+		"dice.missing":        "Eh, {user}, ¿me dices qué tirar? `roll X` o `roll XdY` deberían funcionar.",
+		"dice.unknown":        "Eh, {user}, no lo entiendo. Prueba `roll X` o `roll XdY`.",
+		"dice.sides":          "Eh, {user}, no creo que pueda tirar un dado de {sides} caras.",
+		"dice.dice":           "Eh, {user}, no creo que pueda tirar {dice} dados.",
+		"dice.roll":           "Eh, {user}, parece que salió un {roll}",
+		"dice.rolls":          "Eh, {user}, parece que salieron {rolls}, que suman {sum}",
+	})
+
+	mmmorty.RequirePlaceholders(map[string][]string{
+		"dice.roll":  {"roll"},
+		"dice.rolls": {"rolls"},
+	})
+
+	mmmorty.RegisterSamples(map[string]mmmorty.Vars{
+		"dice": {
+			"dice":  0,
+			"roll":  4,
+			"rolls": "[2 5 3]",
+			"sides": 0,
+			"sum":   10,
+		},
+	})
+}
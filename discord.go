@@ -1,11 +1,13 @@
 package mmmorty
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -92,7 +94,7 @@ func (m DiscordMessage) Type() MessageType {
 // Discord is a Service provider for Discord.
 type Discord struct {
 	args        []interface{}
-	messageChan chan *DiscordMessage
+	messageChan chan Message
 
 	Shards int
 
@@ -101,13 +103,16 @@ type Discord struct {
 	Sessions            []*discordgo.Session
 	OwnerUserID         string
 	ApplicationClientID string
+
+	slashMu       sync.Mutex
+	slashCommands []SlashCommand
 }
 
 // NewDiscord creates a new discord service.
 func NewDiscord(args ...interface{}) *Discord {
 	return &Discord{
 		args:        args,
-		messageChan: make(chan *DiscordMessage, 200),
+		messageChan: make(chan Message, 200),
 	}
 }
 
@@ -188,7 +193,7 @@ func (d *Discord) Name() string {
 }
 
 // Open opens the service and returns a channel which all messages will be sent on.
-func (d *Discord) Open() (<-chan *DiscordMessage, error) {
+func (d *Discord) Open() (<-chan Message, error) {
 	shards := d.Shards
 	if shards < 1 {
 		shards = 1
@@ -206,6 +211,7 @@ func (d *Discord) Open() (<-chan *DiscordMessage, error) {
 		session.AddHandler(d.onMessageCreate)
 		session.AddHandler(d.onMessageUpdate)
 		session.AddHandler(d.onMessageDelete)
+		session.AddHandler(d.onEvent)
 		session.State.TrackPresences = false
 
 		d.Sessions[i] = session
@@ -220,8 +226,22 @@ func (d *Discord) Open() (<-chan *DiscordMessage, error) {
 	return d.messageChan, nil
 }
 
+// Close closes every shard's session. Shards whose session failed to be created are skipped.
+func (d *Discord) Close() error {
+	errs := []error{}
+	for _, session := range d.Sessions {
+		if session == nil {
+			continue
+		}
+		if err := session.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("shard %d: %w", session.ShardID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // IsMe returns whether or not a message was sent by the bot.
-func (d *Discord) IsMe(message DiscordMessage) bool {
+func (d *Discord) IsMe(message Message) bool {
 	if d.Session.State.User == nil {
 		return false
 	}
@@ -231,22 +251,40 @@ func (d *Discord) IsMe(message DiscordMessage) bool {
 // SendMessage sends a message.
 func (d *Discord) SendMessage(channel, message string) error {
 	if channel == "" {
-		log.Println("Empty channel could not send message", message)
+		slog.Warn("Empty channel could not send message", "message", message)
 		return nil
 	}
 
 	if _, err := d.Session.ChannelMessageSend(channel, message); err != nil {
-		log.Println("Error sending discord message: ", err)
 		return err
 	}
 
 	return nil
 }
 
+// SendMessageID sends a message and returns its ID.
+func (d *Discord) SendMessageID(channel, message string) (string, error) {
+	if channel == "" {
+		return "", errors.New("empty channel could not send message")
+	}
+
+	m, err := d.Session.ChannelMessageSend(channel, message)
+	if err != nil {
+		return "", err
+	}
+	return m.ID, nil
+}
+
+// EditMessage changes a message the bot sent.
+func (d *Discord) EditMessage(channel, messageID, message string) error {
+	_, err := d.Session.ChannelMessageEdit(channel, messageID, message)
+	return err
+}
+
 // SendAction sends an action.
 func (d *Discord) SendAction(channel, message string) error {
 	if channel == "" {
-		log.Println("Empty channel could not send message", message)
+		slog.Warn("Empty channel could not send message", "message", message)
 		return nil
 	}
 
@@ -276,7 +314,7 @@ func (d *Discord) DeleteMessage(channel, messageID string) error {
 // SendFile sends a file.
 func (d *Discord) SendFile(channel, name string, r io.Reader) error {
 	if _, err := d.Session.ChannelFileSend(channel, name, r); err != nil {
-		log.Println("Error sending discord message: ", err)
+		slog.Error("Error sending discord file", "channel", channel, "err", err)
 		return err
 	}
 	return nil
@@ -352,19 +390,24 @@ func (d *Discord) CommandPrefix() string {
 	return fmt.Sprintf("@%s ", d.UserName())
 }
 
+// BotOwnerID returns the user id of the bot's owner.
+func (d *Discord) BotOwnerID() string {
+	return d.OwnerUserID
+}
+
 // IsBotOwner returns whether or not a message sender was the owner of the bot.
-func (d *Discord) IsBotOwner(message DiscordMessage) bool {
+func (d *Discord) IsBotOwner(message Message) bool {
 	return message.UserID() == d.OwnerUserID
 }
 
 // IsPrivate returns whether or not a message was private.
-func (d *Discord) IsPrivate(message DiscordMessage) bool {
+func (d *Discord) IsPrivate(message Message) bool {
 	c, err := d.Channel(message.Channel())
 	return err == nil && c.Type == 1
 }
 
 // IsChannelOwner returns whether or not the sender of a message is a moderator.
-func (d *Discord) IsChannelOwner(message DiscordMessage) bool {
+func (d *Discord) IsChannelOwner(message Message) bool {
 	c, err := d.Channel(message.Channel())
 	if err != nil {
 		return false
@@ -377,7 +420,7 @@ func (d *Discord) IsChannelOwner(message DiscordMessage) bool {
 }
 
 // IsModerator returns whether or not the sender of a message is a moderator.
-func (d *Discord) IsModerator(message DiscordMessage) bool {
+func (d *Discord) IsModerator(message Message) bool {
 	p, err := d.UserChannelPermissions(message.UserID(), message.Channel())
 	if err == nil {
 		if p&discordgo.PermissionAdministrator == discordgo.PermissionAdministrator || p&discordgo.PermissionManageChannels == discordgo.PermissionManageChannels || p&discordgo.PermissionManageServer == discordgo.PermissionManageServer {
@@ -426,7 +469,7 @@ func (d *Discord) GuildLeave(guildID string) (err error) {
 func (d *Discord) GuildMemberRoleAdd(guild, user, role string) bool {
 	err := d.Session.GuildMemberRoleAdd(guild, user, role)
 	if err != nil {
-		log.Println(fmt.Sprintf("%v", err))
+		slog.Error("Error changing member roles", "guild", guild, "user", user, "role", role, "err", err)
 		return false
 	}
 	return true
@@ -436,12 +479,31 @@ func (d *Discord) GuildMemberRoleAdd(guild, user, role string) bool {
 func (d *Discord) GuildMemberRoleRemove(guild, user, role string) bool {
 	err := d.Session.GuildMemberRoleRemove(guild, user, role)
 	if err != nil {
-		log.Println(fmt.Sprintf("%v", err))
+		slog.Error("Error changing member roles", "guild", guild, "user", user, "role", role, "err", err)
 		return false
 	}
 	return true
 }
 
+// ShardHealth returns whether each shard is connected to the gateway. Shards that failed to open aren't connected.
+func (d *Discord) ShardHealth() []ShardHealth {
+	shards := make([]ShardHealth, len(d.Sessions))
+	for i, session := range d.Sessions {
+		if session == nil {
+			shards[i] = ShardHealth{Shard: i}
+			continue
+		}
+		session.RLock()
+		shards[i] = ShardHealth{
+			Shard:        session.ShardID,
+			Connected:    session.DataReady,
+			HeartbeatAck: session.LastHeartbeatAck,
+		}
+		session.RUnlock()
+	}
+	return shards
+}
+
 // ChannelCount returns the number of channels the bot is in.
 func (d *Discord) ChannelCount() int {
 	return len(d.Guilds())
@@ -528,14 +590,14 @@ func (d *Discord) UserColor(userID, channelID string) int {
 func (d *Discord) UserRoles(guild, memberID string) []string {
 	member, err := d.Session.GuildMember(guild, memberID)
 	if err != nil {
-		log.Println(fmt.Sprintf("Error getting user roles: %v", err))
+		slog.Error("Error getting user roles", "guild", guild, "user", memberID, "err", err)
 		return []string{}
 	}
 	return member.Roles
 }
 
 // Nickname gets the nickname of the speaker of a message
-func (d *Discord) Nickname(message DiscordMessage) string {
+func (d *Discord) Nickname(message Message) string {
 	return d.NicknameForID(message.UserID(), message.UserName(), message.Channel())
 }
 
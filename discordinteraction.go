@@ -0,0 +1,298 @@
+package mmmorty
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	interactionResponseMessage  = 4
+	interactionResponseDeferred = 5
+	interactionFlagEphemeral    = 64
+
+	// Discord fails an interaction that isn't answered within 3 seconds, so one still waiting in the queue
+	// is acknowledged first and answered later.
+	interactionDeferAfter = 2 * time.Second
+	// Interactions can only be answered for 15 minutes, after that replies are sent to the channel instead, such as
+	// when a sprint started with a slash command ends.
+	interactionLifetime = 14 * time.Minute
+
+	slashOptionString  = 3
+	slashOptionInteger = 4
+)
+
+type discordSlashOption struct {
+	Type        int    `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+type discordSlashCommand struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Options     []discordSlashOption `json:"options,omitempty"`
+}
+
+type discordInteractionData struct {
+	Content string `json:"content"`
+	Flags   int    `json:"flags,omitempty"`
+}
+
+type discordInteractionResponse struct {
+	Type int                     `json:"type"`
+	Data *discordInteractionData `json:"data,omitempty"`
+}
+
+// RegisterSlashCommands registers the bot's slash commands with Discord, replacing any registered before.
+// Slash commands need the bot's ApplicationClientID.
+func (d *Discord) RegisterSlashCommands(commands []SlashCommand) error {
+	d.slashMu.Lock()
+	d.slashCommands = commands
+	d.slashMu.Unlock()
+
+	if d.ApplicationClientID == "" {
+		slog.Info("No application client id, slash commands are disabled")
+		return nil
+	}
+
+	payload := make([]discordSlashCommand, len(commands))
+	for i, c := range commands {
+		payload[i] = discordSlashCommand{
+			Name:        c.Name,
+			Description: c.Description,
+		}
+		for _, o := range c.Options {
+			option := discordSlashOption{
+				Type:        slashOptionString,
+				Name:        o.Name,
+				Description: o.Description,
+				Required:    o.Required,
+			}
+			if o.Integer {
+				option.Type = slashOptionInteger
+			}
+			payload[i].Options = append(payload[i].Options, option)
+		}
+	}
+
+	_, err := d.Session.Request("PUT", discordgo.EndpointAPI+"applications/"+d.ApplicationClientID+"/commands", payload)
+	return err
+}
+
+// onEvent handles interactions, which this version of discordgo only passes along as raw events.
+func (d *Discord) onEvent(s *discordgo.Session, event *discordgo.Event) {
+	if event.Type != "INTERACTION_CREATE" {
+		return
+	}
+	if err := d.HandleInteraction(event.RawData); err != nil {
+		slog.Error("Error handling interaction", "err", err)
+	}
+}
+
+// HandleInteraction passes an interaction payload to the bot as a message, as if it had been received from Discord.
+func (d *Discord) HandleInteraction(data []byte) error {
+	i, err := ParseInteraction(data)
+	if err != nil {
+		return err
+	}
+
+	d.slashMu.Lock()
+	c, ok := FindSlashCommand(d.slashCommands, i.Data.Name)
+	d.slashMu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	message := &DiscordInteraction{
+		Discord:     d,
+		Interaction: i,
+		Content:     d.CommandPrefix() + c.Content(i),
+		created:     time.Now(),
+	}
+	message.timer = time.AfterFunc(interactionDeferAfter, message.deferResponse)
+	d.messageChan <- message
+	return nil
+}
+
+// DiscordInteraction is a Message for a slash command used on Discord.
+// Replies to its channel are sent as responses to the interaction.
+type DiscordInteraction struct {
+	Discord     *Discord
+	Interaction *Interaction
+	Content     string
+
+	mu             sync.Mutex
+	created        time.Time
+	timer          *time.Timer
+	responded      bool
+	deferred       bool
+	editedOriginal bool
+}
+
+// Channel returns the channel id for this message.
+func (m *DiscordInteraction) Channel() string {
+	return m.Interaction.ChannelID
+}
+
+// UserName returns the user name for this message.
+func (m *DiscordInteraction) UserName() string {
+	author := m.Interaction.Author()
+	return m.Discord.NicknameForID(author.ID, author.Username, m.Interaction.ChannelID)
+}
+
+// UserID returns the user id for this message.
+func (m *DiscordInteraction) UserID() string {
+	return m.Interaction.Author().ID
+}
+
+// UserAvatar returns the avatar url for this message.
+func (m *DiscordInteraction) UserAvatar() string {
+	author := m.Interaction.Author()
+	return discordgo.EndpointUserAvatar(author.ID, author.Avatar)
+}
+
+// Message returns the command the interaction stands for.
+func (m *DiscordInteraction) Message() string {
+	return m.Content
+}
+
+// RawMessage returns the command the interaction stands for.
+func (m *DiscordInteraction) RawMessage() string {
+	return m.Content
+}
+
+// MessageID returns the interaction ID.
+func (m *DiscordInteraction) MessageID() string {
+	return m.Interaction.ID
+}
+
+// Type returns the type of message.
+func (m *DiscordInteraction) Type() MessageType {
+	return MessageTypeCreate
+}
+
+// ReplyService returns the service plugins use to answer the interaction.
+func (m *DiscordInteraction) ReplyService(service Service) Service {
+	return &discordInteractionService{
+		Service:     service,
+		interaction: m,
+	}
+}
+
+func (m *DiscordInteraction) deferResponse() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.responded {
+		return
+	}
+	m.deferred = true
+	if err := m.callback(discordInteractionResponse{Type: interactionResponseDeferred}); err != nil {
+		slog.Error("Error deferring interaction response", "interaction", m.Interaction.ID, "err", err)
+	}
+}
+
+func (m *DiscordInteraction) callback(response discordInteractionResponse) error {
+	_, err := m.Discord.Session.Request("POST", discordgo.EndpointAPI+"interactions/"+m.Interaction.ID+"/"+m.Interaction.Token+"/callback", response)
+	return err
+}
+
+// respond answers the interaction. The first reply responds to it, later replies are sent as follow ups.
+func (m *DiscordInteraction) respond(content string, flags int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data := &discordInteractionData{
+		Content: content,
+		Flags:   flags,
+	}
+	webhook := discordgo.EndpointWebhookToken(m.Interaction.ApplicationID, m.Interaction.Token)
+
+	var err error
+	switch {
+	case !m.responded && !m.deferred:
+		m.responded = true
+		m.timer.Stop()
+		err = m.callback(discordInteractionResponse{
+			Type: interactionResponseMessage,
+			Data: data,
+		})
+	case m.deferred && !m.editedOriginal:
+		// Whether a deferred response is ephemeral was decided when it was deferred.
+		m.editedOriginal = true
+		_, err = m.Discord.Session.Request("PATCH", webhook+"/messages/@original", data)
+	default:
+		_, err = m.Discord.Session.Request("POST", webhook, data)
+	}
+	return err
+}
+
+// finish answers the interaction with the reply if nothing has, so Discord doesn't show it as failed. A deferred
+// response nothing followed up on is deleted rather than left thinking.
+func (m *DiscordInteraction) finish(reply string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case !m.responded && !m.deferred:
+		m.responded = true
+		m.timer.Stop()
+		return m.callback(discordInteractionResponse{
+			Type: interactionResponseMessage,
+			Data: &discordInteractionData{
+				Content: reply,
+				Flags:   interactionFlagEphemeral,
+			},
+		})
+	case m.deferred && !m.editedOriginal:
+		m.editedOriginal = true
+		webhook := discordgo.EndpointWebhookToken(m.Interaction.ApplicationID, m.Interaction.Token)
+		_, err := m.Discord.Session.Request("DELETE", webhook+"/messages/@original", nil)
+		return err
+	}
+	return nil
+}
+
+// discordInteractionService is the Discord service as seen while answering an interaction.
+type discordInteractionService struct {
+	Service
+	interaction *DiscordInteraction
+}
+
+// answers returns whether a message to the channel answers the interaction.
+func (s *discordInteractionService) answers(channel string) bool {
+	return channel == s.interaction.Channel() && time.Since(s.interaction.created) < interactionLifetime
+}
+
+// SendMessage responds to the interaction, or sends a message to any other channel.
+func (s *discordInteractionService) SendMessage(channel, message string) error {
+	if !s.answers(channel) {
+		return s.Service.SendMessage(channel, message)
+	}
+	return s.interaction.respond(message, 0)
+}
+
+// SendAction responds to the interaction, or sends an action to any other channel.
+func (s *discordInteractionService) SendAction(channel, message string) error {
+	if !s.answers(channel) {
+		return s.Service.SendAction(channel, message)
+	}
+	return s.interaction.respond(message, 0)
+}
+
+// SendEphemeralMessage responds to the interaction so only the person who used it can see the reply.
+func (s *discordInteractionService) SendEphemeralMessage(channel, message string) error {
+	if !s.answers(channel) {
+		return SendEphemeral(s.Service, channel, message)
+	}
+	return s.interaction.respond(message, interactionFlagEphemeral)
+}
+
+// FinishAnswer answers the interaction with the reply if nothing has.
+func (s *discordInteractionService) FinishAnswer(reply string) error {
+	return s.interaction.finish(reply)
+}
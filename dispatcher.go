@@ -0,0 +1,304 @@
+package mmmorty
+
+import (
+	"sync"
+	"time"
+)
+
+// QueueStats is a snapshot of a service's message queue.
+type QueueStats struct {
+	// Received is the number of messages received from the service that haven't been queued yet.
+	Received int
+	// Queued is the number of messages waiting for a worker.
+	Queued int
+	// Capacity is the number of messages that can wait before edits are dropped and creates block.
+	Capacity int
+	// Workers is the number of workers handling messages.
+	Workers int
+	// Handled is the number of messages handled.
+	Handled uint64
+	// Dropped is the number of messages dropped: edits and deletes because the queue was full, and anything left once
+	// the queue was closed.
+	Dropped uint64
+	// TimedOut is the number of plugin handlers that took longer than the handler timeout.
+	TimedOut uint64
+}
+
+type queuedMessage struct {
+	message Message
+	seq     uint64
+}
+
+// dispatcher hands a service's messages to a fixed pool of workers.
+// Messages from one guild are handled one at a time and in order, and guilds take turns so a busy guild can't starve
+// the rest. When the queue is full the oldest queued edit or delete is dropped to make room, and new creates wait
+// for space rather than being dropped. Once closed, the workers stop when every queued message has been handled.
+type dispatcher struct {
+	bot     *Bot
+	service Service
+	workers int
+	size    int
+	timeout time.Duration
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	drained  *sync.Cond // signalled when the last queued message has been handled
+	closed   bool
+	seq      uint64
+	queued   int
+	queues   map[string][]queuedMessage
+	busy     map[string]bool
+	// Guilds with queued messages that no worker is handling, in the order they should be handled.
+	ready []string
+
+	handled  uint64
+	dropped  uint64
+	timedOut uint64
+}
+
+func newDispatcher(bot *Bot, service Service, workers, size int, timeout time.Duration) *dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	if size < 1 {
+		size = 1
+	}
+	d := &dispatcher{
+		bot:     bot,
+		service: service,
+		workers: workers,
+		size:    size,
+		timeout: timeout,
+		queues:  map[string][]queuedMessage{},
+		busy:    map[string]bool{},
+	}
+	d.notEmpty = sync.NewCond(&d.mu)
+	d.notFull = sync.NewCond(&d.mu)
+	d.drained = sync.NewCond(&d.mu)
+	return d
+}
+
+func (d *dispatcher) start() {
+	for i := 0; i < d.workers; i++ {
+		go d.work()
+	}
+}
+
+// push queues a message, blocking while the queue is full of creates. Messages pushed once the dispatcher is closed
+// are dropped.
+func (d *dispatcher) push(message Message) {
+	guild := guildKey(d.service, message)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for {
+		if d.closed {
+			d.dropped++
+			return
+		}
+		if d.queued < d.size || d.dropOldestEdit() {
+			break
+		}
+		if message.Type() != MessageTypeCreate {
+			d.dropped++
+			return
+		}
+		d.notFull.Wait()
+	}
+
+	d.seq++
+	d.queues[guild] = append(d.queues[guild], queuedMessage{
+		message: message,
+		seq:     d.seq,
+	})
+	d.queued++
+
+	if len(d.queues[guild]) == 1 && !d.busy[guild] {
+		d.ready = append(d.ready, guild)
+		d.notEmpty.Signal()
+	}
+}
+
+// dropOldestEdit drops the oldest queued message that isn't a create, d.mu must be held.
+func (d *dispatcher) dropOldestEdit() bool {
+	oldestGuild, oldestIndex := "", -1
+	var oldestSeq uint64
+	for guild, queue := range d.queues {
+		for i, q := range queue {
+			if q.message.Type() == MessageTypeCreate {
+				continue
+			}
+			if oldestIndex == -1 || q.seq < oldestSeq {
+				oldestGuild, oldestIndex, oldestSeq = guild, i, q.seq
+			}
+			break
+		}
+	}
+	if oldestIndex == -1 {
+		return false
+	}
+
+	queue := d.queues[oldestGuild]
+	d.queues[oldestGuild] = append(queue[:oldestIndex:oldestIndex], queue[oldestIndex+1:]...)
+	if len(d.queues[oldestGuild]) == 0 {
+		delete(d.queues, oldestGuild)
+		d.unready(oldestGuild)
+	}
+	d.queued--
+	d.dropped++
+	d.bot.Logger.Warn("Message queue is full, dropped an edit", "service", d.service.Name())
+	return true
+}
+
+// unready takes a guild that has no queued messages left out of its turn, d.mu must be held.
+func (d *dispatcher) unready(guild string) {
+	for i, g := range d.ready {
+		if g == guild {
+			d.ready = append(d.ready[:i:i], d.ready[i+1:]...)
+			return
+		}
+	}
+}
+
+// next waits for a guild that has queued messages and no worker, and takes its oldest message.
+// It returns false once the dispatcher is closed and no guild is waiting for a worker.
+func (d *dispatcher) next() (string, Message, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for {
+		for len(d.ready) == 0 {
+			if d.closed {
+				return "", nil, false
+			}
+			d.notEmpty.Wait()
+		}
+		guild := d.ready[0]
+		d.ready = d.ready[1:]
+
+		queue := d.queues[guild]
+		if len(queue) == 0 || d.busy[guild] {
+			// A worker already has the guild, and it takes its next turn when the worker is done.
+			continue
+		}
+		if len(queue) == 1 {
+			delete(d.queues, guild)
+		} else {
+			d.queues[guild] = queue[1:]
+		}
+		d.queued--
+		d.busy[guild] = true
+		d.notFull.Signal()
+		return guild, queue[0].message, true
+	}
+}
+
+// done marks a guild's message as handled and lets the guild take its next turn.
+func (d *dispatcher) done(guild string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.handled++
+	delete(d.busy, guild)
+	if len(d.queues[guild]) > 0 {
+		d.ready = append(d.ready, guild)
+		d.notEmpty.Signal()
+	}
+	if d.queued == 0 && len(d.busy) == 0 {
+		d.drained.Broadcast()
+	}
+}
+
+func (d *dispatcher) work() {
+	for {
+		guild, message, ok := d.next()
+		if !ok {
+			return
+		}
+		d.handle(message)
+		d.done(guild)
+	}
+}
+
+// close stops the workers once every queued message has been handled. Messages still queued after the timeout are
+// dropped, and it returns how many were. Nothing can be pushed once it's closed.
+func (d *dispatcher) close(timeout time.Duration) int {
+	d.mu.Lock()
+	d.closed = true
+	d.notEmpty.Broadcast()
+	d.notFull.Broadcast()
+	d.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		d.mu.Lock()
+		for d.queued > 0 || len(d.busy) > 0 {
+			d.drained.Wait()
+		}
+		d.mu.Unlock()
+		close(drained)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-drained:
+		return 0
+	case <-timer.C:
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dropped := d.queued
+	d.dropped += uint64(dropped)
+	d.queues = map[string][]queuedMessage{}
+	d.ready = nil
+	d.queued = 0
+	return dropped
+}
+
+// handle runs each plugin's part in handling a message in turn, giving up on any plugin that takes longer than the timeout.
+// A plugin that timed out keeps its lock until it finishes, but the rest of the plugins still see the message.
+func (d *dispatcher) handle(message Message) {
+	calls, done := d.bot.messageCalls(d.service, message)
+	defer done()
+	for _, c := range calls {
+		if d.timeout <= 0 {
+			d.bot.runPluginCall(message, c)
+			continue
+		}
+
+		finished := make(chan struct{})
+		go func(c pluginCall) {
+			defer close(finished)
+			d.bot.runPluginCall(message, c)
+		}(c)
+
+		timer := time.NewTimer(d.timeout)
+		select {
+		case <-finished:
+			timer.Stop()
+		case <-timer.C:
+			d.mu.Lock()
+			d.timedOut++
+			d.mu.Unlock()
+			Logger(c.service).Warn("Plugin took too long to handle a message", "timeout", d.timeout)
+		}
+	}
+}
+
+func (d *dispatcher) stats() QueueStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return QueueStats{
+		Queued:   d.queued,
+		Capacity: d.size,
+		Workers:  d.workers,
+		Handled:  d.handled,
+		Dropped:  d.dropped,
+		TimedOut: d.timedOut,
+	}
+}
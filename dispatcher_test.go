@@ -0,0 +1,299 @@
+package mmmorty_test
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/todd-beckman/mmmorty"
+	"github.com/todd-beckman/mmmorty/mmmortytest"
+)
+
+// recorder is a plugin that remembers every message it sees, in the order it sees them.
+type recorder struct {
+	mu   sync.Mutex
+	seen []string
+	// wait, if it's set, holds up every message until it's closed.
+	wait chan struct{}
+}
+
+func (r *recorder) Name() string                                                       { return "Recorder" }
+func (r *recorder) Load(*mmmorty.Bot, mmmorty.Service, []byte) error                   { return nil }
+func (r *recorder) Save() ([]byte, error)                                              { return nil, nil }
+func (r *recorder) Help(*mmmorty.Bot, mmmorty.Service, mmmorty.Message, bool) []string { return nil }
+
+func (r *recorder) Message(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
+	if r.wait != nil {
+		<-r.wait
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seen = append(r.seen, message.Message())
+}
+
+func (r *recorder) Seen() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.seen...)
+}
+
+// newTestBot opens a bot with a recorder on a fake service with guilds g0, g1, ... each with one channel, c0, c1, ...
+func newTestBot(t *testing.T, guilds int, r *recorder) (*mmmortytest.Service, *mmmorty.Bot) {
+	t.Helper()
+	service := mmmortytest.New()
+	for i := 0; i < guilds; i++ {
+		guild := fmt.Sprintf("g%d", i)
+		service.AddGuild(guild, guild, "rick")
+		service.AddChannel(guild, fmt.Sprintf("c%d", i), "general")
+	}
+
+	bot := mmmorty.NewBot()
+	bot.Store = mmmortytest.NewStore()
+	bot.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	bot.RegisterService(service)
+	bot.RegisterPlugin(service, r)
+	bot.Open()
+	t.Cleanup(func() {
+		bot.Close(time.Second)
+	})
+	return service, bot
+}
+
+// message returns a message of type t from guild n, saying content.
+func message(id int, guild int, t mmmorty.MessageType, content string) *mmmortytest.Message {
+	return &mmmortytest.Message{
+		ID:          fmt.Sprint(id),
+		ChannelID:   fmt.Sprintf("c%d", guild),
+		AuthorID:    "jerry",
+		AuthorName:  "jerry",
+		Content:     content,
+		MessageType: t,
+	}
+}
+
+// eventually fails the test if done doesn't return true within a few seconds.
+func eventually(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// next takes the next message from d in the background, so tests can check whether it's held up.
+func next(d *mmmorty.Dispatcher) <-chan mmmorty.Message {
+	c := make(chan mmmorty.Message, 1)
+	go func() {
+		if _, message, ok := d.Next(); ok {
+			c <- message
+		}
+	}()
+	return c
+}
+
+func TestDispatcherHandlesEachGuildInOrder(t *testing.T) {
+	r := &recorder{}
+	service, bot := newTestBot(t, 5, r)
+	d := mmmorty.NewDispatcher(bot, service, 4, 1000, time.Second)
+	d.Start()
+
+	for i := 0; i < 50; i++ {
+		for g := 0; g < 5; g++ {
+			d.Push(message(i*5+g, g, mmmorty.MessageTypeCreate, fmt.Sprintf("g%d %03d", g, i)))
+		}
+	}
+	if dropped := d.Close(5 * time.Second); dropped != 0 {
+		t.Fatalf("dropped %d messages on close, expected none", dropped)
+	}
+
+	seen := r.Seen()
+	if len(seen) != 250 {
+		t.Fatalf("handled %d messages, expected 250", len(seen))
+	}
+	byGuild := map[string][]string{}
+	for _, s := range seen {
+		guild := strings.Fields(s)[0]
+		byGuild[guild] = append(byGuild[guild], s)
+	}
+	for guild, messages := range byGuild {
+		if !sort.StringsAreSorted(messages) {
+			t.Errorf("%s's messages were handled out of order: %v", guild, messages)
+		}
+	}
+}
+
+func TestDispatcherDropsOldestEditWhenFull(t *testing.T) {
+	service, bot := newTestBot(t, 3, &recorder{})
+	d := mmmorty.NewDispatcher(bot, service, 1, 3, time.Second)
+
+	d.Push(message(1, 1, mmmorty.MessageTypeCreate, "a"))
+	d.Push(message(2, 2, mmmorty.MessageTypeUpdate, "b"))
+	d.Push(message(3, 1, mmmorty.MessageTypeUpdate, "c"))
+	d.Push(message(4, 0, mmmorty.MessageTypeDelete, "d"))
+
+	stats := d.Stats()
+	if stats.Queued != 3 || stats.Dropped != 1 {
+		t.Fatalf("got %d queued and %d dropped, expected 3 queued and 1 dropped", stats.Queued, stats.Dropped)
+	}
+	got := []string{}
+	for i := 0; i < 3; i++ {
+		guild, message, _ := d.Next()
+		got = append(got, message.Message())
+		d.Done(guild)
+	}
+	sort.Strings(got)
+	if strings.Join(got, " ") != "a c d" {
+		t.Errorf("got %v, expected the oldest edit, b, to be dropped", got)
+	}
+}
+
+func TestDispatcherDropsEditsWhenFullOfCreates(t *testing.T) {
+	service, bot := newTestBot(t, 1, &recorder{})
+	d := mmmorty.NewDispatcher(bot, service, 1, 2, time.Second)
+
+	d.Push(message(1, 0, mmmorty.MessageTypeCreate, "a"))
+	d.Push(message(2, 0, mmmorty.MessageTypeCreate, "b"))
+	d.Push(message(1, 0, mmmorty.MessageTypeUpdate, "a!"))
+
+	if stats := d.Stats(); stats.Queued != 2 || stats.Dropped != 1 {
+		t.Fatalf("got %d queued and %d dropped, expected 2 queued and 1 dropped", stats.Queued, stats.Dropped)
+	}
+}
+
+func TestDispatcherBlocksCreatesWhenFull(t *testing.T) {
+	service, bot := newTestBot(t, 1, &recorder{})
+	d := mmmorty.NewDispatcher(bot, service, 1, 1, time.Second)
+
+	d.Push(message(1, 0, mmmorty.MessageTypeCreate, "a"))
+	pushed := make(chan struct{})
+	go func() {
+		d.Push(message(2, 0, mmmorty.MessageTypeCreate, "b"))
+		close(pushed)
+	}()
+
+	select {
+	case <-pushed:
+		t.Fatal("a create was queued while the queue was full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	guild, _, _ := d.Next()
+	select {
+	case <-pushed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a create wasn't queued once there was room")
+	}
+	d.Done(guild)
+	if stats := d.Stats(); stats.Queued != 1 || stats.Dropped != 0 {
+		t.Errorf("got %d queued and %d dropped, expected 1 queued and none dropped", stats.Queued, stats.Dropped)
+	}
+}
+
+func TestDispatcherHandlesGuildOnceAfterDroppingItsEdit(t *testing.T) {
+	service, bot := newTestBot(t, 3, &recorder{})
+	d := mmmorty.NewDispatcher(bot, service, 1, 3, time.Second)
+
+	// Guild 0's only message is an edit, which is dropped to make room for another message from guild 0.
+	d.Push(message(1, 0, mmmorty.MessageTypeUpdate, "a0"))
+	d.Push(message(2, 1, mmmorty.MessageTypeCreate, "b"))
+	d.Push(message(3, 2, mmmorty.MessageTypeCreate, "c"))
+	d.Push(message(4, 0, mmmorty.MessageTypeCreate, "a1"))
+
+	taken := map[string]string{}
+	for i := 0; i < 3; i++ {
+		guild, message, _ := d.Next()
+		taken[guild] = message.Message()
+	}
+	if taken["g0"] != "a1" || taken["g1"] != "b" || taken["g2"] != "c" {
+		t.Fatalf("took %v, expected a1, b and c", taken)
+	}
+	d.Push(message(5, 0, mmmorty.MessageTypeCreate, "a2"))
+	d.Done("g1")
+	d.Done("g2")
+
+	second := next(d)
+	select {
+	case m := <-second:
+		t.Fatalf("took %s while a worker was still handling a1", m.Message())
+	case <-time.After(50 * time.Millisecond):
+	}
+	d.Done("g0")
+	select {
+	case m := <-second:
+		if m.Message() != "a2" {
+			t.Errorf("took %s, expected a2", m.Message())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("a2 wasn't taken once a1 was handled")
+	}
+}
+
+func TestDispatcherDropsPushesOnceClosed(t *testing.T) {
+	service, bot := newTestBot(t, 1, &recorder{})
+	d := mmmorty.NewDispatcher(bot, service, 1, 10, time.Second)
+	d.Start()
+	d.Close(time.Second)
+
+	d.Push(message(1, 0, mmmorty.MessageTypeCreate, "a"))
+	if stats := d.Stats(); stats.Queued != 0 || stats.Dropped != 1 {
+		t.Errorf("got %d queued and %d dropped, expected none queued and 1 dropped", stats.Queued, stats.Dropped)
+	}
+}
+
+func TestDispatcherTimesOutSlowHandlers(t *testing.T) {
+	r := &recorder{wait: make(chan struct{})}
+	service, bot := newTestBot(t, 1, r)
+	t.Cleanup(func() { close(r.wait) })
+	d := mmmorty.NewDispatcher(bot, service, 1, 10, 20*time.Millisecond)
+	d.Start()
+
+	d.Push(message(1, 0, mmmorty.MessageTypeCreate, "a"))
+	eventually(t, "the message to be handled", func() bool {
+		return d.Stats().Handled == 1
+	})
+	if stats := d.Stats(); stats.TimedOut != 1 {
+		t.Errorf("got %d handlers timed out, expected 1", stats.TimedOut)
+	}
+}
+
+func TestDispatcherDrainsOnClose(t *testing.T) {
+	r := &recorder{}
+	service, bot := newTestBot(t, 2, r)
+	d := mmmorty.NewDispatcher(bot, service, 2, 100, time.Second)
+	d.Start()
+
+	for i := 0; i < 20; i++ {
+		d.Push(message(i, i%2, mmmorty.MessageTypeCreate, fmt.Sprint(i)))
+	}
+	if dropped := d.Close(5 * time.Second); dropped != 0 {
+		t.Errorf("dropped %d messages on close, expected none", dropped)
+	}
+	if seen := r.Seen(); len(seen) != 20 {
+		t.Errorf("handled %d messages before closing, expected 20", len(seen))
+	}
+}
+
+func TestDispatcherDropsWhatsLeftWhenCloseTimesOut(t *testing.T) {
+	r := &recorder{wait: make(chan struct{})}
+	service, bot := newTestBot(t, 1, r)
+	t.Cleanup(func() { close(r.wait) })
+	d := mmmorty.NewDispatcher(bot, service, 1, 10, 0)
+	d.Start()
+
+	for i := 0; i < 3; i++ {
+		d.Push(message(i, 0, mmmorty.MessageTypeCreate, fmt.Sprint(i)))
+	}
+	eventually(t, "a worker to take the first message", func() bool {
+		return d.Stats().Queued == 2
+	})
+	if dropped := d.Close(50 * time.Millisecond); dropped != 2 {
+		t.Errorf("dropped %d messages on close, expected 2", dropped)
+	}
+}
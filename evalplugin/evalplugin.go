@@ -2,7 +2,6 @@ package evalplugin
 
 import (
 	"encoding/json"
-	"fmt"
 
 	"github.com/todd-beckman/mmmorty"
 )
@@ -12,13 +11,15 @@ const (
 	leaveGuild = "leave"
 )
 
+var evalSpec = mmmorty.MustParseArgSpec("eval <command>")
+
 // EvalPlugin a
 type EvalPlugin struct {
 	bot *mmmorty.Bot
 }
 
 // Help a
-func (e *EvalPlugin) Help(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, detail bool) []string {
+func (e *EvalPlugin) Help(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, detail bool) []string {
 	return []string{}
 }
 
@@ -38,41 +39,47 @@ func New() mmmorty.Plugin {
 }
 
 // Load a
-func (e *EvalPlugin) Load(bot *mmmorty.Bot, service mmmorty.Discord, data []byte) error {
+func (e *EvalPlugin) Load(bot *mmmorty.Bot, service mmmorty.Service, data []byte) error {
 	return nil
 }
 
-// Message a
-func (e *EvalPlugin) Message(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage) {
-	defer bot.MessageRecover(service, message.Channel())
-
-	if service.IsMe(message) || service.IsPrivate(message) || message.UserID() != service.OwnerUserID {
-		return
+// Commands a
+func (e *EvalPlugin) Commands() []mmmorty.Command {
+	return []mmmorty.Command{
+		{
+			Name:        eval,
+			Handler:     e.handleEval,
+			Description: "evaluates a command, for the bot owner",
+			Spec:        evalSpec,
+			Permission:  mmmorty.BotOwner,
+		},
 	}
+}
+
+// Message a
+func (e *EvalPlugin) Message(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
+}
 
-	if !mmmorty.MatchesCommand(service, eval, message) {
+func (e *EvalPlugin) handleEval(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
+	if service.IsPrivate(message) {
 		return
 	}
 
-	requester := fmt.Sprintf("<@%s>", message.UserID())
-
 	channelID := message.Channel()
 	discordChannel, err := service.Channel(channelID)
 	if err != nil {
-		reply := fmt.Sprintf("Uh, %s, something went figuring out your server.", requester)
+		reply := mmmorty.Text(service, message, "error.guild", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
-	_, parts := mmmorty.ParseCommand(service, message)
-
-	if len(parts) == 0 {
+	args, err := evalSpec.Parse(service, message)
+	if err != nil {
+		mmmorty.SendEphemeral(service, message.Channel(), evalSpec.UsageReply(service, message, err))
 		return
 	}
 
-	command := parts[1]
-
-	if command == "leave" {
+	if args.String("command") == leaveGuild {
 		guildID := discordChannel.GuildID
 
 		service.GuildLeave(guildID)
@@ -0,0 +1,17 @@
+package mmmorty
+
+import "time"
+
+// Dispatcher lets the tests drive a service's message queue directly.
+type Dispatcher = dispatcher
+
+func NewDispatcher(bot *Bot, service Service, workers, size int, timeout time.Duration) *Dispatcher {
+	return newDispatcher(bot, service, workers, size, timeout)
+}
+
+func (d *dispatcher) Start()                          { d.start() }
+func (d *dispatcher) Push(message Message)            { d.push(message) }
+func (d *dispatcher) Next() (string, Message, bool)   { return d.next() }
+func (d *dispatcher) Done(guild string)               { d.done(guild) }
+func (d *dispatcher) Close(timeout time.Duration) int { return d.close(timeout) }
+func (d *dispatcher) Stats() QueueStats               { return d.stats() }
@@ -0,0 +1,192 @@
+package mmmorty
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// guildService is a service as seen from a guild, with the guild's own settings such as its command prefix.
+// Mentioning the bot still works as a prefix there.
+type guildService struct {
+	Service
+	guildID   string
+	prefix    string
+	language  string
+	replies   map[string]string
+	plugins   map[string]bool           // plugins turned on or off in the channel, by name
+	grants    map[string]*commandGrants // commands granted to the guild's roles and members, by name
+	cooldowns map[string]Cooldown       // cooldowns the guild has changed, by command
+	logger    *slog.Logger
+	metrics   Metrics
+	// correlationID is logged with every line about the message being handled, if there is one.
+	correlationID string
+	// sent tracks the replies to the message being handled, if the service can edit them.
+	sent *replies
+}
+
+// CommandPrefix returns the guild's command prefix.
+func (s *guildService) CommandPrefix() string {
+	if s.prefix == "" {
+		return s.Service.CommandPrefix()
+	}
+	return s.prefix
+}
+
+// Language returns the language the bot replies in on the guild.
+func (s *guildService) Language() string {
+	return s.language
+}
+
+// Reply returns the guild's own template for a message.
+func (s *guildService) Reply(id string) (string, bool) {
+	t, ok := s.replies[id]
+	return t, ok
+}
+
+// SendMessage sends a message, logging it if it can't be sent. Replies to an edited message edit the replies from
+// before it was edited instead.
+func (s *guildService) SendMessage(channel, message string) error {
+	var err error
+	if s.sent != nil {
+		err = s.sent.send(channel, message)
+	} else {
+		err = s.Service.SendMessage(channel, message)
+	}
+	if err != nil {
+		s.logger.Error("Error sending message", "to", channel, "err", err)
+		s.metrics.SendFailed(s.Name())
+	}
+	return err
+}
+
+// SendAction sends an action, logging it if it can't be sent.
+func (s *guildService) SendAction(channel, message string) error {
+	err := s.Service.SendAction(channel, message)
+	if err != nil {
+		s.logger.Error("Error sending action", "to", channel, "err", err)
+		s.metrics.SendFailed(s.Name())
+	}
+	return err
+}
+
+// PrivateMessage sends a private message to a user, logging it if it can't be sent.
+func (s *guildService) PrivateMessage(userID, message string) error {
+	err := s.Service.PrivateMessage(userID, message)
+	if err != nil {
+		s.logger.Error("Error sending private message", "to", userID, "err", err)
+		s.metrics.SendFailed(s.Name())
+	}
+	return err
+}
+
+// SendEphemeralMessage sends a reply only the person who used a command can see if the service can.
+func (s *guildService) SendEphemeralMessage(channel, message string) error {
+	e, ok := s.Service.(EphemeralSender)
+	if !ok {
+		return s.SendMessage(channel, message)
+	}
+	err := e.SendEphemeralMessage(channel, message)
+	if err != nil {
+		s.logger.Error("Error sending message", "to", channel, "err", err)
+		s.metrics.SendFailed(s.Name())
+	}
+	return err
+}
+
+// commandPrefixes returns every prefix a command can start with on a service, the guild's own prefix first.
+func commandPrefixes(service Service) []string {
+	if g, ok := service.(*guildService); ok && g.prefix != "" {
+		return []string{g.prefix, g.Service.CommandPrefix()}
+	}
+	return []string{service.CommandPrefix()}
+}
+
+// trimCommandPrefix removes the command prefix from a message, and returns whether it had one.
+func trimCommandPrefix(service Service, message string) (string, bool) {
+	lowerMessage := strings.ToLower(message)
+	for _, prefix := range commandPrefixes(service) {
+		if strings.HasPrefix(lowerMessage, strings.ToLower(prefix)) {
+			return strings.TrimSpace(message[len(prefix):]), true
+		}
+	}
+	return message, false
+}
+
+// guildService returns the service as seen from the guild a message was sent in.
+func (b *Bot) guildService(service Service, message Message) Service {
+	return b.GuildService(service, message.Channel())
+}
+
+// GuildService returns the service as seen from the guild of a channel, with the guild's command prefix, language and replies.
+// Plugins are given this service while they handle a message, and can use it for replies sent later, such as timers.
+func (b *Bot) GuildService(service Service, channelID string) Service {
+	if g, ok := service.(*guildService); ok {
+		service = g.Service
+	}
+	config := b.config()
+	g := &guildService{
+		Service:  service,
+		language: config.Language,
+		logger:   b.Logger.With("service", service.Name(), "channel", channelID),
+		metrics:  b.Metrics,
+	}
+
+	channel, err := service.Channel(channelID)
+	if err != nil || channel.GuildID == "" {
+		return g
+	}
+	g.guildID = channel.GuildID
+	g.language = config.guildLanguage(channel.GuildID)
+	g.logger = g.logger.With("guild", channel.GuildID)
+	plugins := b.Services[service.Name()].Plugins
+
+	if p, ok := plugins["Prefix"].(*prefixPlugin); ok {
+		b.LockPlugin(p)
+		g.prefix = p.Prefixes[channel.GuildID]
+		b.UnlockPlugin(p)
+	}
+	if p, ok := plugins["Language"].(*languagePlugin); ok {
+		b.LockPlugin(p)
+		if language := p.Languages[channel.GuildID]; language != "" {
+			g.language = language
+		}
+		b.UnlockPlugin(p)
+	}
+	if p, ok := plugins["Reply"].(*replyPlugin); ok {
+		b.LockPlugin(p)
+		if replies := p.Replies[channel.GuildID]; len(replies) > 0 {
+			g.replies = make(map[string]string, len(replies))
+			for id, t := range replies {
+				g.replies[id] = t
+			}
+		}
+		b.UnlockPlugin(p)
+	}
+	if p, ok := plugins["Toggle"].(*togglePlugin); ok {
+		b.LockPlugin(p)
+		g.plugins = p.channelPlugins(channel.GuildID, channelID)
+		b.UnlockPlugin(p)
+	}
+	// Plugins the configuration turns on or off on the guild are only on or off until the guild says otherwise.
+	if configured := config.Guilds[channel.GuildID].Plugins; len(configured) > 0 {
+		plugins := make(map[string]bool, len(configured)+len(g.plugins))
+		for name, enabled := range configured {
+			plugins[name] = enabled
+		}
+		for name, enabled := range g.plugins {
+			plugins[name] = enabled
+		}
+		g.plugins = plugins
+	}
+	if p, ok := plugins["Permission"].(*permissionPlugin); ok {
+		b.LockPlugin(p)
+		g.grants = p.guildGrants(channel.GuildID)
+		b.UnlockPlugin(p)
+	}
+	if p, ok := plugins["Cooldown"].(*cooldownPlugin); ok {
+		b.LockPlugin(p)
+		g.cooldowns = p.guildCooldowns(channel.GuildID)
+		b.UnlockPlugin(p)
+	}
+	return g
+}
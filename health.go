@@ -0,0 +1,75 @@
+package mmmorty
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ShardHealth is whether one of a service's gateway connections is up.
+type ShardHealth struct {
+	Shard     int  `json:"shard"`
+	Connected bool `json:"connected"`
+	// HeartbeatAck is when the gateway last answered a heartbeat.
+	HeartbeatAck time.Time `json:"heartbeatAck"`
+}
+
+// ShardReporter is implemented by services that connect through shards, such as Discord.
+type ShardReporter interface {
+	ShardHealth() []ShardHealth
+}
+
+// ServiceHealth is whether a service is connected.
+type ServiceHealth struct {
+	Name    string        `json:"name"`
+	Healthy bool          `json:"healthy"`
+	Shards  []ShardHealth `json:"shards,omitempty"`
+}
+
+// Health is whether the bot is connected everywhere it should be.
+type Health struct {
+	Healthy  bool            `json:"healthy"`
+	Version  string          `json:"version"`
+	Uptime   string          `json:"uptime"`
+	Services []ServiceHealth `json:"services"`
+}
+
+// Health returns whether every shard of every service is connected. Services without shards are always healthy.
+func (b *Bot) Health() Health {
+	h := Health{
+		Healthy:  true,
+		Version:  VersionString,
+		Uptime:   b.Uptime().String(),
+		Services: []ServiceHealth{},
+	}
+	for name, service := range b.Services {
+		s := ServiceHealth{Name: name, Healthy: true}
+		if r, ok := service.Service.(ShardReporter); ok {
+			s.Shards = r.ShardHealth()
+			for _, shard := range s.Shards {
+				s.Healthy = s.Healthy && shard.Connected
+			}
+		}
+		h.Healthy = h.Healthy && s.Healthy
+		h.Services = append(h.Services, s)
+	}
+	sort.Slice(h.Services, func(i, j int) bool {
+		return h.Services[i].Name < h.Services[j].Name
+	})
+	return h
+}
+
+// HealthHandler returns an HTTP handler that serves the bot's Health as JSON, with a 503 status when it isn't healthy.
+func (b *Bot) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := b.Health()
+		w.Header().Set("Content-Type", "application/json")
+		if !h.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(w).Encode(h); err != nil {
+			b.Logger.Error("Error writing health", "err", err)
+		}
+	})
+}
@@ -2,8 +2,6 @@ package mmmorty
 
 import (
 	"encoding/json"
-	"fmt"
-	"log"
 	"sort"
 	"strings"
 )
@@ -19,7 +17,7 @@ func (p *helpPlugin) Name() string {
 }
 
 // Help returns a list of help strings that are printed when the user requests them.
-func (p *helpPlugin) Help(bot *Bot, service Discord, message DiscordMessage, detailed bool) []string {
+func (p *helpPlugin) Help(bot *Bot, service Service, message Message, detailed bool) []string {
 	privs := service.SupportsPrivateMessages() && !service.IsPrivate(message) && service.IsModerator(message)
 	if detailed && !privs {
 		return nil
@@ -28,6 +26,9 @@ func (p *helpPlugin) Help(bot *Bot, service Discord, message DiscordMessage, det
 	commands := []string{}
 
 	for _, plugin := range bot.Services[service.Name()].Plugins {
+		if !bot.PluginEnabled(service, plugin) {
+			continue
+		}
 		hasDetailed := false
 
 		if plugin == p {
@@ -47,52 +48,71 @@ func (p *helpPlugin) Help(bot *Bot, service Discord, message DiscordMessage, det
 	help := []string{}
 
 	if len(commands) > 0 {
-		help = append(help, CommandHelp(service, helpCommand, "topic", "Posts this information.")[0])
+		help = append(help, CommandHelp(service, helpCommand, Text(service, nil, "help.topic", nil), Text(service, nil, "help.help", nil))[0])
 	}
 
 	return help
 }
 
-func (p *helpPlugin) Message(bot *Bot, service Discord, message DiscordMessage) {
-	if !service.IsMe(message) {
-		if MatchesCommand(service, "help", message) || MatchesCommand(service, "command", message) {
+// Commands returns the commands this plugin handles.
+func (p *helpPlugin) Commands() []Command {
+	return []Command{
+		{
+			Name:        helpCommand,
+			Handler:     p.handleHelp,
+			Description: "lists what Morty can do",
+			Rerun:       true,
+		},
+		{
+			Name:        "command",
+			Handler:     p.handleHelp,
+			Description: "lists what Morty can do",
+			Rerun:       true,
+		},
+	}
+}
 
-			_, parts := ParseCommand(service, message)
+func (p *helpPlugin) Message(bot *Bot, service Service, message Message) {
+}
 
-			help := []string{}
+func (p *helpPlugin) handleHelp(bot *Bot, service Service, message Message) {
+	_, parts := ParseCommand(service, message)
 
-			for _, plugin := range bot.Services[service.Name()].Plugins {
-				h := plugin.Help(bot, service, message, false)
-				if h != nil && len(h) > 0 {
-					help = append(help, h...)
-				}
-			}
+	help := []string{}
 
-			if len(parts) == 0 {
-				sort.Strings(help)
-				if service.SupportsPrivateMessages() {
-					help = append([]string{fmt.Sprintf("All commands can be used in private messages without the `%s` prefix.", service.CommandPrefix())}, help...)
-				}
-			}
+	for _, plugin := range bot.Services[service.Name()].Plugins {
+		if !bot.PluginEnabled(service, plugin) {
+			continue
+		}
+		h := plugin.Help(bot, service, message, false)
+		if h != nil && len(h) > 0 {
+			help = append(help, h...)
+		}
+	}
+
+	if len(parts) == 0 {
+		sort.Strings(help)
+		if service.SupportsPrivateMessages() {
+			help = append([]string{Text(service, nil, "help.private", Vars{"prefix": service.CommandPrefix()})}, help...)
+		}
+	}
 
-			if service.SupportsMultiline() {
-				service.SendMessage(message.Channel(), strings.Join(help, "\n"))
-			} else {
-				for _, h := range help {
-					if err := service.SendMessage(message.Channel(), h); err != nil {
-						break
-					}
-				}
+	if service.SupportsMultiline() {
+		SendEphemeral(service, message.Channel(), strings.Join(help, "\n"))
+	} else {
+		for _, h := range help {
+			if err := SendEphemeral(service, message.Channel(), h); err != nil {
+				break
 			}
 		}
 	}
 }
 
 // Load will load plugin state from a byte array.
-func (p *helpPlugin) Load(bot *Bot, service Discord, data []byte) error {
+func (p *helpPlugin) Load(bot *Bot, service Service, data []byte) error {
 	if data != nil {
 		if err := json.Unmarshal(data, p); err != nil {
-			log.Println("Error loading data", err)
+			return err
 		}
 	}
 	return nil
@@ -2,6 +2,8 @@ package mmmorty
 
 import (
 	"errors"
+
+	"github.com/bwmarrin/discordgo"
 )
 
 // MessageType is a type used to determine the CRUD state of a message.
@@ -19,26 +21,97 @@ const (
 // ErrAlreadyJoined is an error dispatched on Join if the bot is already joined to the request.
 var ErrAlreadyJoined = errors.New("already joined")
 
+// Message is a message interface, wraps a single message from a service.
+type Message interface {
+	Channel() string
+	UserName() string
+	UserID() string
+	UserAvatar() string
+	Message() string
+	RawMessage() string
+	MessageID() string
+	Type() MessageType
+}
+
+// Service is a service interface, wraps a single service such as Discord.
+type Service interface {
+	Name() string
+	UserName() string
+	UserID() string
+	BotOwnerID() string
+	Open() (<-chan Message, error)
+	IsMe(message Message) bool
+	SendMessage(channel, message string) error
+	SendAction(channel, message string) error
+	DeleteMessage(channel, messageID string) error
+	PrivateMessage(userID, message string) error
+	SupportsPrivateMessages() bool
+	SupportsMultiline() bool
+	CommandPrefix() string
+	IsBotOwner(message Message) bool
+	IsPrivate(message Message) bool
+	IsChannelOwner(message Message) bool
+	IsModerator(message Message) bool
+	ChannelCount() int
+	Channel(channelID string) (*discordgo.Channel, error)
+	Guild(guildID string) (*discordgo.Guild, error)
+	GetRoleByName(channel, roleName string) *discordgo.Role
+	GetRoles(channel string) []*discordgo.Role
+	GuildLeave(guildID string) error
+	GuildMemberRoleAdd(guild, user, role string) bool
+	GuildMemberRoleRemove(guild, user, role string) bool
+	UserRoles(guild, memberID string) []string
+}
+
 // LoadFunc is the function signature for a load handler.
-type LoadFunc func(*Bot, Discord, []byte) error
+type LoadFunc func(*Bot, Service, []byte) error
 
 // SaveFunc is the function signature for a save handler.
 type SaveFunc func() ([]byte, error)
 
 // HelpFunc is the function signature for a help handler.
-type HelpFunc func(*Bot, Discord, DiscordMessage, bool) []string
+type HelpFunc func(*Bot, Service, Message, bool) []string
 
 // MessageFunc is the function signature for a message handler.
-type MessageFunc func(*Bot, Discord, DiscordMessage)
+type MessageFunc func(*Bot, Service, Message)
 
 // StatsFunc is the function signature for a stats handler.
-type StatsFunc func(*Bot, Discord, DiscordMessage) []string
+type StatsFunc func(*Bot, Service, Message) []string
 
 // Plugin is a plugin interface, supports loading and saving to a byte array and has help and message handlers.
 type Plugin interface {
 	Name() string
-	Load(*Bot, Discord, []byte) error
+	Load(*Bot, Service, []byte) error
 	Save() ([]byte, error)
-	Help(*Bot, Discord, DiscordMessage, bool) []string
-	Message(*Bot, Discord, DiscordMessage)
+	Help(*Bot, Service, Message, bool) []string
+	Message(*Bot, Service, Message)
+}
+
+// Timer is a timer started with Bot.AfterFunc.
+type Timer interface {
+	// Stop stops the timer, returning false if it has already fired or been stopped.
+	Stop() bool
+}
+
+// Closer is implemented by plugins that need to tidy up when the bot closes, such as by stopping timers.
+// Close is called once for each service the plugin is registered on, with the plugin's lock held, after the bot has
+// stopped handling messages and before it saves.
+type Closer interface {
+	Close(*Bot, Service) error
+}
+
+// StatsProvider is implemented by plugins with stats to show in the stats command.
+// Stats is a StatsFunc, and returns lines about the plugin where the message was sent, such as how many quotes a
+// server has.
+type StatsProvider interface {
+	Stats(*Bot, Service, Message) []string
+}
+
+// GuildDataEditor is implemented by plugins whose data for each guild can be edited from the admin dashboard.
+// The plugin's lock is held while these are called.
+type GuildDataEditor interface {
+	// GuildData returns a guild's data, which is shown as JSON.
+	GuildData(service Service, guildID string) interface{}
+	// SetGuildData replaces a guild's data with JSON in the shape GuildData returns, or explains why it can't.
+	SetGuildData(bot *Bot, service Service, guildID string, data []byte) error
 }
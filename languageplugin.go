@@ -0,0 +1,151 @@
+package mmmorty
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	setLanguageCommand   = "set language"
+	resetLanguageCommand = "reset language"
+)
+
+var setLanguageSpec = MustParseArgSpec("set language <language>")
+
+type languagePlugin struct {
+	Languages map[string]string `json:"languages"` // map of guild ID to language code
+}
+
+// Name returns the name of the plugin.
+func (p *languagePlugin) Name() string {
+	return "Language"
+}
+
+// Help returns a list of help strings that are printed when the user requests them.
+func (p *languagePlugin) Help(bot *Bot, service Service, message Message, detailed bool) []string {
+	if detailed || service.IsPrivate(message) || !service.IsModerator(message) {
+		return nil
+	}
+	help := CommandHelp(service, setLanguageCommand, setLanguageSpec.Arguments(), Text(service, nil, "language.help.set", Vars{"languages": languageList()}))
+	return append(help, CommandHelp(service, resetLanguageCommand, "", Text(service, nil, "language.help.reset", nil))...)
+}
+
+// Commands returns the commands this plugin handles.
+func (p *languagePlugin) Commands() []Command {
+	return []Command{
+		{
+			Name:        setLanguageCommand,
+			Handler:     p.handleSetLanguage,
+			Description: "sets the language I speak on this server",
+			Spec:        setLanguageSpec,
+			Permission:  Moderator,
+		},
+		{
+			Name:        resetLanguageCommand,
+			Handler:     p.handleResetLanguage,
+			Description: "goes back to the language I speak by default",
+			Permission:  Moderator,
+		},
+	}
+}
+
+func (p *languagePlugin) Message(bot *Bot, service Service, message Message) {
+}
+
+// languageList returns the languages Morty speaks, eg. "`en` (English), `es` (Español)".
+func languageList() string {
+	languages := []string{}
+	for _, code := range Languages() {
+		languages = append(languages, fmt.Sprintf("`%s` (%s)", code, LanguageName(code)))
+	}
+	return strings.Join(languages, ", ")
+}
+
+// findLanguage returns the code of the language with the given code or name.
+func findLanguage(language string) (string, bool) {
+	for _, code := range Languages() {
+		if strings.EqualFold(language, code) || strings.EqualFold(language, LanguageName(code)) {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+// inLanguage returns the service as seen from the same guild, but replying in another language.
+func inLanguage(service Service, code string) Service {
+	if g, ok := service.(*guildService); ok {
+		changed := *g
+		changed.language = code
+		return &changed
+	}
+	return &guildService{
+		Service:  service,
+		language: code,
+	}
+}
+
+func (p *languagePlugin) handleSetLanguage(bot *Bot, service Service, message Message) {
+	guildID, ok := settingGuildID(service, message, "language.private")
+	if !ok {
+		return
+	}
+
+	args, err := setLanguageSpec.Parse(service, message)
+	if err != nil {
+		SendEphemeral(service, message.Channel(), setLanguageSpec.UsageReply(service, message, err))
+		return
+	}
+
+	code, ok := findLanguage(args.String("language"))
+	if !ok {
+		reply := Text(service, message, "language.unknown", Vars{"languages": languageList()})
+		SendEphemeral(service, message.Channel(), reply)
+		return
+	}
+
+	if p.Languages == nil {
+		p.Languages = map[string]string{}
+	}
+	p.Languages[guildID] = code
+
+	// Reply in the new language.
+	service = inLanguage(service, code)
+	reply := Text(service, message, "language.set", Vars{"language": LanguageName(code)})
+	service.SendMessage(message.Channel(), reply)
+}
+
+func (p *languagePlugin) handleResetLanguage(bot *Bot, service Service, message Message) {
+	guildID, ok := settingGuildID(service, message, "language.private")
+	if !ok {
+		return
+	}
+
+	delete(p.Languages, guildID)
+
+	service = inLanguage(service, bot.config().guildLanguage(guildID))
+	reply := Text(service, message, "language.reset", Vars{"language": LanguageName(Language(service))})
+	service.SendMessage(message.Channel(), reply)
+}
+
+// Load will load plugin state from a byte array.
+func (p *languagePlugin) Load(bot *Bot, service Service, data []byte) error {
+	if data != nil {
+		if err := json.Unmarshal(data, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save will save plugin state to a byte array.
+func (p *languagePlugin) Save() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// NewLanguagePlugin will create a new language plugin.
+func NewLanguagePlugin() Plugin {
+	return &languagePlugin{
+		Languages: map[string]string{},
+	}
+}
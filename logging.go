@@ -0,0 +1,54 @@
+package mmmorty
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+)
+
+// NewLogger returns a logger that writes lines to w as "text" or "json", leaving out anything below level.
+func NewLogger(w io.Writer, format string, level slog.Level) (*slog.Logger, error) {
+	options := &slog.HandlerOptions{Level: level}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q, expected \"text\" or \"json\"", format)
+}
+
+// Logger returns the logger for a service. While a plugin handles a message its lines carry the guild, channel,
+// user, plugin and command, and the message's correlation ID.
+func Logger(service Service) *slog.Logger {
+	if g, ok := service.(*guildService); ok && g.logger != nil {
+		return g.logger
+	}
+	return slog.Default()
+}
+
+// withLogger returns the service logging to a different logger, sharing everything else.
+func withLogger(service Service, logger *slog.Logger) Service {
+	g, ok := service.(*guildService)
+	if !ok {
+		return service
+	}
+	copied := *g
+	copied.logger = logger
+	return &copied
+}
+
+var correlationCount uint64
+
+// newCorrelationID returns an ID for a message that's logged with every line about handling it.
+func newCorrelationID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatUint(atomic.AddUint64(&correlationCount, 1), 10)
+	}
+	return hex.EncodeToString(b)
+}
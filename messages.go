@@ -0,0 +1,248 @@
+package mmmorty
+
+func init() {
+	RegisterLanguage("en", "English", nil)
+	RegisterLanguage("es", "Español", nil)
+
+	RegisterMessages("en", map[string]string{
+		"error.guild":   "Uh, {user}, something went figuring out your server.",
+		"error.denied":  "Uh, {user}, I don't think I can let you do that.",
+		"error.command": "Uh, {user}, I don't have a command called `{command}`.",
+		"error.silent":  "Uh, {user}, I didn't have anything to say to that.",
+
+		"args.usage":      "Uh, {user}, {problem}. Have you tried `{usage}`?",
+		"args.expected":   "I expected {word}",
+		"args.missing":    "I need the {name}",
+		"args.int":        "the {name} should be a number",
+		"args.range":      "the {name} should be a number from {min} to {max}",
+		"args.minute":     "the {name} should be a minute from :00 to :59",
+		"args.unexpected": "I didn't expect {words}",
+
+		"help.private": "All commands can be used in private messages without the `{prefix}` prefix.",
+		"help.topic":   "topic",
+		"help.help":    "Posts this information.",
+
+		"prefix.help.set":      "sets the prefix for commands on this server, mentioning me still works.",
+		"prefix.help.reset":    "goes back to only answering mentions.",
+		"prefix.private":       "Uh, {user}, prefixes are set for a server, not in private.",
+		"prefix.invalid.one":   "Uh, {user}, a prefix should be {count} character and not look like a mention, like `!`.",
+		"prefix.invalid.other": "Uh, {user}, a prefix should be up to {count} characters and not look like a mention, like `!` or `m.`.",
+		"prefix.set":           "Ok, {user}, commands here can start with `{prefix}` now. Mentioning me still works too.",
+		"prefix.reset":         "Ok, {user}, I'll only answer when I'm mentioned here.",
+
+		"language.help.set":   "sets the language I speak on this server, I know {languages}.",
+		"language.help.reset": "goes back to the language I speak by default.",
+		"language.private":    "Uh, {user}, languages are set for a server, not in private.",
+		"language.unknown":    "Uh, {user}, I don't speak that. I know {languages}.",
+		"language.set":        "Ok, {user}, I'll speak {language} here now.",
+		"language.reset":      "Ok, {user}, I'll go back to speaking {language} here.",
+
+		"reply.help.set":          "uses your own words for one of my replies on this server, like `{example}`.",
+		"reply.help.preview":      "shows how a reply would look without changing it.",
+		"reply.help.reset":        "goes back to how I usually say a reply.",
+		"reply.private":           "Uh, {user}, replies are set for a server, not in private.",
+		"reply.unknown":           "Uh, {user}, I don't have a reply called `{id}`.",
+		"reply.placeholders":      "Uh, {user}, I can't fill in {placeholders} there. `{id}` can use {known}.",
+		"reply.placeholders.none": "Uh, {user}, I can't fill in {placeholders} there. `{id}` doesn't have anything to fill in.",
+		"reply.missing":           "Uh, {user}, `{id}` needs {placeholders} in it.",
+		"reply.preview":           "This is how `{id}` would look:\n{preview}",
+		"reply.set":               "Ok, {user}, this is how `{id}` looks here now:\n{preview}",
+		"reply.reset.missing":     "Uh, {user}, `{id}` is already how I usually say it.",
+		"reply.reset":             "Ok, {user}, I'll go back to how I usually say `{id}`.",
+
+		"toggle.help.list":         "lists which plugins are on in this channel.",
+		"toggle.help.enable":       "turns a plugin on across this server.",
+		"toggle.help.disable":      "turns a plugin off across this server.",
+		"toggle.help.enable.here":  "turns a plugin on in just this channel.",
+		"toggle.help.disable.here": "turns a plugin off in just this channel.",
+		"toggle.private":           "Uh, {user}, plugins are turned on and off for a server, not in private.",
+		"toggle.unknown":           "Uh, {user}, I don't have a plugin called `{plugin}`. I have {plugins}.",
+		"toggle.core":              "Uh, {user}, `{plugin}` is always on.",
+		"toggle.list":              "This is what's on in this channel:",
+		"toggle.on":                "`{plugin}` is on",
+		"toggle.off":               "`{plugin}` is off",
+		"toggle.on.here":           "`{plugin}` is on, just in this channel",
+		"toggle.off.here":          "`{plugin}` is off, just in this channel",
+		"toggle.enabled":           "Ok, {user}, `{plugin}` is on across this server now.",
+		"toggle.disabled":          "Ok, {user}, `{plugin}` is off across this server now.",
+		"toggle.enabled.here":      "Ok, {user}, `{plugin}` is on in this channel now.",
+		"toggle.disabled.here":     "Ok, {user}, `{plugin}` is off in this channel now.",
+		"toggle.blocked":           "Uh, {user}, `{plugin}` is off in this channel.",
+
+		"permission.help.grant":  "lets a role or member use a command they otherwise couldn't.",
+		"permission.help.revoke": "takes back a command granted to a role or member.",
+		"permission.help.list":   "shows who can use a command.",
+		"permission.everyone":    "everyone",
+		"permission.moderator":   "moderators",
+		"permission.guildowner":  "the server's owner",
+		"permission.botowner":    "my Rick",
+		"permission.private":     "Uh, {user}, commands are granted on a server, not in private.",
+		"permission.above":       "Uh, {user}, `{command}` is for {level}, so I can't let you grant it.",
+		"permission.who":         "Uh, {user}, I can't find a role called {who}. Mentioning the role or member should work.",
+		"permission.granted":     "Ok, {user}, {who} can use `{command}` now.",
+		"permission.missing":     "Uh, {user}, `{command}` wasn't granted to {who}.",
+		"permission.revoked":     "Ok, {user}, I took `{command}` back from {who}.",
+		"permission.level":       "`{command}` is for {level}.",
+		"permission.grants":      "It's also granted to {who}.",
+
+		"cooldown.help.set":      "changes how long a command waits before it can be used again, per `user`, `channel` or `server`.",
+		"cooldown.help.reset":    "goes back to a command's usual cooldown.",
+		"cooldown.help.show":     "shows how long a command waits before it can be used again.",
+		"cooldown.private":       "Uh, {user}, cooldowns are set for a server, not in private.",
+		"cooldown.scope":         "Uh, {user}, a cooldown is per `user`, `channel` or `server`.",
+		"cooldown.user.one":      "{count} second per user",
+		"cooldown.user.other":    "{count} seconds per user",
+		"cooldown.channel.one":   "{count} second per channel",
+		"cooldown.channel.other": "{count} seconds per channel",
+		"cooldown.guild.one":     "{count} second per server",
+		"cooldown.guild.other":   "{count} seconds per server",
+		"cooldown.none":          "no cooldown",
+		"cooldown.show":          "`{command}` has {cooldown}.",
+		"cooldown.set":           "Ok, {user}, `{command}` has {cooldown} here now.",
+		"cooldown.reset":         "Ok, {user}, `{command}` is back to {cooldown}.",
+		"cooldown.wait.one":      "Uh, {user}, give me a second. You can use `{command}` again in {count} second.",
+		"cooldown.wait.other":    "Uh, {user}, give me a second. You can use `{command}` again in {count} seconds.",
+		"stats.help":             "shows how Morty is doing.",
+		"stats.version":          "**Mmmorty {version}**, up for {uptime}",
+		"stats.guilds":           "Servers: {guilds}, shards connected: {connected} of {shards}",
+		"stats.memory":           "Memory: {memory} MB in use, {goroutines} goroutines",
+	})
+
+	RegisterMessages("es", map[string]string{
+		"error.guild":   "Eh, {user}, algo salió mal averiguando cuál es tu servidor.",
+		"error.denied":  "Eh, {user}, no creo que pueda dejarte hacer eso.",
+		"error.command": "Eh, {user}, no tengo ningún comando llamado `{command}`.",
+		"error.silent":  "Eh, {user}, no tenía nada que decir a eso.",
+
+		"args.usage":      "Eh, {user}, {problem}. ¿Has probado `{usage}`?",
+		"args.expected":   "esperaba {word}",
+		"args.missing":    "me falta `{name}`",
+		"args.int":        "`{name}` debería ser un número",
+		"args.range":      "`{name}` debería ser un número del {min} al {max}",
+		"args.minute":     "`{name}` debería ser un minuto de :00 a :59",
+		"args.unexpected": "no esperaba {words}",
+
+		"help.private": "Todos los comandos se pueden usar en mensajes privados sin el prefijo `{prefix}`.",
+		"help.topic":   "tema",
+		"help.help":    "Muestra esta información.",
+
+		"prefix.help.set":      "cambia el prefijo de los comandos en este servidor, mencionarme sigue funcionando.",
+		"prefix.help.reset":    "vuelve a responder solo cuando me mencionan.",
+		"prefix.private":       "Eh, {user}, los prefijos son de un servidor, no de mensajes privados.",
+		"prefix.invalid.one":   "Eh, {user}, un prefijo debería tener {count} carácter y no parecer una mención, como `!`.",
+		"prefix.invalid.other": "Eh, {user}, un prefijo debería tener hasta {count} caracteres y no parecer una mención, como `!` o `m.`.",
+		"prefix.set":           "Vale, {user}, ahora los comandos aquí pueden empezar con `{prefix}`. Mencionarme también sigue funcionando.",
+		"prefix.reset":         "Vale, {user}, aquí solo responderé cuando me mencionen.",
+
+		"language.help.set":   "cambia el idioma que hablo en este servidor, sé {languages}.",
+		"language.help.reset": "vuelve al idioma que hablo por defecto.",
+		"language.private":    "Eh, {user}, los idiomas son de un servidor, no de mensajes privados.",
+		"language.unknown":    "Eh, {user}, no hablo eso. Sé {languages}.",
+		"language.set":        "Vale, {user}, ahora hablaré {language} aquí.",
+		"language.reset":      "Vale, {user}, volveré a hablar {language} aquí.",
+
+		"reply.help.set":          "usa tus propias palabras para una de mis respuestas en este servidor, como `{example}`.",
+		"reply.help.preview":      "muestra cómo quedaría una respuesta sin cambiarla.",
+		"reply.help.reset":        "vuelve a como suelo decir una respuesta.",
+		"reply.private":           "Eh, {user}, las respuestas son de un servidor, no de mensajes privados.",
+		"reply.unknown":           "Eh, {user}, no tengo ninguna respuesta llamada `{id}`.",
+		"reply.placeholders":      "Eh, {user}, no puedo rellenar {placeholders} ahí. `{id}` puede usar {known}.",
+		"reply.placeholders.none": "Eh, {user}, no puedo rellenar {placeholders} ahí. `{id}` no tiene nada que rellenar.",
+		"reply.missing":           "Eh, {user}, `{id}` necesita {placeholders}.",
+		"reply.preview":           "Así quedaría `{id}`:\n{preview}",
+		"reply.set":               "Vale, {user}, así queda `{id}` aquí ahora:\n{preview}",
+		"reply.reset.missing":     "Eh, {user}, `{id}` ya está como suelo decirlo.",
+		"reply.reset":             "Vale, {user}, volveré a decir `{id}` como siempre.",
+
+		"toggle.help.list":         "muestra qué plugins están activados en este canal.",
+		"toggle.help.enable":       "activa un plugin en todo este servidor.",
+		"toggle.help.disable":      "desactiva un plugin en todo este servidor.",
+		"toggle.help.enable.here":  "activa un plugin solo en este canal.",
+		"toggle.help.disable.here": "desactiva un plugin solo en este canal.",
+		"toggle.private":           "Eh, {user}, los plugins se activan y desactivan en un servidor, no en mensajes privados.",
+		"toggle.unknown":           "Eh, {user}, no tengo ningún plugin llamado `{plugin}`. Tengo {plugins}.",
+		"toggle.core":              "Eh, {user}, `{plugin}` siempre está activado.",
+		"toggle.list":              "Esto es lo que está activado en este canal:",
+		"toggle.on":                "`{plugin}` está activado",
+		"toggle.off":               "`{plugin}` está desactivado",
+		"toggle.on.here":           "`{plugin}` está activado, solo en este canal",
+		"toggle.off.here":          "`{plugin}` está desactivado, solo en este canal",
+		"toggle.enabled":           "Vale, {user}, ahora `{plugin}` está activado en todo este servidor.",
+		"toggle.disabled":          "Vale, {user}, ahora `{plugin}` está desactivado en todo este servidor.",
+		"toggle.enabled.here":      "Vale, {user}, ahora `{plugin}` está activado en este canal.",
+		"toggle.disabled.here":     "Vale, {user}, ahora `{plugin}` está desactivado en este canal.",
+		"toggle.blocked":           "Eh, {user}, `{plugin}` está desactivado en este canal.",
+
+		"permission.help.grant":  "deja a un rol o miembro usar un comando que de otra forma no podría.",
+		"permission.help.revoke": "retira un comando concedido a un rol o miembro.",
+		"permission.help.list":   "muestra quién puede usar un comando.",
+		"permission.everyone":    "todo el mundo",
+		"permission.moderator":   "los moderadores",
+		"permission.guildowner":  "el dueño del servidor",
+		"permission.botowner":    "mi Rick",
+		"permission.private":     "Eh, {user}, los comandos se conceden en un servidor, no en mensajes privados.",
+		"permission.above":       "Eh, {user}, `{command}` es para {level}, así que no puedo dejarte concederlo.",
+		"permission.who":         "Eh, {user}, no encuentro ningún rol llamado {who}. Mencionar al rol o al miembro debería funcionar.",
+		"permission.granted":     "Vale, {user}, ahora {who} puede usar `{command}`.",
+		"permission.missing":     "Eh, {user}, `{command}` no estaba concedido a {who}.",
+		"permission.revoked":     "Vale, {user}, le retiré `{command}` a {who}.",
+		"permission.level":       "`{command}` es para {level}.",
+		"permission.grants":      "También está concedido a {who}.",
+
+		"cooldown.help.set":      "cambia cuánto espera un comando antes de poder usarse otra vez, por `user`, `channel` o `server`.",
+		"cooldown.help.reset":    "vuelve a la espera normal de un comando.",
+		"cooldown.help.show":     "muestra cuánto espera un comando antes de poder usarse otra vez.",
+		"cooldown.private":       "Eh, {user}, las esperas son de un servidor, no de mensajes privados.",
+		"cooldown.scope":         "Eh, {user}, una espera es por `user`, `channel` o `server`.",
+		"cooldown.user.one":      "{count} segundo por usuario",
+		"cooldown.user.other":    "{count} segundos por usuario",
+		"cooldown.channel.one":   "{count} segundo por canal",
+		"cooldown.channel.other": "{count} segundos por canal",
+		"cooldown.guild.one":     "{count} segundo por servidor",
+		"cooldown.guild.other":   "{count} segundos por servidor",
+		"cooldown.none":          "ninguna espera",
+		"cooldown.show":          "`{command}` tiene {cooldown}.",
+		"cooldown.set":           "Vale, {user}, ahora `{command}` tiene {cooldown} aquí.",
+		"cooldown.reset":         "Vale, {user}, `{command}` vuelve a tener {cooldown}.",
+		"cooldown.wait.one":      "Eh, {user}, dame un segundo. Podrás usar `{command}` otra vez en {count} segundo.",
+		"cooldown.wait.other":    "Eh, {user}, dame un segundo. Podrás usar `{command}` otra vez en {count} segundos.",
+		"stats.help":             "muestra cómo le va a Morty.",
+		"stats.version":          "**Mmmorty {version}**, activo desde hace {uptime}",
+		"stats.guilds":           "Servidores: {guilds}, shards conectados: {connected} de {shards}",
+		"stats.memory":           "Memoria: {memory} MB en uso, {goroutines} goroutines",
+	})
+
+	RegisterSamples(map[string]Vars{
+		"": {
+			"command":      "roll",
+			"connected":    1,
+			"cooldown":     "5 seconds per user",
+			"count":        3,
+			"example":      "roll 3d6",
+			"goroutines":   42,
+			"guilds":       3,
+			"id":           "pick.choice",
+			"known":        "`{choice}`",
+			"language":     "English",
+			"languages":    "English, Español",
+			"level":        "moderators",
+			"max":          180,
+			"memory":       12,
+			"min":          1,
+			"name":         "minutes",
+			"placeholders": "`{sprinters}`",
+			"plugin":       "dice",
+			"plugins":      "`dice`, `quote`",
+			"prefix":       "!",
+			"preview":      "Fine, pizza.",
+			"problem":      "the minutes should be a number from 1 to 180",
+			"shards":       1,
+			"uptime":       "2h0m0s",
+			"usage":        "roll 3d6",
+			"version":      "1.0.0",
+			"who":          "@Helpers",
+			"word":         `"at"`,
+			"words":        `"now"`,
+		},
+	})
+}
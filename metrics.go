@@ -0,0 +1,56 @@
+package mmmorty
+
+import (
+	"time"
+)
+
+// Metrics is told what the bot is doing as it happens, so it can be measured and graphed.
+// Bots measure nothing unless their Metrics is replaced, such as with the prommetrics package.
+type Metrics interface {
+	// MessageReceived is called for each message a service receives.
+	MessageReceived(service string, messageType MessageType)
+	// Handled is called after a plugin handles a message, with how long it took. Command is the command the
+	// plugin ran, or empty if the plugin was passed a message that wasn't a command.
+	Handled(service, plugin, command string, d time.Duration)
+	// PanicRecovered is called when a plugin panics while handling a message.
+	PanicRecovered(service string)
+	// SendFailed is called when a message couldn't be sent.
+	SendFailed(service string)
+	// Saved is called after a plugin's data is saved, with how long it took and how much was saved.
+	Saved(service, plugin string, d time.Duration, size int)
+}
+
+// Gauger is implemented by plugins with measurements that go up and down, such as how many sprints are running.
+type Gauger interface {
+	// Gauges returns the plugin's measurements by name, eg. "active_sprints".
+	Gauges() map[string]float64
+}
+
+type noMetrics struct{}
+
+func (noMetrics) MessageReceived(service string, messageType MessageType)  {}
+func (noMetrics) Handled(service, plugin, command string, d time.Duration) {}
+func (noMetrics) PanicRecovered(service string)                            {}
+func (noMetrics) SendFailed(service string)                                {}
+func (noMetrics) Saved(service, plugin string, d time.Duration, size int)  {}
+
+// Gauges returns the measurements of every plugin that has them, keyed by service name then by the measurement's
+// name. Plugins on the same service with a measurement of the same name are added together.
+func (b *Bot) Gauges() map[string]map[string]float64 {
+	gauges := map[string]map[string]float64{}
+	for name, service := range b.Services {
+		gauges[name] = map[string]float64{}
+		for _, plugin := range service.Plugins {
+			g, ok := plugin.(Gauger)
+			if !ok {
+				continue
+			}
+			b.LockPlugin(plugin)
+			for gauge, value := range g.Gauges() {
+				gauges[name][gauge] += value
+			}
+			b.UnlockPlugin(plugin)
+		}
+	}
+	return gauges
+}
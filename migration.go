@@ -0,0 +1,144 @@
+package mmmorty
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// MigrationFunc is the function signature for a migration, it converts plugin data from one version to the next.
+type MigrationFunc func(data []byte) ([]byte, error)
+
+var (
+	migrationsMu sync.Mutex
+	migrations   = map[string]map[int]MigrationFunc{}
+)
+
+// RegisterMigration registers a migration that converts a plugin's data from version from to version from+1.
+// A plugin's current version is one more than the newest migration registered for it, or 0 if it has none.
+// Plugins should register their migrations in init so they run before the bot opens.
+func RegisterMigration(plugin string, from int, migration MigrationFunc) {
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+
+	if migrations[plugin] == nil {
+		migrations[plugin] = map[int]MigrationFunc{}
+	}
+	if migrations[plugin][from] != nil {
+		panic(fmt.Sprintf("mmmorty: migration from version %d already registered for %s", from, plugin))
+	}
+	migrations[plugin][from] = migration
+}
+
+// DataVersion returns the current version of a plugin's data.
+func DataVersion(plugin string) int {
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+
+	version := 0
+	for from := range migrations[plugin] {
+		if from+1 > version {
+			version = from + 1
+		}
+	}
+	return version
+}
+
+// envelope wraps saved plugin data with the version of its format. Its version is named so plugin data that has
+// "version" and "data" of its own isn't mistaken for an envelope.
+// Data saved before versioning has no envelope and is version 0.
+type envelope struct {
+	Version *int            `json:"mmmorty_version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// wrapData wraps plugin data in an envelope with its current version. Data that isn't JSON is saved as is.
+func wrapData(plugin string, data []byte) ([]byte, error) {
+	if !json.Valid(data) {
+		return data, nil
+	}
+	version := DataVersion(plugin)
+	return json.Marshal(envelope{
+		Version: &version,
+		Data:    data,
+	})
+}
+
+func unwrapData(data []byte) (int, []byte) {
+	e := envelope{}
+	if err := json.Unmarshal(data, &e); err != nil || e.Version == nil || e.Data == nil {
+		return 0, data
+	}
+	return *e.Version, e.Data
+}
+
+// migrateData unwraps saved plugin data and runs every migration needed to bring it to the current version.
+func migrateData(plugin string, data []byte) (from, to int, migrated []byte, err error) {
+	from, migrated = unwrapData(data)
+	to = DataVersion(plugin)
+	if from > to {
+		return from, to, nil, fmt.Errorf("data is version %d but %s only understands up to version %d", from, plugin, to)
+	}
+
+	for version := from; version < to; version++ {
+		migrationsMu.Lock()
+		migration := migrations[plugin][version]
+		migrationsMu.Unlock()
+
+		if migration == nil {
+			return from, to, nil, fmt.Errorf("no migration registered for %s from version %d", plugin, version)
+		}
+		if migrated, err = migration(migrated); err != nil {
+			return from, to, nil, fmt.Errorf("migrating %s from version %d: %v", plugin, version, err)
+		}
+	}
+	return from, to, migrated, nil
+}
+
+// MigrationReport describes the migration of one plugin's saved data.
+type MigrationReport struct {
+	Service string
+	Plugin  string
+	From    int
+	To      int
+	Before  []byte
+	After   []byte
+	Err     error
+}
+
+// String describes what the migration would do.
+func (r MigrationReport) String() string {
+	switch {
+	case r.Err != nil:
+		return fmt.Sprintf("%s %s: migration would fail: %v", r.Service, r.Plugin, r.Err)
+	case r.From == r.To:
+		return fmt.Sprintf("%s %s: up to date at version %d", r.Service, r.Plugin, r.To)
+	}
+	return fmt.Sprintf("%s %s: would migrate from version %d to %d (%d bytes to %d bytes)\n%s", r.Service, r.Plugin, r.From, r.To, len(r.Before), len(r.After), r.After)
+}
+
+// MigrationDryRun reports the migrations Open would run on every plugin's saved data, without loading or saving anything.
+func (b *Bot) MigrationDryRun() []MigrationReport {
+	reports := []MigrationReport{}
+	for _, service := range b.Services {
+		for _, plugin := range service.Plugins {
+			data, err := b.Store.Load(service.Name(), plugin.Name())
+			if err != nil || data == nil {
+				continue
+			}
+
+			from, to, migrated, err := migrateData(plugin.Name(), data)
+			_, before := unwrapData(data)
+			reports = append(reports, MigrationReport{
+				Service: service.Name(),
+				Plugin:  plugin.Name(),
+				From:    from,
+				To:      to,
+				Before:  before,
+				After:   migrated,
+				Err:     err,
+			})
+		}
+	}
+	return reports
+}
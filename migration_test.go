@@ -0,0 +1,124 @@
+package mmmorty
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func init() {
+	// Chain's data gained a count in version 1 and named it total in version 2.
+	RegisterMigration("Chain", 0, func(data []byte) ([]byte, error) {
+		i := strings.LastIndex(string(data), "}")
+		return []byte(string(data[:i]) + `,"count":1}`), nil
+	})
+	RegisterMigration("Chain", 1, func(data []byte) ([]byte, error) {
+		return []byte(strings.Replace(string(data), `"count"`, `"total"`, 1)), nil
+	})
+
+	// Broken can't be migrated past version 1.
+	RegisterMigration("Broken", 0, func(data []byte) ([]byte, error) {
+		return data, nil
+	})
+	RegisterMigration("Broken", 1, func(data []byte) ([]byte, error) {
+		return nil, errors.New("can't")
+	})
+}
+
+func TestMigrateData(t *testing.T) {
+	for _, test := range []struct {
+		name, plugin, data string
+		from, to           int
+		migrated           string
+		err                string
+	}{
+		{
+			name: "legacy", plugin: "Chain", data: `{"a":1}`,
+			from: 0, to: 2, migrated: `{"a":1,"total":1}`,
+		},
+		{
+			name: "legacy with version and data", plugin: "Chain", data: `{"version":1,"data":{}}`,
+			from: 0, to: 2, migrated: `{"version":1,"data":{},"total":1}`,
+		},
+		{
+			name: "part way", plugin: "Chain", data: `{"mmmorty_version":1,"data":{"a":1,"count":2}}`,
+			from: 1, to: 2, migrated: `{"a":1,"total":2}`,
+		},
+		{
+			name: "current", plugin: "Chain", data: `{"mmmorty_version":2,"data":{"total":2}}`,
+			from: 2, to: 2, migrated: `{"total":2}`,
+		},
+		{
+			name: "newer", plugin: "Chain", data: `{"mmmorty_version":3,"data":{}}`,
+			from: 3, to: 2, err: "only understands up to version 2",
+		},
+		{
+			name: "fails part way", plugin: "Broken", data: `{}`,
+			from: 0, to: 2, err: "migrating Broken from version 1: can't",
+		},
+		{
+			name: "no migrations", plugin: "Plain", data: `{"mmmorty_version":0,"data":[1,2]}`,
+			from: 0, to: 0, migrated: `[1,2]`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			from, to, migrated, err := migrateData(test.plugin, []byte(test.data))
+			if from != test.from || to != test.to {
+				t.Errorf("migrated from %d to %d, expected %d to %d", from, to, test.from, test.to)
+			}
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("got error %v, expected %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(migrated) != test.migrated {
+				t.Errorf("migrated to %s, expected %s", migrated, test.migrated)
+			}
+		})
+	}
+}
+
+func TestWrapDataRoundTrips(t *testing.T) {
+	wrapped, err := wrapData("Chain", []byte(`{"version":7,"data":"mine"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"mmmorty_version":2,"data":{"version":7,"data":"mine"}}`; string(wrapped) != expected {
+		t.Errorf("wrapped as %s, expected %s", wrapped, expected)
+	}
+	if version, data := unwrapData(wrapped); version != 2 || string(data) != `{"version":7,"data":"mine"}` {
+		t.Errorf("unwrapped version %d and %s", version, data)
+	}
+}
+
+// chainPlugin is a plugin whose data has migrations.
+type chainPlugin struct {
+	notesPlugin
+}
+
+func (p *chainPlugin) Name() string { return "Chain" }
+
+func TestMigrationDryRunChangesNothing(t *testing.T) {
+	bot, store := newStoreBot(t)
+	bot.RegisterService(namedService{})
+	bot.RegisterPlugin(namedService{}, &chainPlugin{})
+	if err := store.Save("Test", "Chain", []byte(`{"Count":3}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	reports := bot.MigrationDryRun()
+	if len(reports) != 1 {
+		t.Fatalf("got %d reports, expected 1: %v", len(reports), reports)
+	}
+	r := reports[0]
+	if r.Plugin != "Chain" || r.From != 0 || r.To != 2 || string(r.After) != `{"Count":3,"total":1}` || r.Err != nil {
+		t.Errorf("got report %+v", r)
+	}
+	if data, _ := store.Load("Test", "Chain"); string(data) != `{"Count":3}` {
+		t.Errorf("the dry run saved %s", data)
+	}
+}
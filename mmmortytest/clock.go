@@ -0,0 +1,96 @@
+package mmmortytest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/todd-beckman/mmmorty"
+)
+
+// Clock is a clock that only moves when it's told to, for a bot's Now and AfterFunc so cooldowns and timers can be
+// tested.
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*clockTimer // in the order they were started
+
+	// pace is how far transcripts move the clock before each message.
+	pace time.Duration
+}
+
+// clockTimer is a timer on a Clock.
+type clockTimer struct {
+	clock *Clock
+	at    time.Time
+	f     func()
+}
+
+// NewClock returns a clock stopped at an arbitrary time.
+func NewClock() *Clock {
+	return &Clock{
+		now:  time.Date(2020, time.November, 1, 12, 0, 0, 0, time.UTC),
+		pace: time.Minute,
+	}
+}
+
+// Now returns the clock's time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward, firing the timers that are due in the order they're due. Timers are fired from
+// the goroutine that advances the clock, with the clock at the time they were due, so they've all finished when
+// Advance returns.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	end := c.now.Add(d)
+	for {
+		next := -1
+		for i, t := range c.timers {
+			if !t.at.After(end) && (next == -1 || t.at.Before(c.timers[next].at)) {
+				next = i
+			}
+		}
+		if next == -1 {
+			break
+		}
+		t := c.timers[next]
+		c.timers = append(c.timers[:next:next], c.timers[next+1:]...)
+		if t.at.After(c.now) {
+			c.now = t.at
+		}
+		c.mu.Unlock()
+		t.f()
+		c.mu.Lock()
+	}
+	c.now = end
+}
+
+// AfterFunc calls f once the clock has been advanced by d.
+func (c *Clock) AfterFunc(d time.Duration, f func()) mmmorty.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &clockTimer{clock: c, at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Stop stops the timer, returning false if it has already fired or been stopped.
+func (t *clockTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, other := range c.timers {
+		if other == t {
+			c.timers = append(c.timers[:i:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package mmmortytest
+
+import (
+	"sync/atomic"
+
+	"github.com/todd-beckman/mmmorty"
+)
+
+// Message is an in-memory mmmorty.Message used to drive plugins in tests.
+type Message struct {
+	ID          string
+	ChannelID   string
+	AuthorID    string
+	AuthorName  string
+	Content     string
+	MessageType mmmorty.MessageType
+	// Interaction is set for slash commands, which can be answered with ephemeral replies.
+	Interaction bool
+}
+
+// Channel returns the channel id for this message.
+func (m *Message) Channel() string {
+	return m.ChannelID
+}
+
+// UserName returns the user name for this message.
+func (m *Message) UserName() string {
+	return m.AuthorName
+}
+
+// UserID returns the user id for this message.
+func (m *Message) UserID() string {
+	return m.AuthorID
+}
+
+// UserAvatar returns the avatar url for this message.
+func (m *Message) UserAvatar() string {
+	return ""
+}
+
+// Message returns the message content for this message.
+func (m *Message) Message() string {
+	return m.Content
+}
+
+// RawMessage returns the raw message content for this message.
+func (m *Message) RawMessage() string {
+	return m.Content
+}
+
+// MessageID returns the message ID for this message.
+func (m *Message) MessageID() string {
+	return m.ID
+}
+
+// Type returns the type of message.
+func (m *Message) Type() mmmorty.MessageType {
+	return m.MessageType
+}
+
+// ReplyService returns the service plugins answer the message with.
+func (m *Message) ReplyService(service mmmorty.Service) mmmorty.Service {
+	s, ok := service.(*Service)
+	if !m.Interaction || !ok {
+		return service
+	}
+	return &interactionService{
+		Service: s,
+		channel: m.ChannelID,
+	}
+}
+
+// interactionService is the fake service as seen while answering a slash command.
+type interactionService struct {
+	*Service
+	channel string
+	// answered is set once anything is sent to the slash command's channel.
+	answered atomic.Bool
+}
+
+// SendMessage records a message, answering the slash command if it's to its channel.
+func (s *interactionService) SendMessage(channel, message string) error {
+	if channel == s.channel {
+		s.answered.Store(true)
+	}
+	return s.Service.SendMessage(channel, message)
+}
+
+// SendAction records an action, answering the slash command if it's to its channel.
+func (s *interactionService) SendAction(channel, message string) error {
+	if channel == s.channel {
+		s.answered.Store(true)
+	}
+	return s.Service.SendAction(channel, message)
+}
+
+// SendEphemeralMessage records a reply only the person who used the slash command can see.
+func (s *interactionService) SendEphemeralMessage(channel, message string) error {
+	if channel != s.channel {
+		return s.SendMessage(channel, message)
+	}
+	s.answered.Store(true)
+	s.record(SentMessage{
+		Channel:   channel,
+		Content:   message,
+		Ephemeral: true,
+	})
+	return nil
+}
+
+// FinishAnswer records the reply as an ephemeral reply if nothing answered the slash command.
+func (s *interactionService) FinishAnswer(reply string) error {
+	if s.answered.Load() {
+		return nil
+	}
+	return s.SendEphemeralMessage(s.channel, reply)
+}
+
+// SentMessage is a message the bot sent through the fake service.
+type SentMessage struct {
+	ID      string
+	Channel string
+	Content string
+	Action  bool
+	// Ephemeral is set for replies only the person who used a slash command can see.
+	Ephemeral bool
+	// Edited is set when the bot changed the message with this ID, rather than sending a new one.
+	Edited bool
+}
+
+// PrivateMessage is a direct message the bot sent to a user.
+type PrivateMessage struct {
+	UserID  string
+	Content string
+}
+
+// RoleChange is a role added to or removed from a guild member.
+type RoleChange struct {
+	Guild string
+	User  string
+	Role  string
+	Added bool
+}
@@ -0,0 +1,645 @@
+package mmmortytest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/todd-beckman/mmmorty"
+)
+
+const (
+	// BotUserID is the user id of the fake bot user.
+	BotUserID = "morty"
+	// BotUserName is the user name of the fake bot user.
+	BotUserName = "morty"
+)
+
+// Used to determine if a member can moderate, mirroring Discord.IsModerator.
+const moderatorPermissions = discordgo.PermissionAdministrator |
+	discordgo.PermissionManageChannels |
+	discordgo.PermissionManageServer
+
+// ErrNotFound is returned when a fake guild, channel or member does not exist.
+var ErrNotFound = errors.New("not found")
+
+var (
+	_ mmmorty.Service        = (*Service)(nil)
+	_ mmmorty.SlashCommander = (*Service)(nil)
+)
+
+// Service is an in-memory mmmorty.Service.
+// It holds fake guilds, channels, roles and members and records everything the bot sends.
+type Service struct {
+	// ServiceName is the name of the service, which defaults to the Discord service name so that Discord-only plugins run.
+	ServiceName string
+	// OwnerUserID is the user id of the bot's owner.
+	OwnerUserID string
+
+	mu          sync.Mutex
+	messageChan chan mmmorty.Message
+	changed     chan struct{}
+	nextID      int
+
+	guilds        map[string]*discordgo.Guild
+	channels      map[string]*discordgo.Channel
+	slashCommands []mmmorty.SlashCommand
+
+	sent        []SentMessage
+	private     []PrivateMessage
+	deleted     []string
+	roleChanges []RoleChange
+	leftGuilds  []string
+}
+
+// New creates a new fake service.
+func New() *Service {
+	return &Service{
+		ServiceName: mmmorty.DiscordServiceName,
+		messageChan: make(chan mmmorty.Message, 200),
+		changed:     make(chan struct{}),
+		guilds:      map[string]*discordgo.Guild{},
+		channels:    map[string]*discordgo.Channel{},
+	}
+}
+
+// NewBot creates a bot with the service and plugins registered and opens it.
+// Plugin data is kept in an in-memory store.
+func NewBot(service *Service, plugins ...mmmorty.Plugin) *mmmorty.Bot {
+	bot := mmmorty.NewBot()
+	bot.Store = NewStore()
+	bot.RegisterService(service)
+	for _, plugin := range plugins {
+		bot.RegisterPlugin(service, plugin)
+	}
+	bot.Open()
+	return bot
+}
+
+// AddGuild adds a guild owned by ownerID.
+func (s *Service) AddGuild(guildID, name, ownerID string) *discordgo.Guild {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g := &discordgo.Guild{
+		ID:      guildID,
+		Name:    name,
+		OwnerID: ownerID,
+		Roles:   []*discordgo.Role{},
+		Members: []*discordgo.Member{},
+	}
+	s.guilds[guildID] = g
+	return g
+}
+
+// AddChannel adds a text channel to a guild.
+func (s *Service) AddChannel(guildID, channelID, name string) *discordgo.Channel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := &discordgo.Channel{
+		ID:      channelID,
+		GuildID: guildID,
+		Name:    name,
+		Type:    discordgo.ChannelTypeGuildText,
+	}
+	s.channels[channelID] = c
+	return c
+}
+
+// AddPrivateChannel adds a direct message channel.
+func (s *Service) AddPrivateChannel(channelID string) *discordgo.Channel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := &discordgo.Channel{
+		ID:   channelID,
+		Type: discordgo.ChannelTypeDM,
+	}
+	s.channels[channelID] = c
+	return c
+}
+
+// AddRole adds a role to a guild.
+func (s *Service) AddRole(guildID, roleID, name string, permissions int64) *discordgo.Role {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := &discordgo.Role{
+		ID:          roleID,
+		Name:        name,
+		Permissions: permissions,
+	}
+	if g, ok := s.guilds[guildID]; ok {
+		g.Roles = append(g.Roles, r)
+	}
+	return r
+}
+
+// AddMember adds a member to a guild with the given role ids.
+func (s *Service) AddMember(guildID, userID, userName string, roles ...string) *discordgo.Member {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := &discordgo.Member{
+		GuildID: guildID,
+		User: &discordgo.User{
+			ID:       userID,
+			Username: userName,
+		},
+		Roles: append([]string{}, roles...),
+	}
+	if g, ok := s.guilds[guildID]; ok {
+		g.Members = append(g.Members, m)
+	}
+	return m
+}
+
+// Create injects a new message into the bot.
+func (s *Service) Create(channel, userID, userName, content string) *Message {
+	message := &Message{
+		ID:          s.newID(),
+		ChannelID:   channel,
+		AuthorID:    userID,
+		AuthorName:  userName,
+		Content:     content,
+		MessageType: mmmorty.MessageTypeCreate,
+	}
+	s.messageChan <- message
+	return message
+}
+
+// Update injects an edit of a previously created message into the bot.
+func (s *Service) Update(message *Message, content string) *Message {
+	updated := *message
+	updated.Content = content
+	updated.MessageType = mmmorty.MessageTypeUpdate
+	s.messageChan <- &updated
+	return &updated
+}
+
+// Delete injects the deletion of a previously created message into the bot.
+func (s *Service) Delete(message *Message) *Message {
+	deleted := *message
+	deleted.MessageType = mmmorty.MessageTypeDelete
+	s.messageChan <- &deleted
+	return &deleted
+}
+
+// Interaction returns a message for a slash command the bot registered being used, without injecting it.
+func (s *Service) Interaction(channel, userID, userName, name string, options ...mmmorty.InteractionOption) (*Message, error) {
+	s.mu.Lock()
+	c, ok := mmmorty.FindSlashCommand(s.slashCommands, name)
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no slash command /%s", name)
+	}
+
+	i := &mmmorty.Interaction{
+		ID:        s.newID(),
+		ChannelID: channel,
+		User: &mmmorty.InteractionUser{
+			ID:       userID,
+			Username: userName,
+		},
+	}
+	i.Data.Name = name
+	i.Data.Options = options
+
+	return &Message{
+		ID:          i.ID,
+		ChannelID:   channel,
+		AuthorID:    userID,
+		AuthorName:  userName,
+		Content:     s.CommandPrefix() + c.Content(i),
+		MessageType: mmmorty.MessageTypeCreate,
+		Interaction: true,
+	}, nil
+}
+
+// Interact injects a slash command being used into the bot.
+func (s *Service) Interact(channel, userID, userName, name string, options ...mmmorty.InteractionOption) (*Message, error) {
+	message, err := s.Interaction(channel, userID, userName, name, options...)
+	if err != nil {
+		return nil, err
+	}
+	s.messageChan <- message
+	return message, nil
+}
+
+// SlashCommands returns the slash commands the bot registered.
+func (s *Service) SlashCommands() []mmmorty.SlashCommand {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]mmmorty.SlashCommand{}, s.slashCommands...)
+}
+
+// RegisterSlashCommands records the bot's slash commands.
+func (s *Service) RegisterSlashCommands(commands []mmmorty.SlashCommand) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.slashCommands = append([]mmmorty.SlashCommand{}, commands...)
+	return nil
+}
+
+// Messages returns every message the bot has sent so far, and every change it made to one, in order.
+func (s *Service) Messages() []SentMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SentMessage{}, s.sent...)
+}
+
+// PrivateMessages returns every direct message the bot has sent so far.
+func (s *Service) PrivateMessages() []PrivateMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]PrivateMessage{}, s.private...)
+}
+
+// DeletedMessages returns the ids of every message the bot has deleted.
+func (s *Service) DeletedMessages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.deleted...)
+}
+
+// RoleChanges returns every role the bot has added or removed.
+func (s *Service) RoleChanges() []RoleChange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]RoleChange{}, s.roleChanges...)
+}
+
+// LeftGuilds returns the ids of every guild the bot has left.
+func (s *Service) LeftGuilds() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.leftGuilds...)
+}
+
+// Reset forgets everything the bot has sent so far.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = nil
+	s.private = nil
+	s.deleted = nil
+	s.roleChanges = nil
+	s.leftGuilds = nil
+}
+
+// WaitForMessages waits until the bot has sent at least n messages or the timeout elapses.
+// It returns every message sent so far either way.
+func (s *Service) WaitForMessages(n int, timeout time.Duration) []SentMessage {
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		if len(s.sent) >= n {
+			sent := append([]SentMessage{}, s.sent...)
+			s.mu.Unlock()
+			return sent
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-deadline:
+			return s.Messages()
+		}
+	}
+}
+
+func (s *Service) newID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	return fmt.Sprintf("%d", s.nextID)
+}
+
+// notify wakes up any waiters, s.mu must be held.
+func (s *Service) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *Service) member(guildID, userID string) *discordgo.Member {
+	g, ok := s.guilds[guildID]
+	if !ok {
+		return nil
+	}
+	for _, m := range g.Members {
+		if m.User.ID == userID {
+			return m
+		}
+	}
+	return nil
+}
+
+func (s *Service) role(guildID, roleID string) *discordgo.Role {
+	g, ok := s.guilds[guildID]
+	if !ok {
+		return nil
+	}
+	for _, r := range g.Roles {
+		if r.ID == roleID {
+			return r
+		}
+	}
+	return nil
+}
+
+// Name returns the name of the service.
+func (s *Service) Name() string {
+	return s.ServiceName
+}
+
+// UserName returns the bots name.
+func (s *Service) UserName() string {
+	return BotUserName
+}
+
+// UserID returns the bots user id.
+func (s *Service) UserID() string {
+	return BotUserID
+}
+
+// BotOwnerID returns the user id of the bot's owner.
+func (s *Service) BotOwnerID() string {
+	return s.OwnerUserID
+}
+
+// Open returns the channel that injected messages are sent on.
+func (s *Service) Open() (<-chan mmmorty.Message, error) {
+	return s.messageChan, nil
+}
+
+// IsMe returns whether or not a message was sent by the bot.
+func (s *Service) IsMe(message mmmorty.Message) bool {
+	return message.UserID() == BotUserID
+}
+
+// SendMessage records a message.
+func (s *Service) SendMessage(channel, message string) error {
+	s.record(SentMessage{
+		Channel: channel,
+		Content: message,
+	})
+	return nil
+}
+
+// SendMessageID records a message and returns its ID.
+func (s *Service) SendMessageID(channel, message string) (string, error) {
+	return s.record(SentMessage{
+		Channel: channel,
+		Content: message,
+	}), nil
+}
+
+// EditMessage records a change to a message the bot sent.
+func (s *Service) EditMessage(channel, messageID, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, SentMessage{
+		ID:      messageID,
+		Channel: channel,
+		Content: message,
+		Edited:  true,
+	})
+	s.notify()
+	return nil
+}
+
+// SendAction records an action.
+func (s *Service) SendAction(channel, message string) error {
+	s.record(SentMessage{
+		Channel: channel,
+		Content: message,
+		Action:  true,
+	})
+	return nil
+}
+
+// record records a message the bot sent and returns its ID.
+func (s *Service) record(sent SentMessage) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	sent.ID = fmt.Sprintf("%d", s.nextID)
+	s.sent = append(s.sent, sent)
+	s.notify()
+	return sent.ID
+}
+
+// DeleteMessage records a deleted message.
+func (s *Service) DeleteMessage(channel, messageID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleted = append(s.deleted, messageID)
+	s.notify()
+	return nil
+}
+
+// PrivateMessage records a private message to a user.
+func (s *Service) PrivateMessage(userID, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.private = append(s.private, PrivateMessage{
+		UserID:  userID,
+		Content: message,
+	})
+	s.notify()
+	return nil
+}
+
+// SupportsPrivateMessages returns whether the service supports private messages.
+func (s *Service) SupportsPrivateMessages() bool {
+	return true
+}
+
+// SupportsMultiline returns whether the service supports multiline messages.
+func (s *Service) SupportsMultiline() bool {
+	return true
+}
+
+// CommandPrefix returns the command prefix for the service.
+func (s *Service) CommandPrefix() string {
+	return fmt.Sprintf("@%s ", BotUserName)
+}
+
+// IsBotOwner returns whether or not a message sender was the owner of the bot.
+func (s *Service) IsBotOwner(message mmmorty.Message) bool {
+	return message.UserID() == s.OwnerUserID
+}
+
+// IsPrivate returns whether or not a message was private.
+func (s *Service) IsPrivate(message mmmorty.Message) bool {
+	c, err := s.Channel(message.Channel())
+	return err == nil && c.Type == discordgo.ChannelTypeDM
+}
+
+// IsChannelOwner returns whether or not the sender of a message owns the guild.
+func (s *Service) IsChannelOwner(message mmmorty.Message) bool {
+	c, err := s.Channel(message.Channel())
+	if err != nil {
+		return false
+	}
+	g, err := s.Guild(c.GuildID)
+	if err != nil {
+		return false
+	}
+	return g.OwnerID == message.UserID() || s.IsBotOwner(message)
+}
+
+// IsModerator returns whether or not the sender of a message is a moderator.
+func (s *Service) IsModerator(message mmmorty.Message) bool {
+	c, err := s.Channel(message.Channel())
+	if err != nil {
+		return false
+	}
+
+	s.mu.Lock()
+	var permissions int64
+	if m := s.member(c.GuildID, message.UserID()); m != nil {
+		for _, roleID := range m.Roles {
+			if r := s.role(c.GuildID, roleID); r != nil {
+				permissions |= r.Permissions
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if permissions&moderatorPermissions != 0 {
+		return true
+	}
+	return s.IsChannelOwner(message)
+}
+
+// ChannelCount returns the number of guilds the bot is in.
+func (s *Service) ChannelCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.guilds)
+}
+
+// Channel gets the fake channel for the given ID.
+func (s *Service) Channel(channelID string) (*discordgo.Channel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.channels[channelID]; ok {
+		return c, nil
+	}
+	return nil, ErrNotFound
+}
+
+// Guild gets the fake guild for the given ID.
+func (s *Service) Guild(guildID string) (*discordgo.Guild, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if g, ok := s.guilds[guildID]; ok {
+		return g, nil
+	}
+	return nil, ErrNotFound
+}
+
+// GetRoleByName returns the role for the given role name.
+func (s *Service) GetRoleByName(channel, roleName string) *discordgo.Role {
+	for _, r := range s.GetRoles(channel) {
+		if strings.ToLower(r.Name) == roleName {
+			return r
+		}
+	}
+	return nil
+}
+
+// GetRoles gets the list of roles for the guild of the given channel.
+func (s *Service) GetRoles(channel string) []*discordgo.Role {
+	c, err := s.Channel(channel)
+	if err != nil {
+		return []*discordgo.Role{}
+	}
+	g, err := s.Guild(c.GuildID)
+	if err != nil {
+		return []*discordgo.Role{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*discordgo.Role{}, g.Roles...)
+}
+
+// GuildLeave records leaving a guild.
+func (s *Service) GuildLeave(guildID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.guilds[guildID]; !ok {
+		return ErrNotFound
+	}
+	s.leftGuilds = append(s.leftGuilds, guildID)
+	s.notify()
+	return nil
+}
+
+// GuildMemberRoleAdd gives a guild member a role.
+func (s *Service) GuildMemberRoleAdd(guild, user, role string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := s.member(guild, user)
+	if m == nil || s.role(guild, role) == nil {
+		return false
+	}
+	for _, r := range m.Roles {
+		if r == role {
+			return true
+		}
+	}
+	m.Roles = append(m.Roles, role)
+	s.roleChanges = append(s.roleChanges, RoleChange{
+		Guild: guild,
+		User:  user,
+		Role:  role,
+		Added: true,
+	})
+	s.notify()
+	return true
+}
+
+// GuildMemberRoleRemove takes a guild member's role.
+func (s *Service) GuildMemberRoleRemove(guild, user, role string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := s.member(guild, user)
+	if m == nil {
+		return false
+	}
+	for i, r := range m.Roles {
+		if r == role {
+			m.Roles = append(m.Roles[:i], m.Roles[i+1:]...)
+			s.roleChanges = append(s.roleChanges, RoleChange{
+				Guild: guild,
+				User:  user,
+				Role:  role,
+			})
+			s.notify()
+			return true
+		}
+	}
+	return false
+}
+
+// UserRoles gets the list of roles of the given user.
+func (s *Service) UserRoles(guild, memberID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := s.member(guild, memberID)
+	if m == nil {
+		return []string{}
+	}
+	return append([]string{}, m.Roles...)
+}
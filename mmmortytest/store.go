@@ -0,0 +1,42 @@
+package mmmortytest
+
+import (
+	"sync"
+
+	"github.com/todd-beckman/mmmorty"
+)
+
+// Store is an in-memory mmmorty.Store, so tests never touch the working directory.
+type Store struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+var _ mmmorty.Store = (*Store)(nil)
+
+// NewStore creates a new in-memory store.
+func NewStore() *Store {
+	return &Store{
+		data: map[string][]byte{},
+	}
+}
+
+// Load returns the data saved for a plugin, or nil if nothing has been saved yet.
+func (s *Store) Load(service, plugin string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data[service+"/"+plugin], nil
+}
+
+// Save stores the data for a plugin.
+func (s *Store) Save(service, plugin string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[service+"/"+plugin] = append([]byte{}, data...)
+	return nil
+}
+
+// Close does nothing.
+func (s *Store) Close() error {
+	return nil
+}
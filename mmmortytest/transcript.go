@@ -0,0 +1,413 @@
+package mmmortytest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/todd-beckman/mmmorty"
+)
+
+const (
+	// TranscriptGuildID is the id of the guild transcripts are replayed in.
+	TranscriptGuildID = "guild"
+	// TranscriptChannelID is the id of the channel transcripts start in.
+	TranscriptChannelID = "general"
+	// TranscriptOwnerID is the user id of the bot and guild owner in transcripts.
+	TranscriptOwnerID = "rick"
+)
+
+var (
+	speakerRegex     = regexp.MustCompile(`^([A-Za-z0-9_.-]+)> ?(.*)$`)
+	slashOptionRegex = regexp.MustCompile(`(?:^|\s)([\w-]+):`)
+)
+
+const (
+	// ephemeralMarker starts an expected reply that only the person who used a slash command can see.
+	ephemeralMarker = "(ephemeral) "
+	// editedMarker starts an expected change to a reply Morty already sent.
+	editedMarker = "(edited) "
+	// deletedMarker starts a reply Morty is expected to delete, as it was when it was deleted.
+	deletedMarker = "(deleted) "
+	// editMarker starts a message that edits the user's last message in the channel.
+	editMarker = "(edit) "
+)
+
+// reply is something Morty did in reply to a message: sending, changing or deleting one of its own messages.
+type reply struct {
+	SentMessage
+	deleted bool
+}
+
+type stepKind int
+
+const (
+	stepDirective stepKind = iota
+	stepSay
+	stepExpect
+)
+
+type step struct {
+	kind    stepKind
+	line    int
+	speaker string
+	text    string
+}
+
+// Transcript is a scripted conversation with Morty.
+//
+// Each line is one of:
+//
+//	# a comment
+//	/channel <id>             talk in another channel of the guild
+//	/dm                       talk in private
+//	/role <name> [mod]        create a role, with moderator permissions if "mod"
+//	/give <user> <role>       give a user a role
+//	/pace <duration>          send each following message this long after the one before, eg. /pace 1s,
+//	                          messages are a minute apart otherwise
+//	/limit <name> <value>     change one of the plugins' limits, eg. /limit quotes 2
+//	/wait <duration>          let time pass, eg. /wait 5m, so timers such as sprint alerts go off
+//	<user>> <message>         a message sent by user
+//	<user>> (edit) <message>  user's last message in the channel, edited to say something else
+//	<user>> /<command> <option>:<value> ...
+//	                          a slash command used by user
+//	morty> <reply>            a reply Morty is expected to send
+//	morty> (ephemeral) <reply>
+//	                          a reply only the user of a slash command can see
+//	morty> (edited) <reply>   a reply Morty is expected to change one of its messages to
+//	morty> (deleted) <reply>  a message of Morty's that's expected to be deleted, as it was
+//
+// Replies to a message include anything Morty's timers sent as the clock moved on to it. Replies to /wait are only
+// what the timers sent.
+// Lines that match none of these continue the previous reply, so multiline replies can be written out in full.
+// Inside a reply, {{regex}} matches the regular expression, so {{.*}} matches anything and {{a|b}} matches a random pick.
+type Transcript struct {
+	Name  string
+	steps []step
+}
+
+// ParseTranscript parses a transcript.
+func ParseTranscript(name string, r io.Reader) (*Transcript, error) {
+	t := &Transcript{
+		Name: name,
+	}
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+
+		switch {
+		case text == "" || strings.HasPrefix(text, "#"):
+			continue
+		case strings.HasPrefix(text, "/"):
+			t.steps = append(t.steps, step{kind: stepDirective, line: line, text: text})
+		case speakerRegex.MatchString(text):
+			match := speakerRegex.FindStringSubmatch(text)
+			if match[1] == BotUserName {
+				t.steps = append(t.steps, step{kind: stepExpect, line: line, text: match[2]})
+			} else {
+				t.steps = append(t.steps, step{kind: stepSay, line: line, speaker: match[1], text: match[2]})
+			}
+		default:
+			if len(t.steps) == 0 || t.steps[len(t.steps)-1].kind != stepExpect {
+				return nil, fmt.Errorf("%s:%d: %q is not a message, reply or directive", name, line, text)
+			}
+			t.steps[len(t.steps)-1].text += "\n" + text
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// LoadTranscripts parses every transcript matching the glob pattern.
+func LoadTranscripts(pattern string) ([]*Transcript, error) {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	transcripts := []*Transcript{}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		t, err := ParseTranscript(file, f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		transcripts = append(transcripts, t)
+	}
+	return transcripts, nil
+}
+
+// Run replays the transcript through a new bot with the given plugins registered.
+// It returns an error describing the first reply that did not match.
+func (t *Transcript) Run(plugins ...mmmorty.Plugin) error {
+	service := New()
+	service.ServiceName = "Transcript"
+	service.OwnerUserID = TranscriptOwnerID
+	service.AddGuild(TranscriptGuildID, TranscriptGuildID, TranscriptOwnerID)
+	service.AddChannel(TranscriptGuildID, TranscriptChannelID, TranscriptChannelID)
+
+	clock := NewClock()
+	bot := mmmorty.NewBot()
+	bot.Store = NewStore()
+	bot.Now = clock.Now
+	bot.AfterFunc = clock.AfterFunc
+	bot.RegisterService(service)
+	for _, plugin := range plugins {
+		bot.RegisterPlugin(service, plugin)
+	}
+	service.RegisterSlashCommands(bot.SlashCommands(service))
+
+	channel := TranscriptChannelID
+	pending := []reply{}
+	lastSay := 0
+	// The last message each user sent in each channel, which they can edit.
+	last := map[string]*Message{}
+
+	for _, s := range t.steps {
+		switch s.kind {
+		case stepDirective:
+			if err := t.unexpected(pending, lastSay); err != nil {
+				return err
+			}
+
+			before, deletedBefore := len(service.Messages()), len(service.DeletedMessages())
+			next, err := t.runDirective(bot, service, clock, s, channel)
+			if err != nil {
+				return err
+			}
+			channel = next
+			pending = t.replies(service, before, deletedBefore)
+			lastSay = s.line
+		case stepSay:
+			if err := t.unexpected(pending, lastSay); err != nil {
+				return err
+			}
+
+			t.ensureMember(service, s.speaker)
+			before, deletedBefore := len(service.Messages()), len(service.DeletedMessages())
+			clock.Advance(clock.pace)
+
+			message, err := t.message(service, s, channel, last)
+			if err != nil {
+				return err
+			}
+			bot.HandleMessage(service, message)
+			pending = t.replies(service, before, deletedBefore)
+			lastSay = s.line
+		case stepExpect:
+			if len(pending) == 0 {
+				return fmt.Errorf("%s:%d: expected a reply to line %d but got nothing:\n%s", t.Name, s.line, lastSay, s.text)
+			}
+			got := pending[0]
+			pending = pending[1:]
+
+			expected := s.text
+			for _, m := range []struct {
+				marker string
+				got    bool
+			}{{ephemeralMarker, got.Ephemeral}, {editedMarker, got.Edited}, {deletedMarker, got.deleted}} {
+				marked := strings.HasPrefix(expected, m.marker)
+				if marked != m.got {
+					return fmt.Errorf("%s:%d: expected %s to be %v for reply:\n%s", t.Name, s.line, strings.Trim(m.marker, "() "), marked, got.Content)
+				}
+				expected = strings.TrimPrefix(expected, m.marker)
+			}
+			pattern, err := expectRegex(expected)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %v", t.Name, s.line, err)
+			}
+			if !pattern.MatchString(got.Content) {
+				return fmt.Errorf("%s:%d: reply did not match\nexpected:\n%s\ngot:\n%s", t.Name, s.line, s.text, got.Content)
+			}
+		}
+	}
+
+	return t.unexpected(pending, lastSay)
+}
+
+// message returns the message a user sends, which is a slash command if it starts with a slash, or an edit of their
+// last message in the channel if it starts with "(edit) ".
+func (t *Transcript) message(service *Service, s step, channel string, last map[string]*Message) (*Message, error) {
+	key := s.speaker + "/" + channel
+	if strings.HasPrefix(s.text, editMarker) {
+		if last[key] == nil {
+			return nil, fmt.Errorf("%s:%d: %s hasn't sent a message to edit in %s", t.Name, s.line, s.speaker, channel)
+		}
+		edited := *last[key]
+		edited.Content = strings.TrimPrefix(s.text, editMarker)
+		edited.MessageType = mmmorty.MessageTypeUpdate
+		last[key] = &edited
+		return &edited, nil
+	}
+	if !strings.HasPrefix(s.text, "/") {
+		message := &Message{
+			ID:          service.newID(),
+			ChannelID:   channel,
+			AuthorID:    s.speaker,
+			AuthorName:  s.speaker,
+			Content:     s.text,
+			MessageType: mmmorty.MessageTypeCreate,
+		}
+		last[key] = message
+		return message, nil
+	}
+
+	fields := strings.SplitN(s.text[1:], " ", 2)
+	options := []mmmorty.InteractionOption{}
+	if len(fields) == 2 {
+		// Each option's value runs up to the next option.
+		rest := fields[1]
+		matches := slashOptionRegex.FindAllStringSubmatchIndex(rest, -1)
+		if len(matches) == 0 && strings.TrimSpace(rest) != "" {
+			return nil, fmt.Errorf("%s:%d: %q is not an option:value", t.Name, s.line, rest)
+		}
+		for i, m := range matches {
+			end := len(rest)
+			if i+1 < len(matches) {
+				end = matches[i+1][0]
+			}
+			options = append(options, mmmorty.InteractionOption{
+				Name:  rest[m[2]:m[3]],
+				Value: strings.TrimSpace(rest[m[1]:end]),
+			})
+		}
+	}
+
+	message, err := service.Interaction(channel, s.speaker, s.speaker, fields[0], options...)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%d: %v", t.Name, s.line, err)
+	}
+	return message, nil
+}
+
+func (t *Transcript) ensureMember(service *Service, userID string) {
+	service.mu.Lock()
+	m := service.member(TranscriptGuildID, userID)
+	service.mu.Unlock()
+	if m == nil {
+		service.AddMember(TranscriptGuildID, userID, userID)
+	}
+}
+
+// replies returns what Morty did since it had sent before messages and deleted deletedBefore: the messages it sent
+// or changed, then the ones it deleted.
+func (t *Transcript) replies(service *Service, before, deletedBefore int) []reply {
+	sent := service.Messages()
+	replies := []reply{}
+	for _, m := range sent[before:] {
+		replies = append(replies, reply{SentMessage: m})
+	}
+	for _, id := range service.DeletedMessages()[deletedBefore:] {
+		deleted := reply{SentMessage: SentMessage{ID: id}, deleted: true}
+		for _, m := range sent {
+			if m.ID == id {
+				deleted.Channel, deleted.Content = m.Channel, m.Content
+			}
+		}
+		replies = append(replies, deleted)
+	}
+	return replies
+}
+
+func (t *Transcript) unexpected(pending []reply, line int) error {
+	if len(pending) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s:%d: unexpected reply:\n%s", t.Name, line, pending[0].Content)
+}
+
+func (t *Transcript) runDirective(bot *mmmorty.Bot, service *Service, clock *Clock, s step, channel string) (string, error) {
+	parts := strings.Fields(s.text)
+	directive, args := parts[0], parts[1:]
+
+	switch {
+	case directive == "/channel" && len(args) == 1:
+		if _, err := service.Channel(args[0]); err != nil {
+			service.AddChannel(TranscriptGuildID, args[0], args[0])
+		}
+		return args[0], nil
+	case directive == "/dm" && len(args) == 0:
+		service.AddPrivateChannel("dm")
+		return "dm", nil
+	case directive == "/role" && (len(args) == 1 || len(args) == 2):
+		var permissions int64
+		if len(args) == 2 && args[1] == "mod" {
+			permissions = moderatorPermissions
+		}
+		service.AddRole(TranscriptGuildID, strings.ToLower(args[0]), args[0], permissions)
+		return channel, nil
+	case directive == "/give" && len(args) == 2:
+		t.ensureMember(service, args[0])
+		if !service.GuildMemberRoleAdd(TranscriptGuildID, args[0], strings.ToLower(args[1])) {
+			return "", fmt.Errorf("%s:%d: could not give %s the %s role", t.Name, s.line, args[0], args[1])
+		}
+		return channel, nil
+	case directive == "/pace" && len(args) == 1:
+		d, err := time.ParseDuration(args[0])
+		if err != nil {
+			return "", fmt.Errorf("%s:%d: %v", t.Name, s.line, err)
+		}
+		clock.pace = d
+		return channel, nil
+	case directive == "/wait" && len(args) == 1:
+		d, err := time.ParseDuration(args[0])
+		if err != nil {
+			return "", fmt.Errorf("%s:%d: %v", t.Name, s.line, err)
+		}
+		clock.Advance(d)
+		return channel, nil
+	case directive == "/limit" && len(args) == 2:
+		value, err := strconv.Atoi(args[1])
+		if err != nil {
+			return "", fmt.Errorf("%s:%d: %v", t.Name, s.line, err)
+		}
+		config := bot.Config
+		config.Limits = map[string]int{}
+		for name, limit := range bot.Limits {
+			config.Limits[name] = limit
+		}
+		config.Limits[args[0]] = value
+		if err := bot.Reconfigure(config); err != nil {
+			return "", fmt.Errorf("%s:%d: %v", t.Name, s.line, err)
+		}
+		return channel, nil
+	}
+
+	return "", fmt.Errorf("%s:%d: unknown directive %q", t.Name, s.line, s.text)
+}
+
+// expectRegex compiles an expected reply, where {{regex}} segments are regular expressions and everything else is literal.
+func expectRegex(expected string) (*regexp.Regexp, error) {
+	pattern := "(?s)^"
+	rest := expected
+	for {
+		start := strings.Index(rest, "{{")
+		if start == -1 {
+			pattern += regexp.QuoteMeta(rest)
+			break
+		}
+		end := strings.Index(rest[start:], "}}")
+		if end == -1 {
+			return nil, fmt.Errorf("unterminated {{ in %q", expected)
+		}
+		pattern += regexp.QuoteMeta(rest[:start]) + "(?:" + rest[start+2:start+end] + ")"
+		rest = rest[start+end+2:]
+	}
+	return regexp.Compile(pattern + "$")
+}
@@ -0,0 +1,346 @@
+package mmmorty
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// Permission is who can use a command, unless a server grants it to more of its members.
+type Permission int
+
+const (
+	// Everyone can use the command.
+	Everyone Permission = iota
+	// Moderator commands can be used by members who can manage the server or its channels.
+	Moderator
+	// GuildOwner commands can be used by the server's owner.
+	GuildOwner
+	// BotOwner commands can only be used by the bot's owner.
+	BotOwner
+)
+
+// HasPermission returns whether the sender of a message has a permission without any grants.
+// The server's owner is also a moderator, and the bot's owner has every permission.
+func HasPermission(service Service, message Message, permission Permission) bool {
+	switch permission {
+	case Everyone:
+		return true
+	case Moderator:
+		return service.IsModerator(message)
+	case GuildOwner:
+		return service.IsChannelOwner(message)
+	}
+	return service.IsBotOwner(message)
+}
+
+// permissionName returns the message ID naming who has a permission.
+func permissionName(permission Permission) string {
+	switch permission {
+	case Everyone:
+		return "permission.everyone"
+	case Moderator:
+		return "permission.moderator"
+	case GuildOwner:
+		return "permission.guildowner"
+	}
+	return "permission.botowner"
+}
+
+const (
+	grantCommand       = "grant"
+	revokeCommand      = "revoke"
+	permissionsCommand = "permissions"
+)
+
+var (
+	grantSpec       = MustParseArgSpec("grant <command:text> to <who:text>")
+	revokeSpec      = MustParseArgSpec("revoke <command:text> from <who:text>")
+	permissionsSpec = MustParseArgSpec("permissions <command:text>")
+
+	userMentionRegex = regexp.MustCompile(`^<@!?(\w+)>$`)
+	roleMentionRegex = regexp.MustCompile(`^<@&(\w+)>$`)
+)
+
+// commandGrants are the roles and members a command is granted to on a server.
+type commandGrants struct {
+	Roles []string `json:"roles"`
+	Users []string `json:"users"`
+}
+
+// allows returns whether a command is granted to a member with the given roles.
+func (g *commandGrants) allows(userID string, roles []string) bool {
+	if containsString(g.Users, userID) {
+		return true
+	}
+	for _, role := range roles {
+		if containsString(g.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+type permissionPlugin struct {
+	Grants map[string]map[string]*commandGrants `json:"grants"` // map of guild ID to command to who it's granted to
+}
+
+// Name returns the name of the plugin.
+func (p *permissionPlugin) Name() string {
+	return "Permission"
+}
+
+// Help returns a list of help strings that are printed when the user requests them.
+func (p *permissionPlugin) Help(bot *Bot, service Service, message Message, detailed bool) []string {
+	if detailed || service.IsPrivate(message) || !service.IsModerator(message) {
+		return nil
+	}
+	help := CommandHelp(service, grantCommand, grantSpec.Arguments(), Text(service, nil, "permission.help.grant", nil))
+	help = append(help, CommandHelp(service, revokeCommand, revokeSpec.Arguments(), Text(service, nil, "permission.help.revoke", nil))...)
+	return append(help, CommandHelp(service, permissionsCommand, permissionsSpec.Arguments(), Text(service, nil, "permission.help.list", nil))...)
+}
+
+// Commands returns the commands this plugin handles.
+func (p *permissionPlugin) Commands() []Command {
+	return []Command{
+		{
+			Name:        grantCommand,
+			Handler:     p.handleGrant,
+			Description: "lets a role or member use a command",
+			Spec:        grantSpec,
+			Permission:  Moderator,
+		},
+		{
+			Name:        revokeCommand,
+			Handler:     p.handleRevoke,
+			Description: "takes back a command granted to a role or member",
+			Spec:        revokeSpec,
+			Permission:  Moderator,
+		},
+		{
+			Name:        permissionsCommand,
+			Handler:     p.handlePermissions,
+			Description: "shows who can use a command",
+			Spec:        permissionsSpec,
+			Permission:  Moderator,
+			Rerun:       true,
+		},
+	}
+}
+
+func (p *permissionPlugin) Message(bot *Bot, service Service, message Message) {
+}
+
+// guildGrants returns a copy of the commands granted on a guild.
+func (p *permissionPlugin) guildGrants(guildID string) map[string]*commandGrants {
+	grants := map[string]*commandGrants{}
+	for command, g := range p.Grants[guildID] {
+		grants[command] = &commandGrants{
+			Roles: append([]string{}, g.Roles...),
+			Users: append([]string{}, g.Users...),
+		}
+	}
+	return grants
+}
+
+// findCommand returns the registered command a moderator named, replying if there isn't one.
+func findCommand(bot *Bot, service Service, message Message, name string) (routedCommand, bool) {
+	c, ok := bot.Services[service.Name()].router.commands[normalizeCommand(name)]
+	if !ok {
+		SendEphemeral(service, message.Channel(), Text(service, message, "error.command", Vars{"command": name}))
+	}
+	return c, ok
+}
+
+// grant is a command being granted to, or taken back from, a role or member of a guild.
+type grant struct {
+	guildID string
+	command routedCommand
+	id      string
+	role    bool
+	name    string // how to show who it's granted to without mentioning a role
+}
+
+// grantee finds the role or member a command is granted to from a mention or a role's name.
+func grantee(service Service, message Message, who string, g *grant) bool {
+	if m := userMentionRegex.FindStringSubmatch(who); m != nil {
+		g.id, g.name = m[1], "<@"+m[1]+">"
+		return true
+	}
+	roleName := strings.TrimPrefix(who, "@")
+	if strings.EqualFold(roleName, "everyone") {
+		// Every member has the server's @everyone role, and its ID is the server's.
+		g.id, g.role, g.name = g.guildID, true, "@everyone"
+		return true
+	}
+	if m := roleMentionRegex.FindStringSubmatch(who); m != nil {
+		for _, role := range service.GetRoles(message.Channel()) {
+			if role.ID == m[1] {
+				roleName = role.Name
+			}
+		}
+	}
+	role := service.GetRoleByName(message.Channel(), strings.ToLower(roleName))
+	if role == nil {
+		return false
+	}
+	g.id, g.role, g.name = role.ID, true, "@"+role.Name
+	return true
+}
+
+// parseGrant parses a grant or revoke, replying with the problem if it can't be done.
+// Moderators can only grant commands they could use without being granted them.
+func parseGrant(bot *Bot, service Service, message Message, spec *ArgSpec) (grant, bool) {
+	g := grant{}
+	guildID, ok := settingGuildID(service, message, "permission.private")
+	if !ok {
+		return g, false
+	}
+	g.guildID = guildID
+
+	args, err := spec.Parse(service, message)
+	if err != nil {
+		SendEphemeral(service, message.Channel(), spec.UsageReply(service, message, err))
+		return g, false
+	}
+
+	g.command, ok = findCommand(bot, service, message, args.String("command"))
+	if !ok {
+		return g, false
+	}
+	if !HasPermission(service, message, g.command.permission) {
+		vars := Vars{"command": g.command.name, "level": Text(service, nil, permissionName(g.command.permission), nil)}
+		SendEphemeral(service, message.Channel(), Text(service, message, "permission.above", vars))
+		return g, false
+	}
+
+	if !grantee(service, message, args.String("who"), &g) {
+		SendEphemeral(service, message.Channel(), Text(service, message, "permission.who", Vars{"who": args.String("who")}))
+		return g, false
+	}
+	return g, true
+}
+
+func (p *permissionPlugin) handleGrant(bot *Bot, service Service, message Message) {
+	g, ok := parseGrant(bot, service, message, grantSpec)
+	if !ok {
+		return
+	}
+
+	if p.Grants == nil {
+		p.Grants = map[string]map[string]*commandGrants{}
+	}
+	if p.Grants[g.guildID] == nil {
+		p.Grants[g.guildID] = map[string]*commandGrants{}
+	}
+	grants := p.Grants[g.guildID][g.command.name]
+	if grants == nil {
+		grants = &commandGrants{}
+		p.Grants[g.guildID][g.command.name] = grants
+	}
+	if g.role && !containsString(grants.Roles, g.id) {
+		grants.Roles = append(grants.Roles, g.id)
+	} else if !g.role && !containsString(grants.Users, g.id) {
+		grants.Users = append(grants.Users, g.id)
+	}
+
+	reply := Text(service, message, "permission.granted", Vars{"command": g.command.name, "who": g.name})
+	service.SendMessage(message.Channel(), reply)
+}
+
+func removeString(list []string, s string) []string {
+	kept := []string{}
+	for _, item := range list {
+		if item != s {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+func (p *permissionPlugin) handleRevoke(bot *Bot, service Service, message Message) {
+	g, ok := parseGrant(bot, service, message, revokeSpec)
+	if !ok {
+		return
+	}
+
+	grants := p.Grants[g.guildID][g.command.name]
+	if grants == nil || (g.role && !containsString(grants.Roles, g.id)) || (!g.role && !containsString(grants.Users, g.id)) {
+		reply := Text(service, message, "permission.missing", Vars{"command": g.command.name, "who": g.name})
+		SendEphemeral(service, message.Channel(), reply)
+		return
+	}
+	if g.role {
+		grants.Roles = removeString(grants.Roles, g.id)
+	} else {
+		grants.Users = removeString(grants.Users, g.id)
+	}
+	if len(grants.Roles) == 0 && len(grants.Users) == 0 {
+		delete(p.Grants[g.guildID], g.command.name)
+	}
+	if len(p.Grants[g.guildID]) == 0 {
+		delete(p.Grants, g.guildID)
+	}
+
+	reply := Text(service, message, "permission.revoked", Vars{"command": g.command.name, "who": g.name})
+	service.SendMessage(message.Channel(), reply)
+}
+
+func (p *permissionPlugin) handlePermissions(bot *Bot, service Service, message Message) {
+	guildID, ok := settingGuildID(service, message, "permission.private")
+	if !ok {
+		return
+	}
+
+	args, err := permissionsSpec.Parse(service, message)
+	if err != nil {
+		SendEphemeral(service, message.Channel(), permissionsSpec.UsageReply(service, message, err))
+		return
+	}
+
+	c, ok := findCommand(bot, service, message, args.String("command"))
+	if !ok {
+		return
+	}
+
+	vars := Vars{"command": c.name, "level": Text(service, nil, permissionName(c.permission), nil)}
+	lines := []string{Text(service, message, "permission.level", vars)}
+	if g := p.Grants[guildID][c.name]; g != nil {
+		names := []string{}
+		if containsString(g.Roles, guildID) {
+			names = append(names, "@everyone")
+		}
+		for _, role := range service.GetRoles(message.Channel()) {
+			if role.ID != guildID && containsString(g.Roles, role.ID) {
+				names = append(names, "@"+role.Name)
+			}
+		}
+		for _, user := range g.Users {
+			names = append(names, "<@"+user+">")
+		}
+		lines = append(lines, Text(service, message, "permission.grants", Vars{"who": strings.Join(names, ", ")}))
+	}
+	SendEphemeral(service, message.Channel(), strings.Join(lines, "\n"))
+}
+
+// Load will load plugin state from a byte array.
+func (p *permissionPlugin) Load(bot *Bot, service Service, data []byte) error {
+	if data != nil {
+		if err := json.Unmarshal(data, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save will save plugin state to a byte array.
+func (p *permissionPlugin) Save() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// NewPermissionPlugin will create a new plugin that grants commands to a server's roles and members.
+func NewPermissionPlugin() Plugin {
+	return &permissionPlugin{
+		Grants: map[string]map[string]*commandGrants{},
+	}
+}
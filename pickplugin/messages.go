@@ -0,0 +1,33 @@
+package pickplugin
+
+import "github.com/todd-beckman/mmmorty"
+
+func init() {
+	mmmorty.RegisterMessages("en", map[string]string{
+		"pick.help":           "asks Morty to pick between an arbitrary number of things for you",
+		"pick.help.arguments": "option 1 or option 2 or ...",
+		"pick.links":          "Uh, {user}, I would rather not pick between links.",
+		"pick.long":           "Uh, {user}, that message is kind of long. Is there any way you can shorten it?",
+		"pick.empty":          "Uh, {user}, I didn't get that last one.",
+		"pick.one":            "Uh, {user}, I didn't get that. Maybe put `or` between options?",
+		"pick.choice":         "Uh, I'll go with this one: {choice}",
+	})
+
+	mmmorty.RegisterMessages("es", map[string]string{
+		"pick.help":           "le pide a Morty que elija entre las cosas que quieras",
+		"pick.help.arguments": "opción 1 or opción 2 or ...",
+		"pick.links":          "Eh, {user}, prefiero no elegir entre enlaces.",
+		"pick.long":           "Eh, {user}, ese mensaje es un poco largo. ¿Hay alguna forma de acortarlo?",
+		"pick.empty":          "Eh, {user}, no entendí la última opción.",
+		"pick.one":            "Eh, {user}, no lo entendí. ¿Quizás poner `or` entre las opciones?",
+		"pick.choice":         "Eh, me quedo con esta: {choice}",
+	})
+
+	mmmorty.RequirePlaceholders(map[string][]string{
+		"pick.choice": {"choice"},
+	})
+
+	mmmorty.RegisterSamples(map[string]mmmorty.Vars{
+		"pick": {"choice": "pizza"},
+	})
+}
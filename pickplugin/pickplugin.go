@@ -2,10 +2,9 @@ package pickplugin
 
 import (
 	"encoding/json"
-	"fmt"
-	"log"
 	"math/rand"
 	"strings"
+	"time"
 
 	"github.com/todd-beckman/mmmorty"
 )
@@ -13,7 +12,6 @@ import (
 const (
 	maxWordCount = 100
 	pickCommand  = "choose"
-	pickTemplate = "Uh, I'll go with this one: %s"
 )
 
 // PickPlugin is a the save structure for this plugin
@@ -22,16 +20,15 @@ type PickPlugin struct {
 }
 
 // Help gets the usage for this plugin
-func (p *PickPlugin) Help(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, detailed bool) []string {
-	return mmmorty.CommandHelp(service, pickCommand, "option 1 or option 2 or ...",
-		"asks Morty to pick between an arbitrary number of things for you")
+func (p *PickPlugin) Help(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, detailed bool) []string {
+	return mmmorty.CommandHelp(service, pickCommand, mmmorty.Text(service, nil, "pick.help.arguments", nil),
+		mmmorty.Text(service, nil, "pick.help", nil))
 }
 
 // Load loads the plugin from the given data
-func (p *PickPlugin) Load(bot *mmmorty.Bot, service mmmorty.Discord, data []byte) error {
+func (p *PickPlugin) Load(bot *mmmorty.Bot, service mmmorty.Service, data []byte) error {
 	if data != nil {
 		if err := json.Unmarshal(data, p); err != nil {
-			log.Println("Error loading data", err)
 			return err
 		}
 	}
@@ -39,24 +36,26 @@ func (p *PickPlugin) Load(bot *mmmorty.Bot, service mmmorty.Discord, data []byte
 	return nil
 }
 
-// Message is the command handler for this plugin
-func (p *PickPlugin) Message(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage) {
-	defer bot.MessageRecover(service, message.Channel())
-
-	if service.IsMe(message) {
-		return
-	}
-
-	if mmmorty.MatchesCommand(service, pickCommand, message) {
-		p.handlePickCommand(bot, service, message)
+// Commands returns the commands this plugin handles
+func (p *PickPlugin) Commands() []mmmorty.Command {
+	return []mmmorty.Command{
+		{
+			Name:        pickCommand,
+			Handler:     p.handlePickCommand,
+			Description: "asks Morty to pick between things, like `x or y`",
+			Cooldown:    mmmorty.Cooldown{User: 3 * time.Second},
+			Rerun:       true,
+		},
 	}
 }
 
-func (p *PickPlugin) handlePickCommand(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage) {
-	requester := fmt.Sprintf("<@%s>", message.UserID())
+// Message is unused, this plugin's commands are routed by the bot
+func (p *PickPlugin) Message(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
+}
 
+func (p *PickPlugin) handlePickCommand(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
 	if strings.Contains(message.Message(), "http") {
-		reply := fmt.Sprintf("Uh, %s, I would rather not pick between links.", requester)
+		reply := mmmorty.Text(service, message, "pick.links", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
@@ -68,7 +67,7 @@ func (p *PickPlugin) handlePickCommand(bot *mmmorty.Bot, service mmmorty.Discord
 
 	for index, word := range parts {
 		if index > maxWordCount {
-			reply := fmt.Sprintf("Uh, %s, that message is kind of long. Is there any way you can shorten it?", requester)
+			reply := mmmorty.Text(service, message, "pick.long", nil)
 			service.SendMessage(message.Channel(), reply)
 			return
 		}
@@ -81,7 +80,7 @@ func (p *PickPlugin) handlePickCommand(bot *mmmorty.Bot, service mmmorty.Discord
 	}
 
 	if len(currentOption) == 0 {
-		reply := fmt.Sprintf("Uh, %s, I didn't get that last one.", requester)
+		reply := mmmorty.Text(service, message, "pick.empty", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
@@ -89,14 +88,14 @@ func (p *PickPlugin) handlePickCommand(bot *mmmorty.Bot, service mmmorty.Discord
 	options = append(options, strings.Join(currentOption, " "))
 
 	if len(options) < 2 {
-		reply := fmt.Sprintf("Uh, %s, I didn't get that. Maybe put `or` between options?", requester)
+		reply := mmmorty.Text(service, message, "pick.one", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
 	index := rand.Intn(len(options))
 	choice := options[index]
-	reply := fmt.Sprintf(pickTemplate, choice)
+	reply := mmmorty.Text(service, message, "pick.choice", mmmorty.Vars{"choice": choice})
 	service.SendMessage(message.Channel(), reply)
 }
 
@@ -0,0 +1,131 @@
+package mmmorty
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const (
+	setPrefixCommand   = "set prefix"
+	resetPrefixCommand = "reset prefix"
+	maxPrefixLength    = 5
+)
+
+var setPrefixSpec = MustParseArgSpec("set prefix <prefix>")
+
+type prefixPlugin struct {
+	Prefixes map[string]string `json:"prefixes"` // map of guild ID to prefix
+}
+
+// Name returns the name of the plugin.
+func (p *prefixPlugin) Name() string {
+	return "Prefix"
+}
+
+// Help returns a list of help strings that are printed when the user requests them.
+func (p *prefixPlugin) Help(bot *Bot, service Service, message Message, detailed bool) []string {
+	if detailed || service.IsPrivate(message) || !service.IsModerator(message) {
+		return nil
+	}
+	help := CommandHelp(service, setPrefixCommand, setPrefixSpec.Arguments(), Text(service, nil, "prefix.help.set", nil))
+	return append(help, CommandHelp(service, resetPrefixCommand, "", Text(service, nil, "prefix.help.reset", nil))...)
+}
+
+// Commands returns the commands this plugin handles.
+func (p *prefixPlugin) Commands() []Command {
+	return []Command{
+		{
+			Name:        setPrefixCommand,
+			Handler:     p.handleSetPrefix,
+			Description: "sets the prefix for commands on this server",
+			Spec:        setPrefixSpec,
+			Permission:  Moderator,
+		},
+		{
+			Name:        resetPrefixCommand,
+			Handler:     p.handleResetPrefix,
+			Description: "goes back to only answering mentions",
+			Permission:  Moderator,
+		},
+	}
+}
+
+func (p *prefixPlugin) Message(bot *Bot, service Service, message Message) {
+}
+
+// settingGuildID returns the guild a moderator asked to change a setting of, replying if it can't be found.
+// privateID is the message explaining the setting can't be changed in private.
+func settingGuildID(service Service, message Message, privateID string) (string, bool) {
+	if service.IsPrivate(message) {
+		SendEphemeral(service, message.Channel(), Text(service, message, privateID, nil))
+		return "", false
+	}
+	channel, err := service.Channel(message.Channel())
+	if err != nil {
+		SendEphemeral(service, message.Channel(), Text(service, message, "error.guild", nil))
+		return "", false
+	}
+	return channel.GuildID, true
+}
+
+func (p *prefixPlugin) handleSetPrefix(bot *Bot, service Service, message Message) {
+	guildID, ok := settingGuildID(service, message, "prefix.private")
+	if !ok {
+		return
+	}
+
+	args, err := setPrefixSpec.Parse(service, message)
+	if err != nil {
+		SendEphemeral(service, message.Channel(), setPrefixSpec.UsageReply(service, message, err))
+		return
+	}
+
+	prefix := args.String("prefix")
+	if len(prefix) > maxPrefixLength || strings.HasPrefix(prefix, "<") || strings.HasPrefix(prefix, "@") {
+		reply := Text(service, message, "prefix.invalid", Vars{"count": maxPrefixLength})
+		SendEphemeral(service, message.Channel(), reply)
+		return
+	}
+
+	if p.Prefixes == nil {
+		p.Prefixes = map[string]string{}
+	}
+	p.Prefixes[guildID] = prefix
+
+	reply := Text(service, message, "prefix.set", Vars{"prefix": prefix})
+	service.SendMessage(message.Channel(), reply)
+}
+
+func (p *prefixPlugin) handleResetPrefix(bot *Bot, service Service, message Message) {
+	guildID, ok := settingGuildID(service, message, "prefix.private")
+	if !ok {
+		return
+	}
+
+	delete(p.Prefixes, guildID)
+
+	reply := Text(service, message, "prefix.reset", nil)
+	service.SendMessage(message.Channel(), reply)
+}
+
+// Load will load plugin state from a byte array.
+func (p *prefixPlugin) Load(bot *Bot, service Service, data []byte) error {
+	if data != nil {
+		if err := json.Unmarshal(data, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save will save plugin state to a byte array.
+func (p *prefixPlugin) Save() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// NewPrefixPlugin will create a new prefix plugin.
+func NewPrefixPlugin() Plugin {
+	return &prefixPlugin{
+		Prefixes: map[string]string{},
+	}
+}
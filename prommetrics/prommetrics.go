@@ -0,0 +1,163 @@
+package prommetrics
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/todd-beckman/mmmorty"
+)
+
+const namespace = "mmmorty"
+
+// Metrics is a mmmorty.Metrics that measures a bot for Prometheus.
+// Queue depths and plugin gauges, such as active sprints, are read from the bot when they're scraped.
+type Metrics struct {
+	bot      *mmmorty.Bot
+	registry *prometheus.Registry
+
+	messages    *prometheus.CounterVec
+	commands    *prometheus.CounterVec
+	handled     *prometheus.HistogramVec
+	panics      *prometheus.CounterVec
+	sendsFailed *prometheus.CounterVec
+	saves       *prometheus.HistogramVec
+	saveSizes   *prometheus.HistogramVec
+
+	queued   *prometheus.Desc
+	received *prometheus.Desc
+}
+
+var _ mmmorty.Metrics = (*Metrics)(nil)
+
+// New creates the metrics for a bot. Set it as the bot's Metrics for them to be measured.
+func New(bot *mmmorty.Bot) *Metrics {
+	m := &Metrics{
+		bot:      bot,
+		registry: prometheus.NewRegistry(),
+		messages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "messages_received_total",
+			Help:      "Messages received, by type.",
+		}, []string{"service", "type"}),
+		commands: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "commands_total",
+			Help:      "Commands run, by plugin and command.",
+		}, []string{"service", "plugin", "command"}),
+		handled: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "handler_duration_seconds",
+			Help:      "How long plugins take to handle a message, by plugin and command. Messages that weren't commands have no command.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 8),
+		}, []string{"service", "plugin", "command"}),
+		panics: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "panics_recovered_total",
+			Help:      "Panics recovered while plugins handled messages.",
+		}, []string{"service"}),
+		sendsFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "send_failures_total",
+			Help:      "Messages that couldn't be sent.",
+		}, []string{"service"}),
+		saves: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "save_duration_seconds",
+			Help:      "How long saving a plugin's data takes.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 8),
+		}, []string{"service", "plugin"}),
+		saveSizes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "save_size_bytes",
+			Help:      "How much data is saved for a plugin.",
+			Buckets:   prometheus.ExponentialBuckets(256, 4, 10),
+		}, []string{"service", "plugin"}),
+		queued: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "queue_depth"),
+			"Messages waiting for a worker.",
+			[]string{"service"}, nil,
+		),
+		received: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "received_queue_depth"),
+			"Messages received from the service that haven't been queued for a worker yet.",
+			[]string{"service"}, nil,
+		),
+	}
+	m.registry.MustRegister(
+		m.messages, m.commands, m.handled, m.panics, m.sendsFailed, m.saves, m.saveSizes,
+		&botCollector{m},
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler returns the HTTP handler that serves the metrics to Prometheus.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// MessageReceived counts a message a service received.
+func (m *Metrics) MessageReceived(service string, messageType mmmorty.MessageType) {
+	m.messages.WithLabelValues(service, string(messageType)).Inc()
+}
+
+// Handled measures how long a plugin took to handle a message, and counts the command it ran.
+func (m *Metrics) Handled(service, plugin, command string, d time.Duration) {
+	m.handled.WithLabelValues(service, plugin, command).Observe(d.Seconds())
+	if command != "" {
+		m.commands.WithLabelValues(service, plugin, command).Inc()
+	}
+}
+
+// PanicRecovered counts a panic.
+func (m *Metrics) PanicRecovered(service string) {
+	m.panics.WithLabelValues(service).Inc()
+}
+
+// SendFailed counts a message that couldn't be sent.
+func (m *Metrics) SendFailed(service string) {
+	m.sendsFailed.WithLabelValues(service).Inc()
+}
+
+// Saved measures how long saving a plugin took and how much it saved.
+func (m *Metrics) Saved(service, plugin string, d time.Duration, size int) {
+	m.saves.WithLabelValues(service, plugin).Observe(d.Seconds())
+	m.saveSizes.WithLabelValues(service, plugin).Observe(float64(size))
+}
+
+// botCollector reads the bot's queues and plugin gauges when the metrics are scraped.
+// Plugins name their own gauges, so it's an unchecked collector that describes nothing up front.
+type botCollector struct {
+	m *Metrics
+}
+
+func (c *botCollector) Describe(ch chan<- *prometheus.Desc) {
+}
+
+func (c *botCollector) Collect(ch chan<- prometheus.Metric) {
+	for service, stats := range c.m.bot.QueueStats() {
+		ch <- prometheus.MustNewConstMetric(c.m.queued, prometheus.GaugeValue, float64(stats.Queued), service)
+		ch <- prometheus.MustNewConstMetric(c.m.received, prometheus.GaugeValue, float64(stats.Received), service)
+	}
+	for service, gauges := range c.m.bot.Gauges() {
+		for name, value := range gauges {
+			desc := prometheus.NewDesc(prometheus.BuildFQName(namespace, "", gaugeName(name)), "Measured by a plugin.", []string{"service"}, nil)
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, service)
+		}
+	}
+}
+
+// gaugeName returns a plugin's name for a gauge as a valid metric name, eg. "active sprints" becomes "active_sprints".
+func gaugeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,39 @@
+package promptplugin
+
+import "github.com/todd-beckman/mmmorty"
+
+func init() {
+	mmmorty.RegisterMessages("en", map[string]string{
+		"prompt.help.add":       "adds a prompt for Morty to remember",
+		"prompt.help.arguments": "some prompt",
+		"prompt.help.prompt":    "asks Morty for a prompt at random.",
+		"prompt.full":           "Uh, {user}, I can't remember all these prompts. Rick might need to help get rid of some.",
+		"prompt.links":          "Uh, {user}, I would rather not remember prompts with links.",
+		"prompt.long":           "Uh, {user}, that prompt is kind of long. Is there any way you can shorten it?",
+		"prompt.missing":        "Uh, {user}, I didn't get that. Be sure to say the prompt you want me to remember.",
+		"prompt.added":          "Ok, {user}, you got it! I will try to remember that one.",
+		"prompt.empty":          "Uh, {user}, I don't know any prompts yet. Maybe you could add them?",
+		"prompt.prompt":         "```\n{prompt}\n```",
+	})
+
+	mmmorty.RegisterMessages("es", map[string]string{
+		"prompt.help.add":       "añade una idea para que Morty la recuerde",
+		"prompt.help.arguments": "alguna idea",
+		"prompt.help.prompt":    "le pide a Morty una idea al azar.",
+		"prompt.full":           "Eh, {user}, no puedo recordar tantas ideas. Quizás Rick tenga que ayudar a borrar algunas.",
+		"prompt.links":          "Eh, {user}, prefiero no recordar ideas con enlaces.",
+		"prompt.long":           "Eh, {user}, esa idea es un poco larga. ¿Hay alguna forma de acortarla?",
+		"prompt.missing":        "Eh, {user}, no lo entendí. Asegúrate de decir la idea que quieres que recuerde.",
+		"prompt.added":          "Vale, {user}, ¡hecho! Intentaré recordar esa.",
+		"prompt.empty":          "Eh, {user}, todavía no conozco ninguna idea. ¿Quizás podrías añadir alguna?",
+		"prompt.prompt":         "```\n{prompt}\n```",
+	})
+
+	mmmorty.RequirePlaceholders(map[string][]string{
+		"prompt.prompt": {"prompt"},
+	})
+
+	mmmorty.RegisterSamples(map[string]mmmorty.Vars{
+		"prompt": {"prompt": "Write about a portal to a world where it's always Tuesday."},
+	})
+}
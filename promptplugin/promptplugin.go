@@ -3,9 +3,9 @@ package promptplugin
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"math/rand"
 	"strings"
+	"time"
 
 	"github.com/todd-beckman/mmmorty"
 )
@@ -13,10 +13,8 @@ import (
 const (
 	addPromptCommand = "add prompt"
 	promptCommand    = "prompt"
-	promptTemplate   = "```\n" +
-		"%s\n" +
-		"```"
 
+	// The default limits, which the bot's configuration can change.
 	maxPromptCount = 500
 	maxWordCount   = 150
 )
@@ -31,33 +29,76 @@ type PromptPlugin struct {
 	Prompts map[string][]Prompt `json:"prompts"`
 }
 
-type handleFunc func(*mmmorty.Bot, mmmorty.Discord, mmmorty.DiscordMessage, string)
+// Limits returns how many prompts a server can keep and how many words each can have.
+func (p *PromptPlugin) Limits() map[string]int {
+	return map[string]int{"prompts": maxPromptCount, "prompt_words": maxWordCount}
+}
 
-func (p *PromptPlugin) findHandler(service mmmorty.Discord, message mmmorty.DiscordMessage) handleFunc {
-	handlers := map[string]handleFunc{
-		addPromptCommand: p.handleAddPromptCommand,
-		promptCommand:    p.handlePromptCommand,
+// GuildData returns a server's prompts.
+func (p *PromptPlugin) GuildData(service mmmorty.Service, guildID string) interface{} {
+	if p.Prompts[guildID] == nil {
+		return []Prompt{}
 	}
-	for c, h := range handlers {
-		if mmmorty.MatchesCommand(service, c, message) {
-			return h
+	return p.Prompts[guildID]
+}
+
+// SetGuildData replaces a server's prompts.
+func (p *PromptPlugin) SetGuildData(bot *mmmorty.Bot, service mmmorty.Service, guildID string, data []byte) error {
+	prompts := []Prompt{}
+	if err := json.Unmarshal(data, &prompts); err != nil {
+		return err
+	}
+	if limit := bot.Limit(guildID, "prompts"); len(prompts) > limit {
+		return fmt.Errorf("a server can have at most %d prompts", limit)
+	}
+	for i, prompt := range prompts {
+		if strings.TrimSpace(prompt.Prompt) == "" {
+			return fmt.Errorf("prompt %d is empty", i+1)
 		}
 	}
+
+	if p.Prompts == nil {
+		p.Prompts = map[string][]Prompt{}
+	}
+	if len(prompts) == 0 {
+		delete(p.Prompts, guildID)
+	} else {
+		p.Prompts[guildID] = prompts
+	}
 	return nil
 }
 
+type handleFunc func(*mmmorty.Bot, mmmorty.Service, mmmorty.Message, string)
+
+// Commands returns the commands this plugin handles
+func (p *PromptPlugin) Commands() []mmmorty.Command {
+	return []mmmorty.Command{
+		{
+			Name:        addPromptCommand,
+			Handler:     p.guildCommand(p.handleAddPromptCommand),
+			Description: "adds a prompt for Morty to remember",
+		},
+		{
+			Name:        promptCommand,
+			Handler:     p.guildCommand(p.handlePromptCommand),
+			Description: "asks Morty for a prompt at random",
+			Cooldown:    mmmorty.Cooldown{User: 10 * time.Second},
+			Rerun:       true,
+		},
+	}
+}
+
 // Help gets the usage for this plugin
-func (p *PromptPlugin) Help(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, detailed bool) []string {
-	help := mmmorty.CommandHelp(service, addPromptCommand, "some prompt", "adds a prompt for Morty to remember")
-	help = append(help, mmmorty.CommandHelp(service, promptCommand, "", "asks Morty for a prompt at random.")[0])
+func (p *PromptPlugin) Help(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, detailed bool) []string {
+	help := mmmorty.CommandHelp(service, addPromptCommand, mmmorty.Text(service, nil, "prompt.help.arguments", nil), mmmorty.Text(service, nil, "prompt.help.add", nil))
+	help = append(help, mmmorty.CommandHelp(service, promptCommand, "", mmmorty.Text(service, nil, "prompt.help.prompt", nil))[0])
 	return help
 }
 
 // Load sets the state of the plugin from the given data
-func (p *PromptPlugin) Load(bot *mmmorty.Bot, service mmmorty.Discord, data []byte) error {
+func (p *PromptPlugin) Load(bot *mmmorty.Bot, service mmmorty.Service, data []byte) error {
 	if data != nil {
 		if err := json.Unmarshal(data, p); err != nil {
-			log.Println("Error loading data", err)
 			return err
 		}
 	}
@@ -65,52 +106,46 @@ func (p *PromptPlugin) Load(bot *mmmorty.Bot, service mmmorty.Discord, data []by
 	return nil
 }
 
-// Message is the command handler for this plugin
-func (p *PromptPlugin) Message(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage) {
-	defer bot.MessageRecover(service, message.Channel())
-	if service.IsMe(message) {
-		return
-	}
+// Message is unused, this plugin's commands are routed by the bot
+func (p *PromptPlugin) Message(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
+}
 
-	handler := p.findHandler(service, message)
-	if handler == nil {
-		return
-	}
+// guildCommand looks up the guild a command was sent in before handling it
+func (p *PromptPlugin) guildCommand(handler handleFunc) mmmorty.CommandFunc {
+	return func(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
+		channelID := message.Channel()
+		discordChannel, err := service.Channel(channelID)
+		if err != nil {
+			reply := mmmorty.Text(service, message, "error.guild", nil)
+			service.SendMessage(message.Channel(), reply)
+			return
+		}
+		guildID := discordChannel.GuildID
 
-	channelID := message.Channel()
-	discordChannel, err := service.Channel(channelID)
-	if err != nil {
-		requester := fmt.Sprintf("<@%s>", message.UserID())
-		reply := fmt.Sprintf("Uh, %s, something went figuring out your server.", requester)
-		service.SendMessage(message.Channel(), reply)
-		return
+		handler(bot, service, message, guildID)
 	}
-	guildID := discordChannel.GuildID
-
-	handler(bot, service, message, guildID)
 }
 
-func (p *PromptPlugin) handleAddPromptCommand(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, guildID string) {
-	requester := fmt.Sprintf("<@%s>", message.UserID())
-
-	if len(p.Prompts[guildID]) >= maxPromptCount {
-		reply := fmt.Sprintf("Uh, %s, I can't remember all these prompts. Rick might need to help get rid of some.", requester)
+func (p *PromptPlugin) handleAddPromptCommand(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, guildID string) {
+	if len(p.Prompts[guildID]) >= bot.Limit(guildID, "prompts") {
+		reply := mmmorty.Text(service, message, "prompt.full", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
 	if strings.Contains(message.Message(), "http") {
-		reply := fmt.Sprintf("Uh, %s, I would rather not remember prompts with links.", requester)
+		reply := mmmorty.Text(service, message, "prompt.links", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
 	_, parts := mmmorty.ParseCommand(service, message)
 
+	maxWords := bot.Limit(guildID, "prompt_words")
 	promptParts := []string{}
 	for index, word := range parts[1:] {
-		if index > maxWordCount {
-			reply := fmt.Sprintf("Uh, %s, that prompt is kind of long. Is there any way you can shorten it?", requester)
+		if index > maxWords {
+			reply := mmmorty.Text(service, message, "prompt.long", nil)
 			service.SendMessage(message.Channel(), reply)
 			return
 		}
@@ -118,7 +153,7 @@ func (p *PromptPlugin) handleAddPromptCommand(bot *mmmorty.Bot, service mmmorty.
 	}
 
 	if len(promptParts) == 0 {
-		reply := fmt.Sprintf("Uh, %s, I didn't get that. Be sure to say the prompt you want me to remember.", requester)
+		reply := mmmorty.Text(service, message, "prompt.missing", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
@@ -140,23 +175,21 @@ func (p *PromptPlugin) handleAddPromptCommand(bot *mmmorty.Bot, service mmmorty.
 
 	p.Prompts[guildID] = append(p.Prompts[guildID], newPrompt)
 
-	reply := fmt.Sprintf("Ok, %s, you got it! I will try to remember that one.", requester)
+	reply := mmmorty.Text(service, message, "prompt.added", nil)
 	service.SendMessage(message.Channel(), reply)
 }
 
-func (p *PromptPlugin) handlePromptCommand(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, guildID string) {
-	requester := fmt.Sprintf("<@%s>", message.UserID())
-
+func (p *PromptPlugin) handlePromptCommand(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, guildID string) {
 	promptCount := len(p.Prompts[guildID])
 	if promptCount == 0 {
-		reply := fmt.Sprintf("Uh, %s, I don't know any prompts yet. Maybe you could add them?", requester)
+		reply := mmmorty.Text(service, message, "prompt.empty", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
 	index := rand.Intn(promptCount)
 	prompt := p.Prompts[guildID][index]
-	reply := fmt.Sprintf(promptTemplate, prompt.Prompt)
+	reply := mmmorty.Text(service, message, "prompt.prompt", mmmorty.Vars{"prompt": prompt.Prompt})
 	service.SendMessage(message.Channel(), reply)
 }
 
@@ -0,0 +1,43 @@
+package quoteplugin
+
+import "github.com/todd-beckman/mmmorty"
+
+func init() {
+	mmmorty.RegisterMessages("en", map[string]string{
+		"quote.help.add":   "adds a quote for Morty to remember",
+		"quote.help.quote": "retrieves a quote at random.",
+		"quote.private":    "Uh, {user}, I can't add quotes privately.",
+		"quote.full":       "Uh, {user}, I can't remember all these quotes. Rick might need to help get rid of some.",
+		"quote.links":      "Uh, {user}, I would rather not remember quotes with links.",
+		"quote.long":       "Uh, {user}, that quote is kind of long. Is there any way you can shorten it?",
+		"quote.added":      "Ok, {user}, you got it! I will try to remember that one.",
+		"quote.empty":      "Uh, {user}, I don't know any quotes yet. Maybe you could add them?",
+		"quote.quote":      "```\n{author} said:\n{quote}\n```",
+		"quote.stats":      "Quotes remembered here: {count}",
+	})
+
+	mmmorty.RegisterMessages("es", map[string]string{
+		"quote.help.add":   "añade una cita para que Morty la recuerde",
+		"quote.help.quote": "muestra una cita al azar.",
+		"quote.private":    "Eh, {user}, no puedo añadir citas en privado.",
+		"quote.full":       "Eh, {user}, no puedo recordar tantas citas. Quizás Rick tenga que ayudar a borrar algunas.",
+		"quote.links":      "Eh, {user}, prefiero no recordar citas con enlaces.",
+		"quote.long":       "Eh, {user}, esa cita es un poco larga. ¿Hay alguna forma de acortarla?",
+		"quote.added":      "Vale, {user}, ¡hecho! Intentaré recordar esa.",
+		"quote.empty":      "Eh, {user}, todavía no conozco ninguna cita. ¿Quizás podrías añadir alguna?",
+		"quote.quote":      "```\n{author} dijo:\n{quote}\n```",
+		"quote.stats":      "Citas recordadas aquí: {count}",
+	})
+
+	mmmorty.RequirePlaceholders(map[string][]string{
+		"quote.quote": {"quote"},
+	})
+
+	mmmorty.RegisterSamples(map[string]mmmorty.Vars{
+		"quote": {
+			"author": "Rick",
+			"count":  12,
+			"quote":  "Wubba lubba dub dub!",
+		},
+	})
+}
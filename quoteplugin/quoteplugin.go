@@ -3,9 +3,9 @@ package quoteplugin
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"math/rand"
 	"strings"
+	"time"
 
 	"github.com/todd-beckman/mmmorty"
 )
@@ -13,15 +13,14 @@ import (
 const (
 	addQuoteCommand = "add quote"
 	quoteCommand    = "quote me"
-	quoteTemplate   = "```\n" +
-		"%s said:\n" +
-		"%s\n" +
-		"```"
 
+	// The default limits, which the bot's configuration can change.
 	maxQuoteCount = 500
 	maxWordCount  = 150
 )
 
+var addQuoteSpec = mmmorty.MustParseArgSpec("add quote <author:text> said <quote:text>")
+
 // Quote is who said what
 type Quote struct {
 	Author string `json:"author"`
@@ -33,33 +32,89 @@ type QuotePlugin struct {
 	Quotes map[string][]Quote `json:"quotes"`
 }
 
-type handleFunc func(*mmmorty.Bot, mmmorty.Discord, mmmorty.DiscordMessage, string)
+// Stats shows how many quotes the server has.
+func (p *QuotePlugin) Stats(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) []string {
+	if service.IsPrivate(message) {
+		return nil
+	}
+	channel, err := service.Channel(message.Channel())
+	if err != nil {
+		return nil
+	}
+	return []string{mmmorty.Text(service, nil, "quote.stats", mmmorty.Vars{"count": len(p.Quotes[channel.GuildID])})}
+}
 
-func (p *QuotePlugin) findHandler(service mmmorty.Discord, message mmmorty.DiscordMessage) handleFunc {
-	handlers := map[string]handleFunc{
-		addQuoteCommand: p.handleAddQuoteCommand,
-		quoteCommand:    p.handleQuoteCommand,
+// Limits returns how many quotes a server can keep and how many words each can have.
+func (p *QuotePlugin) Limits() map[string]int {
+	return map[string]int{"quotes": maxQuoteCount, "quote_words": maxWordCount}
+}
+
+// GuildData returns a server's quotes.
+func (p *QuotePlugin) GuildData(service mmmorty.Service, guildID string) interface{} {
+	if p.Quotes[guildID] == nil {
+		return []Quote{}
 	}
-	for c, h := range handlers {
-		if mmmorty.MatchesCommand(service, c, message) {
-			return h
+	return p.Quotes[guildID]
+}
+
+// SetGuildData replaces a server's quotes.
+func (p *QuotePlugin) SetGuildData(bot *mmmorty.Bot, service mmmorty.Service, guildID string, data []byte) error {
+	quotes := []Quote{}
+	if err := json.Unmarshal(data, &quotes); err != nil {
+		return err
+	}
+	if limit := bot.Limit(guildID, "quotes"); len(quotes) > limit {
+		return fmt.Errorf("a server can have at most %d quotes", limit)
+	}
+	for i, quote := range quotes {
+		if strings.TrimSpace(quote.Author) == "" || strings.TrimSpace(quote.Quote) == "" {
+			return fmt.Errorf("quote %d needs an author and a quote", i+1)
 		}
 	}
+
+	if p.Quotes == nil {
+		p.Quotes = map[string][]Quote{}
+	}
+	if len(quotes) == 0 {
+		delete(p.Quotes, guildID)
+	} else {
+		p.Quotes[guildID] = quotes
+	}
 	return nil
 }
 
+type handleFunc func(*mmmorty.Bot, mmmorty.Service, mmmorty.Message, string)
+
+// Commands returns the commands this plugin handles
+func (p *QuotePlugin) Commands() []mmmorty.Command {
+	return []mmmorty.Command{
+		{
+			Name:        addQuoteCommand,
+			Handler:     p.guildCommand(p.handleAddQuoteCommand),
+			Description: "adds a quote for Morty to remember",
+			Spec:        addQuoteSpec,
+		},
+		{
+			Name:        quoteCommand,
+			Handler:     p.guildCommand(p.handleQuoteCommand),
+			Description: "retrieves a quote at random",
+			Cooldown:    mmmorty.Cooldown{User: 10 * time.Second},
+			Rerun:       true,
+		},
+	}
+}
+
 // Help gets usage info for this plugin
-func (p *QuotePlugin) Help(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, detailed bool) []string {
-	help := mmmorty.CommandHelp(service, addQuoteCommand, "somebody said some quote", "adds a quote for Morty to remember")
-	help = append(help, mmmorty.CommandHelp(service, quoteCommand, "", "retrieves a quote at random.")[0])
+func (p *QuotePlugin) Help(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, detailed bool) []string {
+	help := mmmorty.CommandHelp(service, addQuoteCommand, addQuoteSpec.Arguments(), mmmorty.Text(service, nil, "quote.help.add", nil))
+	help = append(help, mmmorty.CommandHelp(service, quoteCommand, "", mmmorty.Text(service, nil, "quote.help.quote", nil))[0])
 	return help
 }
 
 // Load reads the state of the plugin fron the data read from file
-func (p *QuotePlugin) Load(bot *mmmorty.Bot, service mmmorty.Discord, data []byte) error {
+func (p *QuotePlugin) Load(bot *mmmorty.Bot, service mmmorty.Service, data []byte) error {
 	if data != nil {
 		if err := json.Unmarshal(data, p); err != nil {
-			log.Println("Error loading data", err)
 			return err
 		}
 	}
@@ -67,80 +122,59 @@ func (p *QuotePlugin) Load(bot *mmmorty.Bot, service mmmorty.Discord, data []byt
 	return nil
 }
 
-// Message is the entry point handler for this bot
-func (p *QuotePlugin) Message(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage) {
-	defer bot.MessageRecover(service, message.Channel())
-	if service.IsMe(message) {
-		return
-	}
+// Message is unused, this plugin's commands are routed by the bot
+func (p *QuotePlugin) Message(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
+}
 
-	handler := p.findHandler(service, message)
-	if handler == nil {
-		return
-	}
+// guildCommand looks up the guild a command was sent in before handling it
+func (p *QuotePlugin) guildCommand(handler handleFunc) mmmorty.CommandFunc {
+	return func(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
+		channelID := message.Channel()
+		discordChannel, err := service.Channel(channelID)
+		if err != nil {
+			reply := mmmorty.Text(service, message, "error.guild", nil)
+			service.SendMessage(message.Channel(), reply)
+			return
+		}
+		guildID := discordChannel.GuildID
 
-	channelID := message.Channel()
-	discordChannel, err := service.Channel(channelID)
-	if err != nil {
-		requester := fmt.Sprintf("<@%s>", message.UserID())
-		reply := fmt.Sprintf("Uh, %s, something went figuring out your server.", requester)
-		service.SendMessage(message.Channel(), reply)
-		return
+		handler(bot, service, message, guildID)
 	}
-	guildID := discordChannel.GuildID
-
-	handler(bot, service, message, guildID)
 }
 
-func (p *QuotePlugin) handleAddQuoteCommand(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, guildID string) {
-	requester := fmt.Sprintf("<@%s>", message.UserID())
-
+func (p *QuotePlugin) handleAddQuoteCommand(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, guildID string) {
 	if service.IsPrivate(message) {
-		reply := fmt.Sprintf("Uh, %s, I can't add quotes privately.", requester)
+		reply := mmmorty.Text(service, message, "quote.private", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
-	if len(p.Quotes[guildID]) >= maxQuoteCount {
-		reply := fmt.Sprintf("Uh, %s, I can't remember all these quotes. Rick might need to help get rid of some.", requester)
+	if len(p.Quotes[guildID]) >= bot.Limit(guildID, "quotes") {
+		reply := mmmorty.Text(service, message, "quote.full", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
 	if strings.Contains(message.Message(), "http") {
-		reply := fmt.Sprintf("Uh, %s, I would rather not remember quotes with links.", requester)
+		reply := mmmorty.Text(service, message, "quote.links", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
-	_, parts := mmmorty.ParseCommand(service, message)
-
-	authorParts := []string{}
-	quoteParts := []string{}
-	saidIndex := 0
-	for index, word := range parts[1:] { // first word is 'quote' because 'add quote'
-		if index > maxWordCount {
-			reply := fmt.Sprintf("Uh, %s, that quote is kind of long. Is there any way you can shorten it?", requester)
-			service.SendMessage(message.Channel(), reply)
-			return
-		}
-		if saidIndex == 0 && word == "said" {
-			saidIndex = index
-		} else if saidIndex == 0 {
-			authorParts = append(authorParts, word)
-		} else {
-			quoteParts = append(quoteParts, word)
-		}
+	args, err := addQuoteSpec.Parse(service, message)
+	if err != nil {
+		mmmorty.SendEphemeral(service, message.Channel(), addQuoteSpec.UsageReply(service, message, err))
+		return
 	}
 
-	if len(quoteParts) == 0 {
-		reply := fmt.Sprintf("Uh, %s, I didn't get that. Maybe put `said` between the author and the quote?", requester)
+	if len(args.Parts) > bot.Limit(guildID, "quote_words") {
+		reply := mmmorty.Text(service, message, "quote.long", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
-	author := strings.Join(authorParts, " ")
-	quote := strings.Join(quoteParts, " ")
+	author := args.String("author")
+	quote := args.String("quote")
 
 	newQuote := Quote{
 		Author: author,
@@ -158,23 +192,21 @@ func (p *QuotePlugin) handleAddQuoteCommand(bot *mmmorty.Bot, service mmmorty.Di
 
 	p.Quotes[guildID] = append(p.Quotes[guildID], newQuote)
 
-	reply := fmt.Sprintf("Ok, %s, you got it! I will try to remember that one.", requester)
+	reply := mmmorty.Text(service, message, "quote.added", nil)
 	service.SendMessage(message.Channel(), reply)
 }
 
-func (p *QuotePlugin) handleQuoteCommand(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, guildID string) {
-	requester := fmt.Sprintf("<@%s>", message.UserID())
-
+func (p *QuotePlugin) handleQuoteCommand(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, guildID string) {
 	quoteCount := len(p.Quotes[guildID])
 	if quoteCount == 0 {
-		reply := fmt.Sprintf("Uh, %s, I don't know any quotes yet. Maybe you could add them?", requester)
+		reply := mmmorty.Text(service, message, "quote.empty", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
 	index := rand.Intn(quoteCount)
 	quote := p.Quotes[guildID][index]
-	reply := fmt.Sprintf(quoteTemplate, quote.Author, quote.Quote)
+	reply := mmmorty.Text(service, message, "quote.quote", mmmorty.Vars{"author": quote.Author, "quote": quote.Quote})
 	service.SendMessage(message.Channel(), reply)
 }
 
@@ -0,0 +1,118 @@
+package mmmorty
+
+import (
+	"sync"
+)
+
+// replyLogSize is how many of the messages the bot replied to most recently it remembers the replies to.
+const replyLogSize = 1000
+
+// MessageEditor is implemented by services that can edit the messages the bot sent.
+// When a message the bot replied to is edited, the bot handles it again and edits its replies rather than sending
+// more, and deletes the ones it doesn't need any more.
+type MessageEditor interface {
+	// SendMessageID sends a message and returns its ID.
+	SendMessageID(channel, message string) (string, error)
+	// EditMessage changes a message the bot sent.
+	EditMessage(channel, messageID, message string) error
+}
+
+// sentReply is a message the bot sent in reply to another.
+type sentReply struct {
+	channel string
+	id      string
+}
+
+// answered is what the bot knows about a message it handled: what it said, and what the bot replied.
+type answered struct {
+	content string
+	replies []sentReply
+}
+
+// replyLog remembers the replies to the messages the bot handled most recently, by service, channel and message ID.
+type replyLog struct {
+	mu       sync.Mutex
+	messages map[string]answered
+	order    []string // oldest first
+}
+
+func newReplyLog() *replyLog {
+	return &replyLog{
+		messages: map[string]answered{},
+	}
+}
+
+func replyKey(service Service, message Message) string {
+	return service.Name() + "/" + message.Channel() + "/" + message.MessageID()
+}
+
+// get returns what the bot knows about a message it handled.
+func (l *replyLog) get(key string) (answered, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	a, ok := l.messages[key]
+	return a, ok
+}
+
+// set remembers a message and the replies to it, forgetting the oldest message if there are too many.
+func (l *replyLog) set(key string, a answered) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.messages[key]; !ok {
+		l.order = append(l.order, key)
+	}
+	l.messages[key] = a
+	for len(l.order) > replyLogSize {
+		delete(l.messages, l.order[0])
+		l.order = l.order[1:]
+	}
+}
+
+// replies tracks the replies to a message while it's handled. The replies sent before the message was edited are
+// edited in turn by the new ones, and any left over are deleted when it's done.
+type replies struct {
+	mu       sync.Mutex
+	editor   MessageEditor
+	previous []sentReply
+	sent     []sentReply
+}
+
+// send sends a reply, or edits the next reply from before the message was edited if it was in the same channel.
+func (r *replies) send(channel, message string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.previous) > 0 && r.previous[0].channel == channel {
+		reply := r.previous[0]
+		r.previous = r.previous[1:]
+		if err := r.editor.EditMessage(channel, reply.id, message); err != nil {
+			return err
+		}
+		r.sent = append(r.sent, reply)
+		return nil
+	}
+
+	id, err := r.editor.SendMessageID(channel, message)
+	if err != nil {
+		return err
+	}
+	r.sent = append(r.sent, sentReply{channel: channel, id: id})
+	return nil
+}
+
+// done deletes the replies from before the message was edited that weren't needed, and returns the replies the
+// message has now.
+func (r *replies) done(service Service) []sentReply {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, reply := range r.previous {
+		if err := service.DeleteMessage(reply.channel, reply.id); err != nil {
+			Logger(service).Error("Error deleting reply", "to", reply.channel, "err", err)
+		}
+	}
+	r.previous = nil
+	return append([]sentReply{}, r.sent...)
+}
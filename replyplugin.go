@@ -0,0 +1,252 @@
+package mmmorty
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	setReplyCommand     = "set reply"
+	previewReplyCommand = "preview reply"
+	resetReplyCommand   = "reset reply"
+)
+
+var (
+	setReplySpec     = MustParseArgSpec("set reply <id> <reply:text>")
+	previewReplySpec = MustParseArgSpec("preview reply <id> <reply:text>")
+	resetReplySpec   = MustParseArgSpec("reset reply <id>")
+)
+
+type replyPlugin struct {
+	Replies map[string]map[string]string `json:"replies"` // map of guild ID to message ID to template
+}
+
+// Name returns the name of the plugin.
+func (p *replyPlugin) Name() string {
+	return "Reply"
+}
+
+// Help returns a list of help strings that are printed when the user requests them.
+func (p *replyPlugin) Help(bot *Bot, service Service, message Message, detailed bool) []string {
+	if detailed || service.IsPrivate(message) || !service.IsModerator(message) {
+		return nil
+	}
+	example := service.CommandPrefix() + setReplyCommand + ` sprint.start "Go go go! {sprinters}"`
+	help := CommandHelp(service, setReplyCommand, setReplySpec.Arguments(), Text(service, nil, "reply.help.set", Vars{"example": example}))
+	help = append(help, CommandHelp(service, previewReplyCommand, previewReplySpec.Arguments(), Text(service, nil, "reply.help.preview", nil))...)
+	return append(help, CommandHelp(service, resetReplyCommand, resetReplySpec.Arguments(), Text(service, nil, "reply.help.reset", nil))...)
+}
+
+// Commands returns the commands this plugin handles.
+func (p *replyPlugin) Commands() []Command {
+	return []Command{
+		{
+			Name:        setReplyCommand,
+			Handler:     p.handleSetReply,
+			Description: "uses your own words for one of my replies on this server",
+			Spec:        setReplySpec,
+			Permission:  Moderator,
+		},
+		{
+			Name:        previewReplyCommand,
+			Handler:     p.handlePreviewReply,
+			Description: "shows how a reply would look without changing it",
+			Spec:        previewReplySpec,
+			Permission:  Moderator,
+			Rerun:       true,
+		},
+		{
+			Name:        resetReplyCommand,
+			Handler:     p.handleResetReply,
+			Description: "goes back to how I usually say a reply",
+			Spec:        resetReplySpec,
+			Permission:  Moderator,
+		},
+	}
+}
+
+func (p *replyPlugin) Message(bot *Bot, service Service, message Message) {
+}
+
+// placeholderList returns placeholders as they're written in a template, eg. "`{name}`, `{sprinters}`".
+func placeholderList(names []string) string {
+	list := make([]string, len(names))
+	for i, name := range names {
+		list[i] = fmt.Sprintf("`{%s}`", name)
+	}
+	return strings.Join(list, ", ")
+}
+
+// replyTemplate returns the reply as it was written after the message ID, without any quotes around it.
+// Reading it from the message rather than the parsed words keeps its spacing and new lines.
+func replyTemplate(message Message, id string) string {
+	text := message.Message()
+	if i := strings.Index(text, id); i != -1 {
+		text = text[i+len(id):]
+	}
+	text = strings.TrimSpace(text)
+	for _, quotes := range [][2]string{{`"`, `"`}, {"“", "”"}} {
+		open, close := quotes[0], quotes[1]
+		if len(text) >= len(open)+len(close) && strings.HasPrefix(text, open) && strings.HasSuffix(text, close) {
+			return strings.TrimSpace(text[len(open) : len(text)-len(close)])
+		}
+	}
+	return text
+}
+
+// parseReply parses a moderator's reply for a message, replying with the problem if it can't be used.
+func parseReply(service Service, message Message, spec *ArgSpec) (string, string, bool) {
+	args, err := spec.Parse(service, message)
+	if err != nil {
+		SendEphemeral(service, message.Channel(), spec.UsageReply(service, message, err))
+		return "", "", false
+	}
+
+	id := strings.ToLower(args.String("id"))
+	known, needed, ok := messagePlaceholders(id)
+	if !ok {
+		SendEphemeral(service, message.Channel(), Text(service, message, "reply.unknown", Vars{"id": id}))
+		return "", "", false
+	}
+
+	template := replyTemplate(message, args.String("id"))
+	if template == "" {
+		err := argError(service, "args.missing", Vars{"name": "reply"})
+		SendEphemeral(service, message.Channel(), spec.UsageReply(service, message, err))
+		return "", "", false
+	}
+
+	used := map[string]bool{}
+	unknown := []string{}
+	for _, name := range placeholders(template) {
+		used[name] = true
+		if !containsString(known, name) && !containsString(unknown, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		vars := Vars{"id": id, "placeholders": placeholderList(unknown), "known": placeholderList(known)}
+		if len(known) == 0 {
+			SendEphemeral(service, message.Channel(), Text(service, message, "reply.placeholders.none", vars))
+		} else {
+			SendEphemeral(service, message.Channel(), Text(service, message, "reply.placeholders", vars))
+		}
+		return "", "", false
+	}
+
+	missing := []string{}
+	for _, name := range needed {
+		if !used[name] && !containsString(missing, name) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		vars := Vars{"id": id, "placeholders": placeholderList(missing)}
+		SendEphemeral(service, message.Channel(), Text(service, message, "reply.missing", vars))
+		return "", "", false
+	}
+
+	return id, template, true
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// previewReply fills in who asked for a reply, and the reply's other placeholders with samples to show how it looks.
+func previewReply(message Message, id, template string) string {
+	vars := sampleVars(id)
+	vars["user"] = fmt.Sprintf("<@%s>", message.UserID())
+	return Format(template, vars)
+}
+
+func (p *replyPlugin) handleSetReply(bot *Bot, service Service, message Message) {
+	guildID, ok := settingGuildID(service, message, "reply.private")
+	if !ok {
+		return
+	}
+
+	id, template, ok := parseReply(service, message, setReplySpec)
+	if !ok {
+		return
+	}
+
+	if p.Replies == nil {
+		p.Replies = map[string]map[string]string{}
+	}
+	if p.Replies[guildID] == nil {
+		p.Replies[guildID] = map[string]string{}
+	}
+	p.Replies[guildID][id] = template
+
+	reply := Text(service, message, "reply.set", Vars{"id": id, "preview": previewReply(message, id, template)})
+	service.SendMessage(message.Channel(), reply)
+}
+
+func (p *replyPlugin) handlePreviewReply(bot *Bot, service Service, message Message) {
+	if _, ok := settingGuildID(service, message, "reply.private"); !ok {
+		return
+	}
+
+	id, template, ok := parseReply(service, message, previewReplySpec)
+	if !ok {
+		return
+	}
+
+	reply := Text(service, message, "reply.preview", Vars{"id": id, "preview": previewReply(message, id, template)})
+	SendEphemeral(service, message.Channel(), reply)
+}
+
+func (p *replyPlugin) handleResetReply(bot *Bot, service Service, message Message) {
+	guildID, ok := settingGuildID(service, message, "reply.private")
+	if !ok {
+		return
+	}
+
+	args, err := resetReplySpec.Parse(service, message)
+	if err != nil {
+		SendEphemeral(service, message.Channel(), resetReplySpec.UsageReply(service, message, err))
+		return
+	}
+
+	id := strings.ToLower(args.String("id"))
+	if _, ok := p.Replies[guildID][id]; !ok {
+		SendEphemeral(service, message.Channel(), Text(service, message, "reply.reset.missing", Vars{"id": id}))
+		return
+	}
+	delete(p.Replies[guildID], id)
+	if len(p.Replies[guildID]) == 0 {
+		delete(p.Replies, guildID)
+	}
+
+	reply := Text(service, message, "reply.reset", Vars{"id": id})
+	service.SendMessage(message.Channel(), reply)
+}
+
+// Load will load plugin state from a byte array.
+func (p *replyPlugin) Load(bot *Bot, service Service, data []byte) error {
+	if data != nil {
+		if err := json.Unmarshal(data, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save will save plugin state to a byte array.
+func (p *replyPlugin) Save() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// NewReplyPlugin will create a new reply plugin.
+func NewReplyPlugin() Plugin {
+	return &replyPlugin{
+		Replies: map[string]map[string]string{},
+	}
+}
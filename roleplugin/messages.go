@@ -0,0 +1,49 @@
+package roleplugin
+
+import "github.com/todd-beckman/mmmorty"
+
+func init() {
+	mmmorty.RegisterMessages("en", map[string]string{
+		"role.help":           "assigns the desired role if this server supports it.",
+		"role.help.arguments": "role",
+		"role.private":        "Uh, {user}, I cannot assign roles in private.",
+		"role.none":           "Uh, {user}, I don't think this server lets me set that role.",
+		"role.missing":        "Uh, {user}, I think you forgot to name a role.",
+		"role.unknown":        "Uh, {user}, I can't find a role called {role}",
+		"role.auth":           "Uh, {user}, I'm not supposed to share that role.",
+		"role.failed":         "Uh, {user}, something went wrong. Are you sure I can let you be {role}?",
+		"role.given":          "You got it, {user}! You are now {role}",
+		"role.manage.private": "Uh, {user}, I cannot manage roles in private.",
+		"role.manage.already": "Uh, {user}, I am already managing {role}",
+		"role.manage.auth":    "Uh, {user}, I don't think I can manage that role.",
+		"role.manage.managed": "Uh, I guess that means I am managing {roles} now.",
+		"role.manage.unknown": "Uh, {user}, I'm not managing {role}",
+		"role.stats":          "Roles members can give themselves here: {count}",
+	})
+
+	mmmorty.RegisterMessages("es", map[string]string{
+		"role.help":           "te da el rol que quieras si este servidor lo permite.",
+		"role.help.arguments": "rol",
+		"role.private":        "Eh, {user}, no puedo dar roles en privado.",
+		"role.none":           "Eh, {user}, no creo que este servidor me deje dar ese rol.",
+		"role.missing":        "Eh, {user}, creo que olvidaste decir el rol.",
+		"role.unknown":        "Eh, {user}, no encuentro ningún rol llamado {role}",
+		"role.auth":           "Eh, {user}, se supone que no debo compartir ese rol.",
+		"role.failed":         "Eh, {user}, algo salió mal. ¿Seguro que puedo hacerte {role}?",
+		"role.given":          "¡Hecho, {user}! Ahora eres {role}",
+		"role.manage.private": "Eh, {user}, no puedo gestionar roles en privado.",
+		"role.manage.already": "Eh, {user}, ya estoy gestionando {role}",
+		"role.manage.auth":    "Eh, {user}, no creo que pueda gestionar ese rol.",
+		"role.manage.managed": "Eh, supongo que eso significa que ahora gestiono {roles}.",
+		"role.manage.unknown": "Eh, {user}, no estoy gestionando {role}",
+		"role.stats":          "Roles que los miembros pueden darse aquí: {count}",
+	})
+
+	mmmorty.RegisterSamples(map[string]mmmorty.Vars{
+		"role": {
+			"count": 2,
+			"role":  "writer",
+			"roles": "[writer artist]",
+		},
+	})
+}
@@ -3,7 +3,7 @@ package roleplugin
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"sort"
 	"strings"
 
 	"github.com/todd-beckman/mmmorty"
@@ -42,22 +42,91 @@ func doesRoleHaveAuth(permissions int64) bool {
 	return permissions&authPermissions > 0
 }
 
-type handleFunc func(*mmmorty.Bot, mmmorty.Discord, mmmorty.DiscordMessage, string)
+// Stats shows how many roles the server lets members give themselves.
+func (p *RolePlugin) Stats(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) []string {
+	if service.IsPrivate(message) {
+		return nil
+	}
+	channel, err := service.Channel(message.Channel())
+	if err != nil {
+		return nil
+	}
+	count := len(p.getPrintableRoles(channel.GuildID))
+	return []string{mmmorty.Text(service, nil, "role.stats", mmmorty.Vars{"count": count})}
+}
+
+// GuildData returns the roles a server lets members give themselves.
+func (p *RolePlugin) GuildData(service mmmorty.Service, guildID string) interface{} {
+	roles := p.getPrintableRoles(guildID)
+	sort.Strings(roles)
+	return roles
+}
 
-func (p *RolePlugin) findHandler(service mmmorty.Discord, message mmmorty.DiscordMessage) handleFunc {
-	handlers := map[string]handleFunc{
-		rolesCommand:        p.handleIAm,
-		manageRolesCommand:  p.handleManageRole,
-		stopManagingCommand: p.handleStopManaging,
+// SetGuildData replaces the roles a server lets members give themselves. Each must be a role on the server that
+// doesn't give members any authority.
+func (p *RolePlugin) SetGuildData(bot *mmmorty.Bot, service mmmorty.Service, guildID string, data []byte) error {
+	names := []string{}
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	guild, err := service.Guild(guildID)
+	if err != nil {
+		return err
 	}
-	for c, h := range handlers {
-		if mmmorty.MatchesCommand(service, c, message) {
-			return h
+
+	managed := map[string]bool{}
+	for _, name := range names {
+		name = strings.ToLower(name)
+		for _, role := range guild.Roles {
+			if strings.ToLower(role.Name) != name {
+				continue
+			}
+			if doesRoleHaveAuth(role.Permissions) {
+				return fmt.Errorf("%s gives members authority", name)
+			}
+			managed[name] = true
+		}
+		if !managed[name] {
+			return fmt.Errorf("there's no role called %s", name)
 		}
 	}
+
+	if p.RolesByGuild == nil {
+		p.RolesByGuild = map[string]rolesSet{}
+	}
+	if len(managed) == 0 {
+		delete(p.RolesByGuild, guildID)
+	} else {
+		p.RolesByGuild[guildID] = rolesSet{ManagedRoles: managed}
+	}
 	return nil
 }
 
+type handleFunc func(*mmmorty.Bot, mmmorty.Service, mmmorty.Message, string)
+
+// Commands returns the commands this plugin handles
+func (p *RolePlugin) Commands() []mmmorty.Command {
+	return []mmmorty.Command{
+		{
+			Name:        rolesCommand,
+			Handler:     p.guildCommand(p.handleIAm),
+			Description: "assigns the desired role if this server supports it",
+		},
+		{
+			Name:        manageRolesCommand,
+			Handler:     p.guildCommand(p.handleManageRole),
+			Description: "lets members give themselves a role, for moderators",
+			Permission:  mmmorty.Moderator,
+		},
+		{
+			Name:        stopManagingCommand,
+			Handler:     p.guildCommand(p.handleStopManaging),
+			Description: "stops members giving themselves a role, for moderators",
+			Permission:  mmmorty.Moderator,
+		},
+	}
+}
+
 func (p *RolePlugin) getPrintableRoles(guildID string) []string {
 	printableRoles := []string{}
 	for role, isManaged := range p.RolesByGuild[guildID].ManagedRoles {
@@ -69,16 +138,15 @@ func (p *RolePlugin) getPrintableRoles(guildID string) []string {
 }
 
 // Help gets the usage for this plugin
-func (p *RolePlugin) Help(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, detailed bool) []string {
-	help := mmmorty.CommandHelp(service, rolesCommand, "role", "assigns the desired role if this server supports it.")
+func (p *RolePlugin) Help(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, detailed bool) []string {
+	help := mmmorty.CommandHelp(service, rolesCommand, mmmorty.Text(service, nil, "role.help.arguments", nil), mmmorty.Text(service, nil, "role.help", nil))
 	return help
 }
 
 // Load loads this plugin from the given data
-func (p *RolePlugin) Load(bot *mmmorty.Bot, service mmmorty.Discord, data []byte) error {
+func (p *RolePlugin) Load(bot *mmmorty.Bot, service mmmorty.Service, data []byte) error {
 	if data != nil {
 		if err := json.Unmarshal(data, p); err != nil {
-			log.Println("Error loading data", err)
 			return err
 		}
 	}
@@ -86,54 +154,47 @@ func (p *RolePlugin) Load(bot *mmmorty.Bot, service mmmorty.Discord, data []byte
 	return nil
 }
 
-// Message is the command handler for this plugin
-func (p *RolePlugin) Message(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage) {
-	defer bot.MessageRecover(service, message.Channel())
-	if service.IsMe(message) {
-		return
-	}
-
-	handler := p.findHandler(service, message)
-	if handler == nil {
-		return
-	}
+// Message is unused, this plugin's commands are routed by the bot
+func (p *RolePlugin) Message(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
+}
 
-	requester := fmt.Sprintf("<@%s>", message.UserID())
-	channelID := message.Channel()
-	discordChannel, err := service.Channel(channelID)
-	if err != nil {
-		reply := fmt.Sprintf("Uh, %s, something went figuring out your server.", requester)
-		service.SendMessage(message.Channel(), reply)
-		return
-	}
-	guildID := discordChannel.GuildID
+// guildCommand looks up the guild a command was sent in before handling it
+func (p *RolePlugin) guildCommand(handler handleFunc) mmmorty.CommandFunc {
+	return func(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
+		channelID := message.Channel()
+		discordChannel, err := service.Channel(channelID)
+		if err != nil {
+			reply := mmmorty.Text(service, message, "error.guild", nil)
+			service.SendMessage(message.Channel(), reply)
+			return
+		}
+		guildID := discordChannel.GuildID
 
-	if p.RolesByGuild == nil {
-		p.RolesByGuild = map[string]rolesSet{
-			guildID: {},
+		if p.RolesByGuild == nil {
+			p.RolesByGuild = map[string]rolesSet{
+				guildID: {},
+			}
 		}
-	}
 
-	if p.RolesByGuild[guildID].ManagedRoles == nil {
-		p.RolesByGuild[guildID] = rolesSet{
-			ManagedRoles: map[string]bool{},
+		if p.RolesByGuild[guildID].ManagedRoles == nil {
+			p.RolesByGuild[guildID] = rolesSet{
+				ManagedRoles: map[string]bool{},
+			}
 		}
-	}
 
-	handler(bot, service, message, guildID)
+		handler(bot, service, message, guildID)
+	}
 }
 
-func (p *RolePlugin) handleIAm(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, guildID string) {
-	requester := fmt.Sprintf("<@%s>", message.UserID())
-
+func (p *RolePlugin) handleIAm(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, guildID string) {
 	if service.IsPrivate(message) {
-		reply := fmt.Sprintf("Uh, %s, I cannot assign roles in private.", requester)
+		reply := mmmorty.Text(service, message, "role.private", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
 	if availableRoles := p.getPrintableRoles(guildID); len(availableRoles) == 0 {
-		reply := fmt.Sprintf("Uh, %s, I don't think this server lets me set that role.", requester)
+		reply := mmmorty.Text(service, message, "role.none", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
@@ -141,7 +202,7 @@ func (p *RolePlugin) handleIAm(bot *mmmorty.Bot, service mmmorty.Discord, messag
 	_, parts := mmmorty.ParseCommand(service, message)
 
 	if len(parts) == 1 {
-		reply := fmt.Sprintf("Uh, %s, I think you forgot to name a role.", requester)
+		reply := mmmorty.Text(service, message, "role.missing", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
@@ -149,40 +210,32 @@ func (p *RolePlugin) handleIAm(bot *mmmorty.Bot, service mmmorty.Discord, messag
 	for _, roleName := range parts[1:] {
 		role := service.GetRoleByName(message.Channel(), roleName)
 		if role == nil {
-			reply := fmt.Sprintf("Uh, %s, I can't find a role called %s", requester, roleName)
+			reply := mmmorty.Text(service, message, "role.unknown", mmmorty.Vars{"role": roleName})
 			service.SendMessage(message.Channel(), reply)
 			return
 		}
 
 		if doesRoleHaveAuth(role.Permissions) {
-			reply := fmt.Sprintf("Uh, %s, I'm not supposed to share that role.", requester)
+			reply := mmmorty.Text(service, message, "role.auth", nil)
 			service.SendMessage(message.Channel(), reply)
 			return
 		}
 
 		ok := service.GuildMemberRoleAdd(guildID, message.UserID(), role.ID)
 		if !ok {
-			reply := fmt.Sprintf("Uh, %s, something went wrong. Are you sure I can let you be %v?", requester, roleName)
+			reply := mmmorty.Text(service, message, "role.failed", mmmorty.Vars{"role": roleName})
 			service.SendMessage(message.Channel(), reply)
 			return
 		}
 
-		reply := fmt.Sprintf("You got it, %s! You are now %s", requester, roleName)
+		reply := mmmorty.Text(service, message, "role.given", mmmorty.Vars{"role": roleName})
 		service.SendMessage(message.Channel(), reply)
 	}
 }
 
-func (p *RolePlugin) handleManageRole(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, guildID string) {
-	requester := fmt.Sprintf("<@%s>", message.UserID())
-
+func (p *RolePlugin) handleManageRole(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, guildID string) {
 	if service.IsPrivate(message) {
-		reply := fmt.Sprintf("Uh, %s, I cannot manage roles in private.", requester)
-		service.SendMessage(message.Channel(), reply)
-		return
-	}
-
-	if message.UserID() != service.OwnerUserID {
-		reply := fmt.Sprintf("Uh, %s, I think you need to ask my Rick for that command.", requester)
+		reply := mmmorty.Text(service, message, "role.manage.private", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
@@ -190,8 +243,7 @@ func (p *RolePlugin) handleManageRole(bot *mmmorty.Bot, service mmmorty.Discord,
 	_, parts := mmmorty.ParseCommand(service, message)
 
 	if len(parts) < 1 {
-		reply := fmt.Sprintf("Uh, %s, I think you forgot to name a role.", requester)
-
+		reply := mmmorty.Text(service, message, "role.missing", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
@@ -199,20 +251,20 @@ func (p *RolePlugin) handleManageRole(bot *mmmorty.Bot, service mmmorty.Discord,
 	for _, c := range parts {
 		roleName := strings.ToLower(c)
 		if p.RolesByGuild[guildID].ManagedRoles[roleName] {
-			reply := fmt.Sprintf("Uh, %s, I am already managing %s", requester, roleName)
+			reply := mmmorty.Text(service, message, "role.manage.already", mmmorty.Vars{"role": roleName})
 			service.SendMessage(message.Channel(), reply)
 			continue
 		}
 
 		role := service.GetRoleByName(message.Channel(), roleName)
 		if role == nil {
-			reply := fmt.Sprintf("Uh, %s, I can't find a role called %s", requester, roleName)
+			reply := mmmorty.Text(service, message, "role.unknown", mmmorty.Vars{"role": roleName})
 			service.SendMessage(message.Channel(), reply)
 			continue
 		}
 
 		if doesRoleHaveAuth(role.Permissions) {
-			reply := fmt.Sprintf("Uh, %s, I don't think I can manage that role.", requester)
+			reply := mmmorty.Text(service, message, "role.manage.auth", nil)
 			service.SendMessage(message.Channel(), reply)
 			continue
 		}
@@ -221,23 +273,15 @@ func (p *RolePlugin) handleManageRole(bot *mmmorty.Bot, service mmmorty.Discord,
 	}
 
 	printableRoles := p.getPrintableRoles(guildID)
-	reply := fmt.Sprintf("Uh, I guess that means I am managing %v now.", printableRoles)
+	reply := mmmorty.Text(service, message, "role.manage.managed", mmmorty.Vars{"roles": printableRoles})
 	service.SendMessage(message.Channel(), reply)
 }
 
-func (p *RolePlugin) handleStopManaging(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, guildID string) {
-	requester := fmt.Sprintf("<@%s>", message.UserID())
-
-	if message.UserID() != service.OwnerUserID {
-		reply := fmt.Sprintf("Uh, %s, I think you need to ask my Rick for that command.", requester)
-		service.SendMessage(message.Channel(), reply)
-		return
-	}
-
+func (p *RolePlugin) handleStopManaging(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, guildID string) {
 	_, parts := mmmorty.ParseCommand(service, message)
 
 	if len(parts) < 1 {
-		reply := fmt.Sprintf("Uh, %s, I think you forgot to name a role.", requester)
+		reply := mmmorty.Text(service, message, "role.missing", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
@@ -245,7 +289,7 @@ func (p *RolePlugin) handleStopManaging(bot *mmmorty.Bot, service mmmorty.Discor
 	for _, c := range parts {
 		role := strings.ToLower(c)
 		if !p.RolesByGuild[guildID].ManagedRoles[role] {
-			reply := fmt.Sprintf("Uh, %s, I'm not managing %s", requester, role)
+			reply := mmmorty.Text(service, message, "role.manage.unknown", mmmorty.Vars{"role": role})
 			service.SendMessage(message.Channel(), reply)
 			continue
 		}
@@ -254,7 +298,7 @@ func (p *RolePlugin) handleStopManaging(bot *mmmorty.Bot, service mmmorty.Discor
 	}
 
 	printableRoles := p.getPrintableRoles(guildID)
-	reply := fmt.Sprintf("Uh, I guess that means I am managing %v now.", printableRoles)
+	reply := mmmorty.Text(service, message, "role.manage.managed", mmmorty.Vars{"roles": printableRoles})
 	service.SendMessage(message.Channel(), reply)
 }
 
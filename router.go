@@ -0,0 +1,147 @@
+package mmmorty
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CommandFunc is the function signature for a routed command handler.
+type CommandFunc func(bot *Bot, service Service, message Message)
+
+// Command is a command a plugin registers with the bot's router.
+type Command struct {
+	// Name is what a message starts with to run the command, eg. "start sprint".
+	Name    string
+	Handler CommandFunc
+	// Description and Spec describe the command's slash command. Without a spec the slash command takes
+	// whatever text the user gives it.
+	Description string
+	Spec        *ArgSpec
+	// Permission is who can use the command unless a server grants it to more of its members, Everyone by default.
+	// The bot checks it before running the handler.
+	Permission Permission
+	// Cooldown is how long the command waits after it's used before it can be used again, unless a server changes
+	// it. Moderators never wait.
+	Cooldown Cooldown
+	// Rerun is set for commands that only reply, like roll, so fixing a message that used one runs it again and
+	// changes its replies. Editing a message never runs other commands, as that would do what they do twice.
+	Rerun bool
+}
+
+// Commander is implemented by plugins that register their commands with the bot.
+// The bot routes each message to the registered command with the longest matching name, and when a command matches
+// no other plugin sees the message. Plugins that don't implement Commander have every other message passed to Message.
+type Commander interface {
+	Commands() []Command
+}
+
+type routedCommand struct {
+	name        string
+	plugin      Plugin
+	handler     CommandFunc
+	description string
+	spec        *ArgSpec
+	permission  Permission
+	cooldown    Cooldown
+	rerun       bool
+}
+
+// router finds the one command a message runs.
+type router struct {
+	commands map[string]routedCommand
+	// Commands ordered longest first, so "quote me" is tried before "quote".
+	ordered []routedCommand
+}
+
+func newRouter() *router {
+	return &router{
+		commands: map[string]routedCommand{},
+	}
+}
+
+// normalizeCommand lowercases a command name and collapses its whitespace, since that's how messages are matched.
+func normalizeCommand(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// conflicts returns an error describing every command that's already registered by another plugin.
+func (r *router) conflicts(plugin Plugin, commands []Command) error {
+	problems := []string{}
+	seen := map[string]bool{}
+	for _, c := range commands {
+		name := normalizeCommand(c.Name)
+		switch {
+		case name == "":
+			problems = append(problems, "a command has no name")
+		case seen[name]:
+			problems = append(problems, fmt.Sprintf("%q is registered twice", name))
+		case r.commands[name].plugin != nil && r.commands[name].plugin.Name() != plugin.Name():
+			problems = append(problems, fmt.Sprintf("%q is already registered by %s", name, r.commands[name].plugin.Name()))
+		}
+		seen[name] = true
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("command conflict: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// add registers a plugin's commands, replacing any commands registered by a plugin with the same name.
+func (r *router) add(plugin Plugin, commands []Command) error {
+	if err := r.conflicts(plugin, commands); err != nil {
+		return err
+	}
+	r.remove(plugin.Name())
+	for _, c := range commands {
+		name := normalizeCommand(c.Name)
+		r.commands[name] = routedCommand{
+			name:        name,
+			plugin:      plugin,
+			handler:     c.Handler,
+			description: c.Description,
+			spec:        c.Spec,
+			permission:  c.Permission,
+			cooldown:    c.Cooldown,
+			rerun:       c.Rerun,
+		}
+	}
+	r.order()
+	return nil
+}
+
+// remove unregisters every command registered by the named plugin.
+func (r *router) remove(pluginName string) {
+	for name, c := range r.commands {
+		if c.plugin.Name() == pluginName {
+			delete(r.commands, name)
+		}
+	}
+	r.order()
+}
+
+func (r *router) order() {
+	r.ordered = make([]routedCommand, 0, len(r.commands))
+	for _, c := range r.commands {
+		r.ordered = append(r.ordered, c)
+	}
+	sort.Slice(r.ordered, func(i, j int) bool {
+		if len(r.ordered[i].name) != len(r.ordered[j].name) {
+			return len(r.ordered[i].name) > len(r.ordered[j].name)
+		}
+		return r.ordered[i].name < r.ordered[j].name
+	})
+}
+
+// match returns the registered command with the longest name that matches a message.
+func (r *router) match(service Service, message Message) (routedCommand, bool) {
+	if service.IsMe(message) {
+		return routedCommand{}, false
+	}
+	for _, c := range r.ordered {
+		if MatchesCommand(service, c.name, message) {
+			return c, true
+		}
+	}
+	return routedCommand{}, false
+}
@@ -0,0 +1,214 @@
+package mmmorty
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// The option given to slash commands without an argument spec, which is passed along as is.
+	slashTextOption = "text"
+	// The interaction type of a slash command being used.
+	interactionTypeCommand = 2
+	// The longest description Discord accepts.
+	maxSlashDescription = 100
+)
+
+var slashNameRegex = regexp.MustCompile(`^[\w-]{1,32}$`)
+
+// SlashCommandOption is an option of a slash command, one for each argument of the command's spec.
+type SlashCommandOption struct {
+	Name        string
+	Description string
+	Integer     bool
+	Required    bool
+}
+
+// SlashCommand is a slash command generated from a routed command, eg. "start sprint" becomes /start-sprint.
+type SlashCommand struct {
+	Name        string
+	Description string
+	Options     []SlashCommandOption
+
+	command string
+	spec    *ArgSpec
+}
+
+// SlashCommander is implemented by services that support slash commands.
+// The bot registers a slash command for every routed command when it opens the service.
+type SlashCommander interface {
+	RegisterSlashCommands(commands []SlashCommand) error
+}
+
+// EphemeralSender is implemented by services that can send a reply only the person who used a command can see.
+type EphemeralSender interface {
+	SendEphemeralMessage(channel, message string) error
+}
+
+// SendEphemeral sends a reply only the person who used a command can see if the service can,
+// and sends it to the channel like any other message otherwise.
+func SendEphemeral(service Service, channel, message string) error {
+	if e, ok := service.(EphemeralSender); ok {
+		return e.SendEphemeralMessage(channel, message)
+	}
+	return service.SendMessage(channel, message)
+}
+
+// Replier is implemented by messages that are answered some other way than by sending to their channel,
+// such as slash commands. Plugins use the service ReplyService returns while they handle the message.
+type Replier interface {
+	ReplyService(service Service) Service
+}
+
+// Answerer is implemented by reply services for messages that must be answered, such as slash commands, which
+// Discord shows as failed if nothing answers them. Once the message has been handled the bot calls FinishAnswer,
+// which sends the reply if nothing else was sent in answer.
+type Answerer interface {
+	FinishAnswer(reply string) error
+}
+
+// newSlashCommand returns the slash command for a routed command, or false if it can't be a slash command.
+func newSlashCommand(c routedCommand) (SlashCommand, bool) {
+	s := SlashCommand{
+		Name:        strings.Join(strings.Fields(c.name), "-"),
+		Description: c.description,
+		command:     c.name,
+		spec:        c.spec,
+	}
+	if !slashNameRegex.MatchString(s.Name) {
+		return SlashCommand{}, false
+	}
+	if s.Description == "" {
+		s.Description = c.name
+	}
+	if len(s.Description) > maxSlashDescription {
+		s.Description = s.Description[:maxSlashDescription-3] + "..."
+	}
+
+	if s.spec == nil {
+		s.Options = []SlashCommandOption{{
+			Name:        slashTextOption,
+			Description: "what to say after " + c.name,
+		}}
+		return s, true
+	}
+	for _, t := range s.spec.tokens {
+		if t.literal != "" {
+			continue
+		}
+		if !slashNameRegex.MatchString(t.name) {
+			return SlashCommand{}, false
+		}
+		description := t.name
+		if t.ranged {
+			description = fmt.Sprintf("%s, from %d to %d", t.name, t.min, t.max)
+		}
+		s.Options = append(s.Options, SlashCommandOption{
+			Name:        strings.ToLower(t.name),
+			Description: description,
+			Integer:     t.kind == argInt,
+			Required:    true,
+		})
+	}
+	return s, true
+}
+
+// Content returns the message an interaction with the slash command stands for, eg. "roll 3d6" for /roll text:3d6.
+func (s SlashCommand) Content(i *Interaction) string {
+	values := map[string]string{}
+	for _, o := range i.Data.Options {
+		values[strings.ToLower(o.Name)] = strings.TrimSpace(fmt.Sprint(o.Value))
+	}
+
+	if s.spec == nil {
+		return strings.TrimSpace(s.command + " " + values[slashTextOption])
+	}
+	words := []string{s.spec.Name}
+	for _, t := range s.spec.tokens {
+		if t.literal != "" {
+			words = append(words, t.literal)
+		} else {
+			words = append(words, values[strings.ToLower(t.name)])
+		}
+	}
+	return strings.Join(strings.Fields(strings.Join(words, " ")), " ")
+}
+
+// FindSlashCommand returns the slash command with the given name.
+func FindSlashCommand(commands []SlashCommand, name string) (SlashCommand, bool) {
+	for _, c := range commands {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return SlashCommand{}, false
+}
+
+// InteractionUser is the user who used a slash command.
+type InteractionUser struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Avatar   string `json:"avatar"`
+}
+
+// InteractionOption is the value given for one option of a slash command.
+type InteractionOption struct {
+	Name  string      `json:"name"`
+	Type  int         `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// Interaction is a slash command someone used, as Discord sends it.
+type Interaction struct {
+	ID            string `json:"id"`
+	ApplicationID string `json:"application_id"`
+	Type          int    `json:"type"`
+	Token         string `json:"token"`
+	GuildID       string `json:"guild_id"`
+	ChannelID     string `json:"channel_id"`
+	// Member is set for commands used in a guild, and User for commands used in private.
+	Member *struct {
+		User *InteractionUser `json:"user"`
+	} `json:"member"`
+	User *InteractionUser `json:"user"`
+	Data struct {
+		Name    string              `json:"name"`
+		Options []InteractionOption `json:"options"`
+	} `json:"data"`
+}
+
+// ParseInteraction parses an interaction payload. Interactions other than slash commands return an error.
+func ParseInteraction(data []byte) (*Interaction, error) {
+	i := &Interaction{}
+	if err := json.Unmarshal(data, i); err != nil {
+		return nil, err
+	}
+	if i.Type != interactionTypeCommand {
+		return nil, fmt.Errorf("interaction %s is type %d, not a slash command", i.ID, i.Type)
+	}
+	if i.Author() == nil {
+		return nil, fmt.Errorf("interaction %s has no user", i.ID)
+	}
+	return i, nil
+}
+
+// Author returns the user who used the slash command.
+func (i *Interaction) Author() *InteractionUser {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User
+	}
+	return i.User
+}
+
+// SlashCommands returns the slash commands for every command routed on a service.
+func (b *Bot) SlashCommands(service Service) []SlashCommand {
+	commands := []SlashCommand{}
+	for _, c := range b.Services[service.Name()].router.ordered {
+		if s, ok := newSlashCommand(c); ok {
+			commands = append(commands, s)
+		}
+	}
+	return commands
+}
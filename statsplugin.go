@@ -0,0 +1,108 @@
+package mmmorty
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const statsCommand = "stats"
+
+type statsPlugin struct {
+}
+
+// Name returns the name of the plugin.
+func (p *statsPlugin) Name() string {
+	return "Stats"
+}
+
+// Help returns a list of help strings that are printed when the user requests them.
+func (p *statsPlugin) Help(bot *Bot, service Service, message Message, detailed bool) []string {
+	if detailed {
+		return nil
+	}
+	return CommandHelp(service, statsCommand, "", Text(service, nil, "stats.help", nil))
+}
+
+// Commands returns the commands this plugin handles.
+func (p *statsPlugin) Commands() []Command {
+	return []Command{
+		{
+			Name:        statsCommand,
+			Handler:     p.handleStats,
+			Description: "shows how Morty is doing",
+			Cooldown:    Cooldown{Channel: 30 * time.Second},
+			Rerun:       true,
+		},
+	}
+}
+
+func (p *statsPlugin) Message(bot *Bot, service Service, message Message) {
+}
+
+// shardsConnected returns how many of a service's shards are connected, and how many it has.
+// Services without shards count as one shard that's always connected.
+func shardsConnected(bot *Bot, service Service) (int, int) {
+	r, ok := bot.Services[service.Name()].Service.(ShardReporter)
+	if !ok {
+		return 1, 1
+	}
+	connected := 0
+	shards := r.ShardHealth()
+	for _, shard := range shards {
+		if shard.Connected {
+			connected++
+		}
+	}
+	return connected, len(shards)
+}
+
+func (p *statsPlugin) handleStats(bot *Bot, service Service, message Message) {
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+	connected, shards := shardsConnected(bot, service)
+
+	lines := []string{
+		Text(service, nil, "stats.version", Vars{"version": VersionString, "uptime": bot.Uptime().String()}),
+		Text(service, nil, "stats.guilds", Vars{"guilds": service.ChannelCount(), "connected": connected, "shards": shards}),
+		Text(service, nil, "stats.memory", Vars{
+			"memory":     fmt.Sprintf("%.1f", float64(memory.Alloc)/1024/1024),
+			"goroutines": runtime.NumGoroutine(),
+		}),
+	}
+
+	// The plugin handling this message is already locked, so other plugins are only locked while they report.
+	for _, plugin := range bot.sortedPlugins(service) {
+		s, ok := plugin.(StatsProvider)
+		if !ok || plugin == Plugin(p) || !bot.PluginEnabled(service, plugin) {
+			continue
+		}
+		bot.LockPlugin(plugin)
+		lines = append(lines, s.Stats(bot, service, message)...)
+		bot.UnlockPlugin(plugin)
+	}
+
+	SendEphemeral(service, message.Channel(), strings.Join(lines, "\n"))
+}
+
+// Load will load plugin state from a byte array.
+func (p *statsPlugin) Load(bot *Bot, service Service, data []byte) error {
+	if data != nil {
+		if err := json.Unmarshal(data, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save will save plugin state to a byte array.
+func (p *statsPlugin) Save() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// NewStatsPlugin will create a new plugin that shows how the bot is doing.
+func NewStatsPlugin() Plugin {
+	return &statsPlugin{}
+}
@@ -0,0 +1,191 @@
+package mmmorty
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The layout used to timestamp backups, sorts chronologically.
+const backupTimeLayout = "20060102T150405.000000000"
+
+// Store is a storage backend for plugin data, keyed by service and plugin name.
+type Store interface {
+	// Load returns the data saved for a plugin, or nil if nothing has been saved yet.
+	Load(service, plugin string) ([]byte, error)
+	// Save stores the data for a plugin.
+	Save(service, plugin string, data []byte) error
+	// Close releases any resources held by the store.
+	Close() error
+}
+
+// BackupStore is a Store that keeps previous versions of plugin data.
+type BackupStore interface {
+	Store
+	// LoadBackups returns the previous versions of a plugin's data, newest first.
+	LoadBackups(service, plugin string) ([][]byte, error)
+}
+
+// FileStore is a Store that keeps each plugin's data in its own file, at <Dir>/<service>/<plugin>.
+// Files are replaced atomically, and the previous Backups versions of each file are kept in <Dir>/<service>/backups.
+type FileStore struct {
+	Dir     string
+	Backups int
+}
+
+// NewFileStore creates a new file store rooted at dir that keeps 5 backups of each plugin.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{
+		Dir:     dir,
+		Backups: 5,
+	}
+}
+
+func (s *FileStore) path(service, plugin string) string {
+	return filepath.Join(s.Dir, service, plugin)
+}
+
+func (s *FileStore) backupDir(service string) string {
+	return filepath.Join(s.Dir, service, "backups")
+}
+
+// backups returns the paths of the backups for a plugin, oldest first.
+func (s *FileStore) backups(service, plugin string) ([]string, error) {
+	files, err := ioutil.ReadDir(s.backupDir(service))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, f := range files {
+		if !f.IsDir() && strings.HasPrefix(f.Name(), plugin+".") {
+			paths = append(paths, filepath.Join(s.backupDir(service), f.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Load returns the data saved for a plugin, or nil if nothing has been saved yet.
+func (s *FileStore) Load(service, plugin string) ([]byte, error) {
+	data, err := ioutil.ReadFile(s.path(service, plugin))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// LoadBackups returns the previous versions of a plugin's data, newest first.
+func (s *FileStore) LoadBackups(service, plugin string) ([][]byte, error) {
+	paths, err := s.backups(service, plugin)
+	if err != nil {
+		return nil, err
+	}
+
+	data := [][]byte{}
+	for i := len(paths) - 1; i >= 0; i-- {
+		if d, err := ioutil.ReadFile(paths[i]); err == nil {
+			data = append(data, d)
+		}
+	}
+	return data, nil
+}
+
+// Save stores the data for a plugin.
+// If the data changed, the current file is kept as a backup and the oldest backups are removed.
+func (s *FileStore) Save(service, plugin string, data []byte) error {
+	if err := os.MkdirAll(filepath.Join(s.Dir, service), 0755); err != nil {
+		return err
+	}
+
+	path := s.path(service, plugin)
+	if s.Backups > 0 {
+		if old, err := ioutil.ReadFile(path); err == nil && !bytes.Equal(old, data) {
+			if err := s.backup(service, plugin, path, old); err != nil {
+				return err
+			}
+		}
+	}
+
+	return writeFileAtomic(path, data)
+}
+
+func (s *FileStore) backup(service, plugin, path string, data []byte) error {
+	if err := os.MkdirAll(s.backupDir(service), 0755); err != nil {
+		return err
+	}
+
+	backup := filepath.Join(s.backupDir(service), plugin+"."+time.Now().UTC().Format(backupTimeLayout))
+	// Hard link the current file so it is never missing, fall back to copying it.
+	if err := os.Link(path, backup); err != nil {
+		if err := writeFileAtomic(backup, data); err != nil {
+			return err
+		}
+	}
+
+	paths, err := s.backups(service, plugin)
+	if err != nil {
+		return err
+	}
+	for len(paths) > s.Backups {
+		os.Remove(paths[0])
+		paths = paths[1:]
+	}
+	return nil
+}
+
+// Close releases any resources held by the store.
+func (s *FileStore) Close() error {
+	return nil
+}
+
+// rename is os.Rename, tests replace it to interrupt writes.
+var rename = os.Rename
+
+// writeFileAtomic writes data to a temporary file, syncs it to disk and renames it over path,
+// so path always holds either the old or the new data even if the process crashes.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Chmod(tmp, 0644); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	// Sync the directory so the rename itself survives a crash.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
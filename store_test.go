@@ -0,0 +1,129 @@
+package mmmorty
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// notesPlugin is a plugin with some JSON data.
+type notesPlugin struct {
+	Notes map[string]string
+	Count int
+}
+
+func (p *notesPlugin) Name() string                               { return "Notes" }
+func (p *notesPlugin) Save() ([]byte, error)                      { return json.Marshal(p) }
+func (p *notesPlugin) Help(*Bot, Service, Message, bool) []string { return nil }
+func (p *notesPlugin) Message(*Bot, Service, Message)             {}
+func (p *notesPlugin) Load(bot *Bot, service Service, data []byte) error {
+	if data != nil {
+		return json.Unmarshal(data, p)
+	}
+	return nil
+}
+
+// namedService is a service that only has a name, which is all storing plugins needs.
+type namedService struct {
+	Service
+}
+
+func (namedService) Name() string { return "Test" }
+
+func newStoreBot(t *testing.T) (*Bot, *FileStore) {
+	t.Helper()
+	store := NewFileStore(t.TempDir())
+	bot := NewBot()
+	bot.Store = store
+	bot.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	return bot, store
+}
+
+func mustSave(t *testing.T, store *FileStore, data string) {
+	t.Helper()
+	if err := store.Save("Test", "Notes", []byte(data)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadFallsBackToNewestGoodBackup(t *testing.T) {
+	for name, corrupt := range map[string]string{
+		"truncated": `{"Notes":{"c":"3"`,
+		"garbage":   "\x00\x00\x00",
+		"empty":     "",
+	} {
+		t.Run(name, func(t *testing.T) {
+			bot, store := newStoreBot(t)
+			mustSave(t, store, `{"Notes":{"a":"1"},"Count":1}`)
+			mustSave(t, store, `{"Notes":{"b":"2"},"Count":2}`)
+			mustSave(t, store, corrupt)
+
+			p := &notesPlugin{}
+			bot.loadPlugin(namedService{}, p)
+			if expected := (&notesPlugin{Notes: map[string]string{"b": "2"}, Count: 2}); !reflect.DeepEqual(p, expected) {
+				t.Errorf("loaded %+v, expected the newest backup %+v", p, expected)
+			}
+		})
+	}
+}
+
+func TestLoadDoesNotMixPartlyDecodedDataIntoBackup(t *testing.T) {
+	bot, store := newStoreBot(t)
+	mustSave(t, store, `{"Notes":{"b":"2"},"Count":2}`)
+	// Notes decodes, then Count doesn't.
+	mustSave(t, store, `{"Notes":{"x":"stale"},"Count":"lots"}`)
+
+	p := &notesPlugin{}
+	bot.loadPlugin(namedService{}, p)
+	if expected := (&notesPlugin{Notes: map[string]string{"b": "2"}, Count: 2}); !reflect.DeepEqual(p, expected) {
+		t.Errorf("loaded %+v, expected only the backup %+v", p, expected)
+	}
+}
+
+func TestSaveKeepsNewestBackups(t *testing.T) {
+	_, store := newStoreBot(t)
+	store.Backups = 2
+	for _, data := range []string{"1", "2", "2", "3", "4", "5"} {
+		mustSave(t, store, data)
+	}
+
+	backups, err := store.LoadBackups("Test", "Notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, b := range backups {
+		got = append(got, string(b))
+	}
+	if expected := []string{"4", "3"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("got backups %v, expected %v", got, expected)
+	}
+	if data, _ := store.Load("Test", "Notes"); string(data) != "5" {
+		t.Errorf("loaded %q, expected %q", data, "5")
+	}
+}
+
+func TestInterruptedSaveKeepsOldFile(t *testing.T) {
+	_, store := newStoreBot(t)
+	store.Backups = 0
+	mustSave(t, store, "old")
+
+	rename = func(string, string) error { return errors.New("interrupted") }
+	defer func() { rename = os.Rename }()
+	if err := store.Save("Test", "Notes", []byte("new")); err == nil {
+		t.Fatal("saved while writes were interrupted")
+	}
+
+	if data, _ := store.Load("Test", "Notes"); string(data) != "old" {
+		t.Errorf("loaded %q after an interrupted save, expected %q", data, "old")
+	}
+	files, _ := filepath.Glob(filepath.Join(store.Dir, "Test", "*"))
+	if len(files) != 1 {
+		t.Errorf("got files %v after an interrupted save, expected just the old one", files)
+	}
+}
@@ -0,0 +1,255 @@
+package mmmorty
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const (
+	pluginsCommand     = "plugins"
+	enableCommand      = "enable"
+	disableCommand     = "disable"
+	enableHereCommand  = "enable here"
+	disableHereCommand = "disable here"
+)
+
+var (
+	enableSpec      = MustParseArgSpec("enable <plugin>")
+	disableSpec     = MustParseArgSpec("disable <plugin>")
+	enableHereSpec  = MustParseArgSpec("enable here <plugin>")
+	disableHereSpec = MustParseArgSpec("disable here <plugin>")
+)
+
+// pluginToggles are whether plugins are on, by plugin name.
+type pluginToggles map[string]bool
+
+type guildToggles struct {
+	Plugins  pluginToggles            `json:"plugins"`
+	Channels map[string]pluginToggles `json:"channels"` // map of channel ID to the plugins turned on or off there
+}
+
+type togglePlugin struct {
+	Guilds map[string]*guildToggles `json:"guilds"` // map of guild ID to the plugins turned on or off there
+}
+
+// Name returns the name of the plugin.
+func (p *togglePlugin) Name() string {
+	return "Toggle"
+}
+
+// Help returns a list of help strings that are printed when the user requests them.
+func (p *togglePlugin) Help(bot *Bot, service Service, message Message, detailed bool) []string {
+	if detailed || service.IsPrivate(message) || !service.IsModerator(message) {
+		return nil
+	}
+	help := CommandHelp(service, pluginsCommand, "", Text(service, nil, "toggle.help.list", nil))
+	help = append(help, CommandHelp(service, enableCommand, enableSpec.Arguments(), Text(service, nil, "toggle.help.enable", nil))...)
+	help = append(help, CommandHelp(service, disableCommand, disableSpec.Arguments(), Text(service, nil, "toggle.help.disable", nil))...)
+	help = append(help, CommandHelp(service, enableHereCommand, enableHereSpec.Arguments(), Text(service, nil, "toggle.help.enable.here", nil))...)
+	return append(help, CommandHelp(service, disableHereCommand, disableHereSpec.Arguments(), Text(service, nil, "toggle.help.disable.here", nil))...)
+}
+
+// Commands returns the commands this plugin handles.
+func (p *togglePlugin) Commands() []Command {
+	return []Command{
+		{
+			Name:        pluginsCommand,
+			Handler:     p.handleList,
+			Description: "lists which plugins are on in this channel",
+			Permission:  Moderator,
+			Rerun:       true,
+		},
+		{
+			Name:        enableCommand,
+			Handler:     p.handleToggle(enableSpec, true, false),
+			Description: "turns a plugin on across this server",
+			Spec:        enableSpec,
+			Permission:  Moderator,
+		},
+		{
+			Name:        disableCommand,
+			Handler:     p.handleToggle(disableSpec, false, false),
+			Description: "turns a plugin off across this server",
+			Spec:        disableSpec,
+			Permission:  Moderator,
+		},
+		{
+			Name:        enableHereCommand,
+			Handler:     p.handleToggle(enableHereSpec, true, true),
+			Description: "turns a plugin on in this channel",
+			Spec:        enableHereSpec,
+			Permission:  Moderator,
+		},
+		{
+			Name:        disableHereCommand,
+			Handler:     p.handleToggle(disableHereSpec, false, true),
+			Description: "turns a plugin off in this channel",
+			Spec:        disableHereSpec,
+			Permission:  Moderator,
+		},
+	}
+}
+
+func (p *togglePlugin) Message(bot *Bot, service Service, message Message) {
+}
+
+// channelPlugins returns the plugins turned on or off in a channel, whether for the channel or its whole guild.
+func (p *togglePlugin) channelPlugins(guildID, channelID string) map[string]bool {
+	g := p.Guilds[guildID]
+	if g == nil {
+		return nil
+	}
+	plugins := map[string]bool{}
+	for name, enabled := range g.Plugins {
+		plugins[name] = enabled
+	}
+	for name, enabled := range g.Channels[channelID] {
+		plugins[name] = enabled
+	}
+	return plugins
+}
+
+// togglePlugins returns the plugins on a service that servers can turn on and off, ordered by name.
+func togglePlugins(bot *Bot, service Service) []Plugin {
+	plugins := []Plugin{}
+	for _, plugin := range bot.sortedPlugins(service) {
+		if !bot.Services[service.Name()].core[plugin.Name()] {
+			plugins = append(plugins, plugin)
+		}
+	}
+	return plugins
+}
+
+// pluginList returns the names of plugins as they're written in commands, eg. "`dice`, `war`".
+func pluginList(plugins []Plugin) string {
+	names := make([]string, len(plugins))
+	for i, plugin := range plugins {
+		names[i] = "`" + strings.ToLower(plugin.Name()) + "`"
+	}
+	return strings.Join(names, ", ")
+}
+
+func (p *togglePlugin) handleList(bot *Bot, service Service, message Message) {
+	guildID, ok := settingGuildID(service, message, "toggle.private")
+	if !ok {
+		return
+	}
+
+	lines := []string{Text(service, message, "toggle.list", nil)}
+	for _, plugin := range togglePlugins(bot, service) {
+		id := "toggle.off"
+		if bot.PluginEnabled(service, plugin) {
+			id = "toggle.on"
+		}
+		if g := p.Guilds[guildID]; g != nil {
+			if _, ok := g.Channels[message.Channel()][plugin.Name()]; ok {
+				id += ".here"
+			}
+		}
+		lines = append(lines, Text(service, nil, id, Vars{"plugin": strings.ToLower(plugin.Name())}))
+	}
+	SendEphemeral(service, message.Channel(), strings.Join(lines, "\n"))
+}
+
+// handleToggle returns a handler that turns a plugin on or off across a guild, or just in the channel if here.
+// Turning a plugin on or off across a guild also forgets whether it was on or off in each of its channels.
+func (p *togglePlugin) handleToggle(spec *ArgSpec, enabled, here bool) CommandFunc {
+	return func(bot *Bot, service Service, message Message) {
+		guildID, ok := settingGuildID(service, message, "toggle.private")
+		if !ok {
+			return
+		}
+
+		args, err := spec.Parse(service, message)
+		if err != nil {
+			SendEphemeral(service, message.Channel(), spec.UsageReply(service, message, err))
+			return
+		}
+
+		name := args.String("plugin")
+		var plugin Plugin
+		for _, candidate := range bot.sortedPlugins(service) {
+			if strings.EqualFold(candidate.Name(), name) {
+				plugin = candidate
+			}
+		}
+		if plugin == nil {
+			reply := Text(service, message, "toggle.unknown", Vars{"plugin": name, "plugins": pluginList(togglePlugins(bot, service))})
+			SendEphemeral(service, message.Channel(), reply)
+			return
+		}
+		name = plugin.Name()
+		if bot.Services[service.Name()].core[name] {
+			SendEphemeral(service, message.Channel(), Text(service, message, "toggle.core", Vars{"plugin": strings.ToLower(name)}))
+			return
+		}
+
+		if p.Guilds == nil {
+			p.Guilds = map[string]*guildToggles{}
+		}
+		g := p.Guilds[guildID]
+		if g == nil {
+			g = &guildToggles{Plugins: pluginToggles{}, Channels: map[string]pluginToggles{}}
+			p.Guilds[guildID] = g
+		}
+
+		if here {
+			if g.Channels[message.Channel()] == nil {
+				g.Channels[message.Channel()] = pluginToggles{}
+			}
+			g.Channels[message.Channel()][name] = enabled
+		} else {
+			g.Plugins[name] = enabled
+			for channelID, plugins := range g.Channels {
+				delete(plugins, name)
+				if len(plugins) == 0 {
+					delete(g.Channels, channelID)
+				}
+			}
+		}
+
+		id := "toggle.disabled"
+		if enabled {
+			id = "toggle.enabled"
+		}
+		if here {
+			id += ".here"
+		}
+		reply := Text(service, message, id, Vars{"plugin": strings.ToLower(name)})
+		service.SendMessage(message.Channel(), reply)
+	}
+}
+
+// Load will load plugin state from a byte array.
+func (p *togglePlugin) Load(bot *Bot, service Service, data []byte) error {
+	if data != nil {
+		if err := json.Unmarshal(data, p); err != nil {
+			return err
+		}
+	}
+	for guildID, g := range p.Guilds {
+		if g == nil {
+			delete(p.Guilds, guildID)
+			continue
+		}
+		if g.Plugins == nil {
+			g.Plugins = pluginToggles{}
+		}
+		if g.Channels == nil {
+			g.Channels = map[string]pluginToggles{}
+		}
+	}
+	return nil
+}
+
+// Save will save plugin state to a byte array.
+func (p *togglePlugin) Save() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// NewTogglePlugin will create a new plugin that turns other plugins on and off per server and channel.
+func NewTogglePlugin() Plugin {
+	return &togglePlugin{
+		Guilds: map[string]*guildToggles{},
+	}
+}
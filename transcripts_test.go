@@ -0,0 +1,55 @@
+package mmmorty_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/todd-beckman/mmmorty"
+	"github.com/todd-beckman/mmmorty/colorplugin"
+	"github.com/todd-beckman/mmmorty/diceplugin"
+	"github.com/todd-beckman/mmmorty/evalplugin"
+	"github.com/todd-beckman/mmmorty/mmmortytest"
+	"github.com/todd-beckman/mmmorty/pickplugin"
+	"github.com/todd-beckman/mmmorty/promptplugin"
+	"github.com/todd-beckman/mmmorty/quoteplugin"
+	"github.com/todd-beckman/mmmorty/roleplugin"
+	"github.com/todd-beckman/mmmorty/warplugin"
+	"github.com/todd-beckman/mmmorty/wordplugin"
+)
+
+// plugins creates a fresh instance of every bundled plugin so transcripts don't share state.
+func plugins() []mmmorty.Plugin {
+	return []mmmorty.Plugin{
+		mmmorty.NewCommandPlugin(),
+		colorplugin.New(),
+		diceplugin.New(),
+		evalplugin.New(),
+		pickplugin.New(),
+		promptplugin.New(),
+		quoteplugin.New(),
+		roleplugin.New(),
+		warplugin.New(),
+		wordplugin.New(),
+	}
+}
+
+// TestTranscripts replays every transcript under testdata/transcripts through the bundled plugins.
+// Use -run TestTranscripts/quote.txt to replay one.
+func TestTranscripts(t *testing.T) {
+	transcripts, err := mmmortytest.LoadTranscripts("testdata/transcripts/*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transcripts) == 0 {
+		t.Fatal("no transcripts in testdata/transcripts")
+	}
+
+	for _, transcript := range transcripts {
+		transcript := transcript
+		t.Run(filepath.Base(transcript.Name), func(t *testing.T) {
+			if err := transcript.Run(plugins()...); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package warplugin
+
+import "github.com/todd-beckman/mmmorty"
+
+func init() {
+	mmmorty.RegisterMessages("en", map[string]string{
+		"sprint.help.id":       "ID",
+		"sprint.help.start":    "starts a sprint when the minute hand points to <minute>, lasting for <minutes> minutes",
+		"sprint.help.end":      "Ends the sprint with the given name.",
+		"sprint.help.join":     "Adds you to the list of people to notify for the given sprint.",
+		"sprint.help.leave":    "Removes you from the list of people to notify for the given sprint.",
+		"sprint.help.thing":    "Shorthand for \"start sprint for 15\" starting in 4 minutes.",
+		"sprint.private":       "Uh, {user}, I can't start sprints privately.",
+		"sprint.join.missing":  "Uh, {user}, what was the sprint you wanted to join?",
+		"sprint.leave.missing": "Uh, {user}, what was the sprint you wanted to leave?",
+		"sprint.end.missing":   "Uh, {user}, what was the sprint you wanted to end?",
+		"sprint.unknown":       "Uh, {user}, I don't see a sprint by that name.",
+		"sprint.join.already":  "Looks like you are in that sprint already {user}. You should be good to go.",
+		"sprint.joined":        "I added you to the sprint, {user}. Good luck!",
+		"sprint.leave.missed":  "Uh, {user}, you are not in that sprint.",
+		"sprint.left":          "I removed you from {name}.",
+		"sprint.ended":         "Sprint {name} was ended.",
+		"sprint.created":       "Ok, {user}, you got it! I added you to this sprint. Use `{join}` to get updates, `{leave}` to stop getting them, and `{end}` to cancel this sprint.",
+		"sprint.alert.one":     "Sprint {name} is starting in one minute, when it will go for {count} minute! {sprinters}",
+		"sprint.alert.other":   "Sprint {name} is starting in one minute, when it will go for {count} minutes! {sprinters}",
+		"sprint.start.one":     "Sprint {name} starts now and goes for {count} minute! {sprinters}",
+		"sprint.start.other":   "Sprint {name} starts now and goes for {count} minutes! {sprinters}",
+		"sprint.end":           "Sprint {name} has ended! {sprinters}",
+		"sprint.stats":         "Sprints running here: {count}",
+		"sprint.full":          "Uh, {user}, that's a lot of sprints at once. Can we finish one before starting another?",
+	})
+
+	mmmorty.RegisterMessages("es", map[string]string{
+		"sprint.help.id":       "ID",
+		"sprint.help.start":    "empieza un sprint cuando el minutero marque <minute>, que dura <minutes> minutos",
+		"sprint.help.end":      "Termina el sprint con ese nombre.",
+		"sprint.help.join":     "Te añade a la lista de gente a la que aviso de ese sprint.",
+		"sprint.help.leave":    "Te quita de la lista de gente a la que aviso de ese sprint.",
+		"sprint.help.thing":    "Atajo para \"start sprint for 15\" que empieza en 4 minutos.",
+		"sprint.private":       "Eh, {user}, no puedo empezar sprints en privado.",
+		"sprint.join.missing":  "Eh, {user}, ¿a qué sprint te querías unir?",
+		"sprint.leave.missing": "Eh, {user}, ¿qué sprint querías dejar?",
+		"sprint.end.missing":   "Eh, {user}, ¿qué sprint querías terminar?",
+		"sprint.unknown":       "Eh, {user}, no veo ningún sprint con ese nombre.",
+		"sprint.join.already":  "Parece que ya estás en ese sprint, {user}. Todo listo.",
+		"sprint.joined":        "Te añadí al sprint, {user}. ¡Suerte!",
+		"sprint.leave.missed":  "Eh, {user}, no estás en ese sprint.",
+		"sprint.left":          "Te quité de {name}.",
+		"sprint.ended":         "El sprint {name} terminó.",
+		"sprint.created":       "Vale, {user}, ¡hecho! Te añadí a este sprint. Usa `{join}` para recibir avisos, `{leave}` para dejar de recibirlos y `{end}` para cancelar este sprint.",
+		"sprint.alert.one":     "¡El sprint {name} empieza en un minuto y durará {count} minuto! {sprinters}",
+		"sprint.alert.other":   "¡El sprint {name} empieza en un minuto y durará {count} minutos! {sprinters}",
+		"sprint.start.one":     "¡El sprint {name} empieza ya y dura {count} minuto! {sprinters}",
+		"sprint.start.other":   "¡El sprint {name} empieza ya y dura {count} minutos! {sprinters}",
+		"sprint.end":           "¡El sprint {name} ha terminado! {sprinters}",
+		"sprint.stats":         "Sprints en marcha aquí: {count}",
+		"sprint.full":          "Eh, {user}, son muchos sprints a la vez. ¿Podemos terminar uno antes de empezar otro?",
+	})
+
+	mmmorty.RequirePlaceholders(map[string][]string{
+		"sprint.alert": {"sprinters"},
+		"sprint.start": {"sprinters"},
+		"sprint.end":   {"sprinters"},
+	})
+
+	mmmorty.RegisterSamples(map[string]mmmorty.Vars{
+		"sprint": {
+			"count":     15,
+			"end":       "end 42",
+			"join":      "join 42",
+			"leave":     "leave 42",
+			"name":      "42",
+			"sprinters": "@Summer @Jerry",
+		},
+	})
+}
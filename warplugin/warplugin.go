@@ -3,9 +3,8 @@ package warplugin
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"math/rand"
-	"strconv"
+	"sort"
 	"strings"
 	"time"
 
@@ -18,7 +17,12 @@ const (
 	joinWarCommand  = "join"
 	leaveWarCommand = "leave"
 	doTheThing      = "do the thing"
-	maxWarCount     = 10
+	maxWarCount     = 10 // the default limit, which the bot's configuration can change
+)
+
+var (
+	startWarAtSpec  = mmmorty.MustParseArgSpec("start sprint at <minute:minute> for <minutes:int 1-180>")
+	startWarForSpec = mmmorty.MustParseArgSpec("start sprint for <minutes:int 1-180> at <minute:minute>")
 )
 
 // War is a timed sprint with users subscribed to the start and end alerts
@@ -31,9 +35,18 @@ type War struct {
 	Start     int64    `json:"start"`     // Unix time, the number of seconds elapsed since January 1, 1970 UTC.
 
 	// private
-	alertTimer *time.Timer
-	startTimer *time.Timer
-	endTimer   *time.Timer
+	alertTimer mmmorty.Timer
+	startTimer mmmorty.Timer
+	endTimer   mmmorty.Timer
+}
+
+// stop stops the sprint's alerts. Sprints loaded from a save don't have any.
+func (w *War) stop() {
+	for _, timer := range []mmmorty.Timer{w.alertTimer, w.startTimer, w.endTimer} {
+		if timer != nil {
+			timer.Stop()
+		}
+	}
 }
 
 // WarPlugin is this plugin's save structure
@@ -42,35 +55,35 @@ type WarPlugin struct {
 }
 
 // Help gets the usage info for this plugin
-func (p *WarPlugin) Help(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, detailed bool) []string {
+func (p *WarPlugin) Help(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, detailed bool) []string {
+	id := mmmorty.Text(service, nil, "sprint.help.id", nil)
 	help := mmmorty.CommandHelp(
-		service, startWarCommand, "at :XX for Y (mins)",
-		"starts a sprint starting when the minute hand points to XX and lasting for Y minutes",
+		service, startWarAtSpec.Name, startWarAtSpec.Arguments(),
+		mmmorty.Text(service, nil, "sprint.help.start", nil),
 	)
 	help = append(help, mmmorty.CommandHelp(
-		service, endWarCommand, "ID",
-		"Ends the sprint with the given name.",
+		service, endWarCommand, id,
+		mmmorty.Text(service, nil, "sprint.help.end", nil),
 	)[0])
 	help = append(help, mmmorty.CommandHelp(
-		service, joinWarCommand, "ID",
-		"Adds you to the list of people to notify for the given sprint.",
+		service, joinWarCommand, id,
+		mmmorty.Text(service, nil, "sprint.help.join", nil),
 	)[0])
 	help = append(help, mmmorty.CommandHelp(
-		service, leaveWarCommand, "ID",
-		"Removes you from the list of people to notify for the given sprint.",
+		service, leaveWarCommand, id,
+		mmmorty.Text(service, nil, "sprint.help.leave", nil),
 	)[0])
 	help = append(help, mmmorty.CommandHelp(
 		service, doTheThing, "",
-		"Shorthand for \"start sprint for 15\" starting in 4 minutes.",
+		mmmorty.Text(service, nil, "sprint.help.thing", nil),
 	)[0])
 	return help
 }
 
 // Load loads the plugin with the given data
-func (p *WarPlugin) Load(bot *mmmorty.Bot, service mmmorty.Discord, data []byte) error {
+func (p *WarPlugin) Load(bot *mmmorty.Bot, service mmmorty.Service, data []byte) error {
 	if data != nil {
 		if err := json.Unmarshal(data, p); err != nil {
-			log.Println("Error loading data", err)
 			return err
 		}
 	}
@@ -78,97 +91,153 @@ func (p *WarPlugin) Load(bot *mmmorty.Bot, service mmmorty.Discord, data []byte)
 	return nil
 }
 
-// Message is the command handler for this plugin
-func (p *WarPlugin) Message(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage) {
-	defer bot.MessageRecover(service, message.Channel())
-
-	if service.Name() != mmmorty.DiscordServiceName {
-		return
+// Commands returns the commands this plugin handles
+func (p *WarPlugin) Commands() []mmmorty.Command {
+	return []mmmorty.Command{
+		{
+			Name:        startWarCommand,
+			Handler:     p.handleStartWarCommand,
+			Description: "starts a sprint at a minute past the hour",
+			Spec:        startWarAtSpec,
+		},
+		{
+			Name:        doTheThing,
+			Handler:     p.handleDoTheThing,
+			Description: "starts a 15 minute sprint in 4 minutes",
+		},
+		{
+			Name:        joinWarCommand,
+			Handler:     p.handleJoinWarCommand,
+			Description: "adds you to the list of people to notify for a sprint",
+		},
+		{
+			Name:        leaveWarCommand,
+			Handler:     p.handleLeaveWarCommand,
+			Description: "removes you from the list of people to notify for a sprint",
+		},
+		{
+			Name:        endWarCommand,
+			Handler:     p.handleEndWarCommand,
+			Description: "ends a sprint",
+		},
 	}
+}
 
-	if service.IsMe(message) {
-		return
-	}
+// Gauges returns how many sprints are running.
+func (p *WarPlugin) Gauges() map[string]float64 {
+	return map[string]float64{"active_sprints": float64(len(p.Wars))}
+}
 
-	if mmmorty.MatchesCommand(service, startWarCommand, message) {
-		p.handleStartWarCommand(bot, service, message)
-	} else if mmmorty.MatchesCommand(service, doTheThing, message) {
-		p.handleDoTheThing(bot, service, message)
-	} else if mmmorty.MatchesCommand(service, joinWarCommand, message) {
-		p.handleJoinWarCommand(bot, service, message)
-	} else if mmmorty.MatchesCommand(service, leaveWarCommand, message) {
-		p.handleLeaveWarCommand(bot, service, message)
-	} else if mmmorty.MatchesCommand(service, endWarCommand, message) {
-		p.handleEndWarCommand(bot, service, message)
+// Close stops the sprints' alerts. The sprints are still saved.
+func (p *WarPlugin) Close(bot *mmmorty.Bot, service mmmorty.Service) error {
+	for _, war := range p.Wars {
+		war.stop()
 	}
+	return nil
 }
 
-func (p *WarPlugin) handleDoTheThing(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage) {
-	now := timeWithoutSeconds()
-	nowMinute := now.Minute()
-	startMinute := (nowMinute + 4) % 60
-	p.startWar(bot, service, message, startMinute, 15)
+// Limits returns how many sprints a server can run at once.
+func (p *WarPlugin) Limits() map[string]int {
+	return map[string]int{"sprints": maxWarCount}
 }
 
-func (p *WarPlugin) handleStartWarCommand(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage) {
-	requester := fmt.Sprintf("<@%s>", message.UserID())
-
+// Stats shows how many sprints are running on the server.
+func (p *WarPlugin) Stats(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) []string {
 	if service.IsPrivate(message) {
-		reply := fmt.Sprintf("Uh, %s, I can't start sprints privately.", requester)
-		service.SendMessage(message.Channel(), reply)
-		return
+		return nil
 	}
+	channel, err := service.Channel(message.Channel())
+	if err != nil {
+		return nil
+	}
+	return []string{mmmorty.Text(service, nil, "sprint.stats", mmmorty.Vars{"count": len(p.guildWars(service, channel.GuildID))})}
+}
 
-	_, parts := mmmorty.ParseCommand(service, message)
+// guildWars returns the sprints started in a server, by name.
+func (p *WarPlugin) guildWars(service mmmorty.Service, guildID string) map[string]*War {
+	wars := map[string]*War{}
+	for name, war := range p.Wars {
+		if channel, err := service.Channel(war.Channel); err == nil && channel.GuildID == guildID {
+			wars[name] = war
+		}
+	}
+	return wars
+}
 
-	// "sprint at :XX for Y (mins)"
-	if len(parts) < 5 || (parts[1] != "at" && parts[1] != "for") || (parts[3] != "at" && parts[3] != "for") {
-		reply := fmt.Sprintf("Uh, %s, I don't quite know what you mean. Have you tried `%s at :XX for Y (mins)`?", requester, startWarCommand)
-		service.SendMessage(message.Channel(), reply)
-		return
+// GuildData returns the sprints running in a server.
+func (p *WarPlugin) GuildData(service mmmorty.Service, guildID string) interface{} {
+	wars := []*War{}
+	for _, war := range p.guildWars(service, guildID) {
+		wars = append(wars, war)
 	}
+	sort.Slice(wars, func(i, j int) bool {
+		return wars[i].Name < wars[j].Name
+	})
+	return wars
+}
 
-	var requestedTime, requestedDuration string
-	if parts[1] == "at" && parts[3] == "for" {
-		requestedTime = parts[2]
-		requestedDuration = parts[4]
-	} else if parts[1] == "for" && parts[3] == "at" {
-		requestedDuration = parts[2]
-		requestedTime = parts[4]
-	} else {
-		reply := fmt.Sprintf("Uh, %s, I don't quite know what you mean. Have you tried `%s at :XX for Y (mins)`?", requester, startWarCommand)
-		service.SendMessage(message.Channel(), reply)
-		return
+// SetGuildData ends the server's sprints that were removed, and changes who's in the rest.
+// Sprints can't be started or rescheduled this way.
+func (p *WarPlugin) SetGuildData(bot *mmmorty.Bot, service mmmorty.Service, guildID string, data []byte) error {
+	edited := []War{}
+	if err := json.Unmarshal(data, &edited); err != nil {
+		return err
+	}
+	wars := p.guildWars(service, guildID)
+	kept := map[string]bool{}
+	for _, e := range edited {
+		war, ok := wars[e.Name]
+		if !ok {
+			return fmt.Errorf("there's no sprint called %q, sprints can only be started with a command", e.Name)
+		}
+		if e.Channel != war.Channel || e.Duration != war.Duration || e.Start != war.Start {
+			return fmt.Errorf("only the sprinters in %s can change", e.Name)
+		}
+		kept[e.Name] = true
 	}
 
-	if requestedTime[0] == ':' {
-		requestedTime = requestedTime[1:]
+	for _, e := range edited {
+		wars[e.Name].Sprinters = e.Sprinters
 	}
-	minutes, err := strconv.Atoi(requestedTime)
-	if err != nil {
-		reply := fmt.Sprintf("Uh, %s, that start time doesn't make sense. `:XX` should work fine.", requester)
-		service.SendMessage(message.Channel(), reply)
-		return
+	for name, war := range wars {
+		if !kept[name] {
+			war.stop()
+			delete(p.Wars, name)
+		}
 	}
-	if minutes < 0 || minutes > 60 {
-		reply := fmt.Sprintf("Uh, %s, that start time doesn't make sense. Maybe try a number between 0 and 60, you know?", requester)
+	return nil
+}
+
+// Message is unused, this plugin's commands are routed by the bot
+func (p *WarPlugin) Message(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
+}
+
+func (p *WarPlugin) handleDoTheThing(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
+	now := timeWithoutSeconds(bot)
+	nowMinute := now.Minute()
+	startMinute := (nowMinute + 4) % 60
+	p.startWar(bot, service, message, startMinute, 15)
+}
+
+func (p *WarPlugin) handleStartWarCommand(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
+	if service.IsPrivate(message) {
+		reply := mmmorty.Text(service, message, "sprint.private", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
-	duration, err := strconv.Atoi(requestedDuration)
-	if err != nil {
-		reply := fmt.Sprintf("Uh, %s, that duration doesn't make sense. A number for the minutes should work fine.", requester)
-		service.SendMessage(message.Channel(), reply)
-		return
+	// The start time and duration can be given in either order.
+	spec := startWarAtSpec
+	if mmmorty.MatchesCommand(service, startWarForSpec.Name, message) {
+		spec = startWarForSpec
 	}
-	if duration > 180 {
-		reply := fmt.Sprintf("Gee, %s, I don't know if Rick will let me keep a sprint going for that long.", requester)
-		service.SendMessage(message.Channel(), reply)
+	args, err := spec.Parse(service, message)
+	if err != nil {
+		mmmorty.SendEphemeral(service, message.Channel(), spec.UsageReply(service, message, err))
 		return
 	}
 
-	p.startWar(bot, service, message, minutes, duration)
+	p.startWar(bot, service, message, args.Int("minute"), args.Int("minutes"))
 }
 
 func (p *WarPlugin) getNameFromParts(parts []string) string {
@@ -185,21 +254,19 @@ func (p *WarPlugin) getNameFromParts(parts []string) string {
 	return parts[0]
 }
 
-func (p *WarPlugin) handleJoinWarCommand(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage) {
-	requester := fmt.Sprintf("<@%s>", message.UserID())
-
+func (p *WarPlugin) handleJoinWarCommand(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
 	_, parts := mmmorty.ParseCommand(service, message)
 	name := p.getNameFromParts(parts)
 
 	if name == "" {
-		reply := fmt.Sprintf("Uh, %s, what was the sprint you wanted to join?", requester)
+		reply := mmmorty.Text(service, message, "sprint.join.missing", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
 	war, ok := p.Wars[name]
 	if !ok {
-		reply := fmt.Sprintf("Uh, %s, I don't see a sprint by that name.", requester)
+		reply := mmmorty.Text(service, message, "sprint.unknown", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
@@ -213,32 +280,30 @@ func (p *WarPlugin) handleJoinWarCommand(bot *mmmorty.Bot, service mmmorty.Disco
 	}
 
 	if isInWar {
-		reply := fmt.Sprintf("Looks like you are in that sprint already %s. You should be good to go.", requester)
+		reply := mmmorty.Text(service, message, "sprint.join.already", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
 	war.Sprinters = append(war.Sprinters, message.UserID())
 
-	reply := fmt.Sprintf("I added you to the sprint, %s. Good luck!", requester)
+	reply := mmmorty.Text(service, message, "sprint.joined", nil)
 	service.SendMessage(message.Channel(), reply)
 }
 
-func (p *WarPlugin) handleLeaveWarCommand(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage) {
-	requester := fmt.Sprintf("<@%s>", message.UserID())
-
+func (p *WarPlugin) handleLeaveWarCommand(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
 	_, parts := mmmorty.ParseCommand(service, message)
 	name := p.getNameFromParts(parts)
 
 	if name == "" {
-		reply := fmt.Sprintf("Uh, %s, what was the sprint you wanted to leave?", requester)
+		reply := mmmorty.Text(service, message, "sprint.leave.missing", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
 	war, ok := p.Wars[name]
 	if !ok {
-		reply := fmt.Sprintf("Uh, %s, I don't see a sprint by that name.", requester)
+		reply := mmmorty.Text(service, message, "sprint.unknown", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
@@ -252,7 +317,7 @@ func (p *WarPlugin) handleLeaveWarCommand(bot *mmmorty.Bot, service mmmorty.Disc
 	}
 
 	if index == -1 {
-		reply := fmt.Sprintf("Uh, %s, you are not in that sprint.", requester)
+		reply := mmmorty.Text(service, message, "sprint.leave.missed", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
@@ -265,35 +330,31 @@ func (p *WarPlugin) handleLeaveWarCommand(bot *mmmorty.Bot, service mmmorty.Disc
 		war.Sprinters = append(war.Sprinters[:index], war.Sprinters[index+1:]...)
 	}
 
-	reply := fmt.Sprintf("I removed you from %s.", name)
+	reply := mmmorty.Text(service, message, "sprint.left", mmmorty.Vars{"name": name})
 	service.SendMessage(message.Channel(), reply)
 }
 
-func (p *WarPlugin) handleEndWarCommand(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage) {
-	requester := fmt.Sprintf("<@%s>", message.UserID())
-
+func (p *WarPlugin) handleEndWarCommand(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
 	_, parts := mmmorty.ParseCommand(service, message)
 	name := p.getNameFromParts(parts)
 
 	if name == "" {
-		reply := fmt.Sprintf("Uh, %s, what was the sprint you wanted to end?", requester)
+		reply := mmmorty.Text(service, message, "sprint.end.missing", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
 	war, ok := p.Wars[name]
 	if !ok {
-		reply := fmt.Sprintf("Uh, %s, I don't see a sprint by that name.", requester)
+		reply := mmmorty.Text(service, message, "sprint.unknown", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
-	war.alertTimer.Stop()
-	war.startTimer.Stop()
-	war.endTimer.Stop()
+	war.stop()
 	delete(p.Wars, name)
 
-	reply := fmt.Sprintf("Sprint %s was ended.", name)
+	reply := mmmorty.Text(service, message, "sprint.ended", mmmorty.Vars{"name": name})
 	service.SendMessage(message.Channel(), reply)
 }
 
@@ -314,10 +375,8 @@ func New() mmmorty.Plugin {
 	}
 }
 
-func (p *WarPlugin) startWar(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, minutes, duration int) {
-	requester := fmt.Sprintf("<@%s>", message.UserID())
-
-	now := timeWithoutSeconds()
+func (p *WarPlugin) startWar(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, minutes, duration int) {
+	now := timeWithoutSeconds(bot)
 	nowMinutes := now.Minute()
 
 	// Cannot start the timer for the current minute
@@ -326,6 +385,14 @@ func (p *WarPlugin) startWar(bot *mmmorty.Bot, service mmmorty.Discord, message
 		return
 	}
 
+	if channel, err := service.Channel(message.Channel()); err == nil {
+		if len(p.guildWars(service, channel.GuildID)) >= bot.Limit(channel.GuildID, "sprints") {
+			reply := mmmorty.Text(service, message, "sprint.full", nil)
+			service.SendMessage(message.Channel(), reply)
+			return
+		}
+	}
+
 	// Rollover to the next hour
 	if minutes < nowMinutes {
 		minutes += 60
@@ -335,10 +402,10 @@ func (p *WarPlugin) startWar(bot *mmmorty.Bot, service mmmorty.Discord, message
 
 	// when to give the minute-before alert
 	minutesToAlert := minutes - nowMinutes - 1
-	var alertTimer *time.Timer
+	var alertTimer mmmorty.Timer
 	if minutesToAlert > 0 {
 		alertingIn, _ := time.ParseDuration(fmt.Sprintf("%vm", minutesToAlert))
-		alertTimer = time.AfterFunc(alertingIn, func() {
+		alertTimer = bot.AfterFunc(alertingIn, func() {
 			p.alertNotify(bot, service, message, name)
 		})
 	}
@@ -346,14 +413,14 @@ func (p *WarPlugin) startWar(bot *mmmorty.Bot, service mmmorty.Discord, message
 	// when to give the starting alert
 	minutesToStart := minutes - nowMinutes
 	startingIn, _ := time.ParseDuration(fmt.Sprintf("%vm", minutesToStart))
-	startTimer := time.AfterFunc(startingIn, func() {
+	startTimer := bot.AfterFunc(startingIn, func() {
 		p.startNotify(bot, service, message, name)
 	})
 
 	// when to give the ending alert
 	minutesToEnd := minutesToStart + duration
 	endingIn, _ := time.ParseDuration(fmt.Sprintf("%vm", minutesToEnd))
-	endTimer := time.AfterFunc(endingIn, func() {
+	endTimer := bot.AfterFunc(endingIn, func() {
 		p.endNotify(bot, service, message, name)
 	})
 
@@ -371,18 +438,16 @@ func (p *WarPlugin) startWar(bot *mmmorty.Bot, service mmmorty.Discord, message
 	}
 	p.Wars[name] = war
 
-	reply := fmt.Sprintf(
-		"Ok, %s, you got it! I added you to this sprint. Use `%s %s` to get updates, `%s %s` to stop getting them, and `%s %s` to cancel this sprint.",
-		requester,
-		joinWarCommand, name,
-		leaveWarCommand, name,
-		endWarCommand, name,
-	)
+	reply := mmmorty.Text(service, message, "sprint.created", mmmorty.Vars{
+		"join":  joinWarCommand + " " + name,
+		"leave": leaveWarCommand + " " + name,
+		"end":   endWarCommand + " " + name,
+	})
 	service.SendMessage(message.Channel(), reply)
 }
 
-func timeWithoutSeconds() time.Time {
-	now := time.Now()
+func timeWithoutSeconds(bot *mmmorty.Bot) time.Time {
+	now := bot.Now()
 	return time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), 0, 0, now.Location())
 }
 
@@ -409,49 +474,53 @@ func (p *WarPlugin) pickName() string {
 	return name
 }
 
-func (p *WarPlugin) alertNotify(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, name string) {
+func (p *WarPlugin) alertNotify(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, name string) {
+	bot.LockPlugin(p)
+	defer bot.UnlockPlugin(p)
+
 	war, ok := p.Wars[name]
 	if !ok {
 		return
 	}
 
-	notifyString := stringifySprinters(war)
-
-	duration := war.Duration
-	minuteString := "minutes"
-	if duration == 1 {
-		minuteString = "minute"
-	}
-
-	reply := fmt.Sprintf("Sprint %s is starting in one minute, when it will go for %v %s! %s", name, duration, minuteString, notifyString)
+	reply := mmmorty.Text(service, nil, "sprint.alert", mmmorty.Vars{
+		"name":      name,
+		"count":     war.Duration,
+		"sprinters": stringifySprinters(war),
+	})
 	service.SendMessage(war.Channel, reply)
 }
 
-func (p *WarPlugin) startNotify(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, name string) {
+func (p *WarPlugin) startNotify(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, name string) {
+	bot.LockPlugin(p)
+	defer bot.UnlockPlugin(p)
+
 	war, ok := p.Wars[name]
 	if !ok {
 		return
 	}
 
-	notifyString := stringifySprinters(war)
-
-	duration := war.Duration
-	minuteString := "minutes"
-	if duration == 1 {
-		minuteString = "minute"
-	}
-	reply := fmt.Sprintf("Sprint %s starts now and goes for %v %s! %s", name, duration, minuteString, notifyString)
+	reply := mmmorty.Text(service, nil, "sprint.start", mmmorty.Vars{
+		"name":      name,
+		"count":     war.Duration,
+		"sprinters": stringifySprinters(war),
+	})
 	service.SendMessage(war.Channel, reply)
 }
 
-func (p *WarPlugin) endNotify(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, name string) {
+func (p *WarPlugin) endNotify(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, name string) {
+	bot.LockPlugin(p)
+	defer bot.UnlockPlugin(p)
+
 	war, ok := p.Wars[name]
 	if !ok {
 		return
 	}
 
-	notifyString := stringifySprinters(war)
-	reply := fmt.Sprintf("Sprint %s has ended! %s", name, notifyString)
+	reply := mmmorty.Text(service, nil, "sprint.end", mmmorty.Vars{
+		"name":      name,
+		"sprinters": stringifySprinters(war),
+	})
 	service.SendMessage(war.Channel, reply)
 
 	delete(p.Wars, name)
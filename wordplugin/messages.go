@@ -0,0 +1,53 @@
+package wordplugin
+
+import "github.com/todd-beckman/mmmorty"
+
+func init() {
+	mmmorty.RegisterMessages("en", map[string]string{
+		"word.help.define":        "defines the word if I was told to remember it",
+		"word.help.add":           "adds a word I should remember",
+		"word.help.delete":        "makes me forget a word",
+		"word.help.word":          "word",
+		"word.help.definition":    "word definition",
+		"word.private":            "Uh, {user}, I can't do this in PM.",
+		"word.missing.definition": "Uh, {user}, I need a word and a definition.",
+		"word.overwrote":          "Uh, {user}, I added that but overwrote this other one: {old}",
+		"word.added":              "You got it, {user}! I will try to remember that!",
+		"word.missing.delete":     "Uh, {user}, I think you forgot to give me word.",
+		"word.unknown.delete":     "Uh, {user}, no one told me to remember that word.",
+		"word.deleted":            "1... 2... and... poof. I have no idea what {word} means.",
+		"word.missing.define":     "Uh, {user}, I think you forgot to name a word.",
+		"word.unknown.define":     "Uh, {user}, no one told me to remember {word}.",
+		"word.definition":         "Uh, {user}, I think {word} is {definition}.",
+	})
+
+	mmmorty.RegisterMessages("es", map[string]string{
+		"word.help.define":        "define la palabra si me pidieron que la recordara",
+		"word.help.add":           "añade una palabra que debería recordar",
+		"word.help.delete":        "hace que olvide una palabra",
+		"word.help.word":          "palabra",
+		"word.help.definition":    "palabra definición",
+		"word.private":            "Eh, {user}, no puedo hacer esto en privado.",
+		"word.missing.definition": "Eh, {user}, necesito una palabra y una definición.",
+		"word.overwrote":          "Eh, {user}, la añadí, pero reemplacé esta otra: {old}",
+		"word.added":              "¡Hecho, {user}! ¡Intentaré recordarla!",
+		"word.missing.delete":     "Eh, {user}, creo que olvidaste darme la palabra.",
+		"word.unknown.delete":     "Eh, {user}, nadie me pidió que recordara esa palabra.",
+		"word.deleted":            "1... 2... y... puf. No tengo ni idea de qué significa {word}.",
+		"word.missing.define":     "Eh, {user}, creo que olvidaste decir la palabra.",
+		"word.unknown.define":     "Eh, {user}, nadie me pidió que recordara {word}.",
+		"word.definition":         "Eh, {user}, creo que {word} es {definition}.",
+	})
+
+	mmmorty.RequirePlaceholders(map[string][]string{
+		"word.definition": {"definition"},
+	})
+
+	mmmorty.RegisterSamples(map[string]mmmorty.Vars{
+		"word": {
+			"definition": "a song",
+			"old":        "getting down and dirty",
+			"word":       "schwifty",
+		},
+	})
+}
@@ -3,7 +3,7 @@ package wordplugin
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"strconv"
 	"strings"
 
 	"github.com/todd-beckman/mmmorty"
@@ -22,35 +22,84 @@ type WordPlugin struct {
 	WordsByGuild map[string]words `json:"wordsByGuild"`
 }
 
-type handleFunc func(*mmmorty.Bot, mmmorty.Discord, mmmorty.DiscordMessage, string)
-
-func (p *WordPlugin) findHandler(service mmmorty.Discord, message mmmorty.DiscordMessage) handleFunc {
-	handlers := map[string]handleFunc{
-		addWordCommand:    p.handleAddWord,
-		deleteWordCommand: p.handleDeleteWord,
-		defineCommand:     p.handleDefine,
+// GuildData returns a server's words and their definitions.
+func (p *WordPlugin) GuildData(service mmmorty.Service, guildID string) interface{} {
+	if p.WordsByGuild[guildID].Words == nil {
+		return map[string]string{}
 	}
-	for c, h := range handlers {
-		if mmmorty.MatchesCommand(service, c, message) {
-			return h
+	return p.WordsByGuild[guildID].Words
+}
+
+// SetGuildData replaces a server's words and their definitions.
+func (p *WordPlugin) SetGuildData(bot *mmmorty.Bot, service mmmorty.Service, guildID string, data []byte) error {
+	edited := map[string]string{}
+	if err := json.Unmarshal(data, &edited); err != nil {
+		return err
+	}
+	w := words{map[string]string{}}
+	for word, definition := range edited {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word == "" || strings.Contains(word, " ") {
+			return fmt.Errorf("%q should be a single word", word)
+		}
+		if strings.TrimSpace(definition) == "" {
+			return fmt.Errorf("%s needs a definition", word)
 		}
+		w.Words[word] = definition
+	}
+
+	if p.WordsByGuild == nil {
+		p.WordsByGuild = map[string]words{}
+	}
+	if len(w.Words) == 0 {
+		delete(p.WordsByGuild, guildID)
+	} else {
+		p.WordsByGuild[guildID] = w
 	}
 	return nil
 }
 
+type handleFunc func(*mmmorty.Bot, mmmorty.Service, mmmorty.Message, string)
+
+// Commands returns the commands this plugin handles
+func (p *WordPlugin) Commands() []mmmorty.Command {
+	return []mmmorty.Command{
+		{
+			Name:        addWordCommand,
+			Handler:     p.guildCommand(p.handleAddWord),
+			Description: "adds a word I should remember",
+			// Small servers can grant it to @everyone.
+			Permission: mmmorty.Moderator,
+		},
+		{
+			Name:        deleteWordCommand,
+			Handler:     p.guildCommand(p.handleDeleteWord),
+			Description: "makes me forget a word",
+			// Small servers can grant it to @everyone.
+			Permission: mmmorty.Moderator,
+		},
+		{
+			Name:        defineCommand,
+			Handler:     p.guildCommand(p.handleDefine),
+			Description: "defines the word if I was told to remember it",
+			Rerun:       true,
+		},
+	}
+}
+
 // Help gets the usage for this plugin
-func (p *WordPlugin) Help(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, detailed bool) []string {
-	help := mmmorty.CommandHelp(service, defineCommand, "word", "defines the word if I was told to remember it")
-	help = append(help, mmmorty.CommandHelp(service, addWordCommand, "word definition", "adds a word I should remember")...)
-	help = append(help, mmmorty.CommandHelp(service, deleteWordCommand, "word", "makes me forget a word")...)
+func (p *WordPlugin) Help(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, detailed bool) []string {
+	word := mmmorty.Text(service, nil, "word.help.word", nil)
+	help := mmmorty.CommandHelp(service, defineCommand, word, mmmorty.Text(service, nil, "word.help.define", nil))
+	help = append(help, mmmorty.CommandHelp(service, addWordCommand, mmmorty.Text(service, nil, "word.help.definition", nil), mmmorty.Text(service, nil, "word.help.add", nil))...)
+	help = append(help, mmmorty.CommandHelp(service, deleteWordCommand, word, mmmorty.Text(service, nil, "word.help.delete", nil))...)
 	return help
 }
 
 // Load loads this plugin from the given data
-func (p *WordPlugin) Load(bot *mmmorty.Bot, service mmmorty.Discord, data []byte) error {
+func (p *WordPlugin) Load(bot *mmmorty.Bot, service mmmorty.Service, data []byte) error {
 	if data != nil {
 		if err := json.Unmarshal(data, p); err != nil {
-			log.Println("Error loading data", err)
 			return err
 		}
 	}
@@ -58,60 +107,46 @@ func (p *WordPlugin) Load(bot *mmmorty.Bot, service mmmorty.Discord, data []byte
 	return nil
 }
 
-// Message is the command handler for this plugin
-func (p *WordPlugin) Message(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage) {
-	defer bot.MessageRecover(service, message.Channel())
-	if service.IsMe(message) {
-		return
-	}
-
-	handler := p.findHandler(service, message)
-	if handler == nil {
-		return
-	}
+// Message is unused, this plugin's commands are routed by the bot
+func (p *WordPlugin) Message(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
+}
 
-	requester := fmt.Sprintf("<@%s>", message.UserID())
-	channelID := message.Channel()
-	discordChannel, err := service.Channel(channelID)
-	if err != nil {
-		reply := fmt.Sprintf("Uh, %s, something went figuring out your server.", requester)
-		service.SendMessage(message.Channel(), reply)
-		return
-	}
+// guildCommand looks up the guild a command was sent in before handling it
+func (p *WordPlugin) guildCommand(handler handleFunc) mmmorty.CommandFunc {
+	return func(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message) {
+		channelID := message.Channel()
+		discordChannel, err := service.Channel(channelID)
+		if err != nil {
+			reply := mmmorty.Text(service, message, "error.guild", nil)
+			service.SendMessage(message.Channel(), reply)
+			return
+		}
 
-	guildID := discordChannel.GuildID
-	if p.WordsByGuild == nil {
-		p.WordsByGuild = map[string]words{}
-	}
-	if p.WordsByGuild[guildID].Words == nil {
-		p.WordsByGuild[guildID] = words{
-			map[string]string{},
+		guildID := discordChannel.GuildID
+		if p.WordsByGuild == nil {
+			p.WordsByGuild = map[string]words{}
+		}
+		if p.WordsByGuild[guildID].Words == nil {
+			p.WordsByGuild[guildID] = words{
+				map[string]string{},
+			}
 		}
-	}
 
-	handler(bot, service, message, guildID)
+		handler(bot, service, message, guildID)
+	}
 }
 
-func (p *WordPlugin) handleAddWord(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, guildID string) {
-	requester := fmt.Sprintf("<@%s>", message.UserID())
-
+func (p *WordPlugin) handleAddWord(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, guildID string) {
 	if service.IsPrivate(message) {
-		reply := fmt.Sprintf("Uh, %s, I can't do this in PM.", requester)
+		reply := mmmorty.Text(service, message, "word.private", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
-	// Should be okay on small servers to let anyone define words.
-	// if !service.IsModerator(message) {
-	// 	reply := fmt.Sprintf("Uh, %s, I don't think I can let you do that.", requester)
-	// 	service.SendMessage(message.Channel(), reply)
-	// 	return
-	// }
-
 	_, parts := mmmorty.ParseCommand(service, message)
 
 	if len(parts) < 3 {
-		reply := fmt.Sprintf("Uh, %s, I need a word and a definition.", requester)
+		reply := mmmorty.Text(service, message, "word.missing.definition", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
@@ -120,71 +155,62 @@ func (p *WordPlugin) handleAddWord(bot *mmmorty.Bot, service mmmorty.Discord, me
 	definition := strings.Join(parts[2:], " ")
 
 	if old, ok := p.WordsByGuild[guildID].Words[word]; ok {
-		reply := fmt.Sprintf("Uh, %s, I added that but overwrote this other one: %q", requester, old)
+		reply := mmmorty.Text(service, message, "word.overwrote", mmmorty.Vars{"old": strconv.Quote(old)})
 		service.SendMessage(message.Channel(), reply)
 	}
 	p.WordsByGuild[guildID].Words[word] = definition
 
-	reply := fmt.Sprintf("You got it, %s! I will try to remember that!", requester)
+	reply := mmmorty.Text(service, message, "word.added", nil)
 	service.SendMessage(message.Channel(), reply)
 }
 
-func (p *WordPlugin) handleDeleteWord(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, guildID string) {
-	requester := fmt.Sprintf("<@%s>", message.UserID())
+func (p *WordPlugin) handleDeleteWord(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, guildID string) {
 	if service.IsPrivate(message) {
-		reply := fmt.Sprintf("Uh, %s, I can't do this in PM.", requester)
+		reply := mmmorty.Text(service, message, "word.private", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
-	// Should be okay on small servers to let anyone define words.
-	// if !service.IsModerator(message) {
-	// 	reply := fmt.Sprintf("Uh, %s, I don't think I can let you do that.", requester)
-	// 	service.SendMessage(message.Channel(), reply)
-	// 	return
-	// }
-
 	_, parts := mmmorty.ParseCommand(service, message)
 	if len(parts) != 2 {
-		reply := fmt.Sprintf("Uh, %s, I think you forgot to give me word.", requester)
+		reply := mmmorty.Text(service, message, "word.missing.delete", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
 	word := strings.ToLower(parts[1])
 	if _, ok := p.WordsByGuild[guildID].Words[word]; !ok {
-		reply := fmt.Sprintf("Uh, %s, no one told me to remember that word.", requester)
+		reply := mmmorty.Text(service, message, "word.unknown.delete", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
-	reply := fmt.Sprintf("1... 2... and... poof. I have no idea what %q means.", word)
+	reply := mmmorty.Text(service, message, "word.deleted", mmmorty.Vars{"word": strconv.Quote(word)})
 	service.SendMessage(message.Channel(), reply)
 }
 
-func (p *WordPlugin) handleDefine(bot *mmmorty.Bot, service mmmorty.Discord, message mmmorty.DiscordMessage, guildID string) {
-	requester := fmt.Sprintf("<@%s>", message.UserID())
+func (p *WordPlugin) handleDefine(bot *mmmorty.Bot, service mmmorty.Service, message mmmorty.Message, guildID string) {
 	if service.IsPrivate(message) {
-		reply := fmt.Sprintf("Uh, %s, I can't do this in PM.", requester)
+		reply := mmmorty.Text(service, message, "word.private", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
 	_, parts := mmmorty.ParseCommand(service, message)
 	if len(parts) != 1 {
-		reply := fmt.Sprintf("Uh, %s, I think you forgot to name a word.", requester)
+		reply := mmmorty.Text(service, message, "word.missing.define", nil)
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 	word := strings.ToLower(parts[0])
 	definition, ok := p.WordsByGuild[guildID].Words[word]
 	if !ok {
-		reply := fmt.Sprintf("Uh, %s, no one told me to remember %s.", requester, word)
+		reply := mmmorty.Text(service, message, "word.unknown.define", mmmorty.Vars{"word": word})
 		service.SendMessage(message.Channel(), reply)
 		return
 	}
 
-	reply := fmt.Sprintf("Uh, %s, I think %q is %q.", requester, word, definition)
+	reply := mmmorty.Text(service, message, "word.definition", mmmorty.Vars{"word": strconv.Quote(word), "definition": strconv.Quote(definition)})
 	service.SendMessage(message.Channel(), reply)
 }
 